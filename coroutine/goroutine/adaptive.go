@@ -0,0 +1,158 @@
+package goroutine
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Number of completed jobs between adjustments of an adaptive limit.
+	adaptiveWindow = 20
+)
+
+/*
+WithAdaptiveLimit - Lets the pool discover how many jobs to run at once instead of always running
+as many as it has workers. An AIMD controller measures the p95 duration of the jobs completed in
+each window and, when it is over targetLatency, cuts the number of jobs allowed to run at once by a
+quarter, otherwise while jobs are waiting for the limit it allows one more. The limit starts at min
+and stays within [min, max], no workers are stopped, dispatch is simply gated.
+*/
+func WithAdaptiveLimit(min, max int, targetLatency time.Duration) Option {
+	return func(pool *WorkPool) {
+		if min < 1 {
+			min = 1
+		}
+		if max < min {
+			max = min
+		}
+		pool.adaptive = &adaptiveLimiter{
+			min:     min,
+			max:     max,
+			limit:   min,
+			target:  targetLatency,
+			changed: make(chan struct{}),
+		}
+	}
+}
+
+/*
+adaptiveLimiter - Gates the number of jobs running at once with a limit adjusted from the measured
+job latency. All methods are safe to call on a nil limiter, which never gates.
+*/
+type adaptiveLimiter struct {
+	mutex     sync.Mutex
+	min       int
+	max       int
+	limit     int
+	inFlight  int
+	saturated bool
+	target    time.Duration
+	clock     Clock
+	samples   []time.Duration
+	p95       time.Duration
+
+	// changed is closed and replaced whenever a slot may have become available
+	changed chan struct{}
+}
+
+/*
+acquire - Waits for a slot under the limit, returns false if timeout or cancel fires first. Nil
+channels never fire.
+*/
+func (l *adaptiveLimiter) acquire(timeout <-chan time.Time, cancel <-chan struct{}) bool {
+	if l == nil {
+		return true
+	}
+	for {
+		l.mutex.Lock()
+		if l.inFlight < l.limit {
+			l.inFlight++
+			l.mutex.Unlock()
+			return true
+		}
+		l.saturated = true
+		changed := l.changed
+		l.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-timeout:
+			return false
+		case <-cancel:
+			return false
+		}
+	}
+}
+
+/*
+now - The start time of a gated job, zero if there is no limiter.
+*/
+func (l *adaptiveLimiter) now() time.Time {
+	if l == nil {
+		return time.Time{}
+	}
+	return l.clock.Now()
+}
+
+/*
+release - Frees the slot of a job which started running at started, a zero start frees the slot
+without recording a latency sample.
+*/
+func (l *adaptiveLimiter) release(started time.Time) {
+	if l == nil {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.inFlight--
+	if !started.IsZero() {
+		l.samples = append(l.samples, l.clock.Now().Sub(started))
+		if len(l.samples) >= adaptiveWindow {
+			l.adjust()
+		}
+	}
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+/*
+adjust - Recomputes the limit from the samples of the window which just completed.
+*/
+func (l *adaptiveLimiter) adjust() {
+	sort.Slice(l.samples, func(i, j int) bool {
+		return l.samples[i] < l.samples[j]
+	})
+	l.p95 = l.samples[(len(l.samples)*95-1)/100]
+	l.samples = l.samples[:0]
+
+	if l.p95 > l.target {
+		l.limit -= (l.limit + 3) / 4
+	} else if l.saturated {
+		l.limit++
+	}
+	if l.limit < l.min {
+		l.limit = l.min
+	}
+	if l.limit > l.max {
+		l.limit = l.max
+	}
+	l.saturated = false
+}
+
+/*
+state - The current limit and the p95 latency of the last complete window.
+*/
+func (l *adaptiveLimiter) state() (int, time.Duration) {
+	if l == nil {
+		return 0, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.limit, l.p95
+}
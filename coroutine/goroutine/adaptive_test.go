@@ -0,0 +1,90 @@
+package goroutine
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimit(t *testing.T) {
+	numWorkers := 16
+	var running int32
+
+	// Each job takes longer the more jobs run alongside it
+	pool, err := CreatePool(numWorkers, func(in interface{}) interface{} {
+		concurrency := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		time.Sleep(time.Duration(concurrency) * time.Millisecond)
+		return nil
+	}, WithAdaptiveLimit(1, numWorkers, 5*time.Millisecond)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	var maxRunning int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < numWorkers*2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 40; j++ {
+				if _, err := pool.SendWork(nil); err != nil {
+					t.Errorf("Failed to send work: %v", err)
+					return
+				}
+				stats := pool.Stats()
+				if stats.EffectiveLimit < 1 || stats.EffectiveLimit > numWorkers {
+					t.Errorf("Limit out of bounds: %v", stats.EffectiveLimit)
+				}
+				if r := atomic.LoadInt32(&running); r > atomic.LoadInt32(&maxRunning) {
+					atomic.StoreInt32(&maxRunning, r)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := pool.Stats()
+	if stats.EffectiveLimit >= numWorkers {
+		t.Errorf("Expected the limit to settle below %v, got %v", numWorkers, stats.EffectiveLimit)
+	}
+	if stats.EffectiveLimit < 2 {
+		t.Errorf("Expected the limit to grow above the minimum, got %v", stats.EffectiveLimit)
+	}
+	if stats.LatencyP95 == 0 {
+		t.Errorf("Expected a measured p95 latency")
+	}
+	t.Logf("Limit settled at %v with p95 %v", stats.EffectiveLimit, stats.LatencyP95)
+}
+
+func TestAdaptiveLimitBounds(t *testing.T) {
+	pool, err := CreatePool(4, func(in interface{}) interface{} {
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	}, WithAdaptiveLimit(2, 3, time.Nanosecond)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	// Every job misses the target so the limit should bottom out at the minimum
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 30; j++ {
+				pool.SendWorkTimed(1000*time.Millisecond, nil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if limit := pool.Stats().EffectiveLimit; limit != 2 {
+		t.Errorf("Expected the limit to stay at the minimum of 2, got %v", limit)
+	}
+}
@@ -0,0 +1,53 @@
+package goroutine
+
+import (
+	"runtime/metrics"
+)
+
+/*
+WithAllocSampling - Measures the memory allocated by every rate'th job, reporting it in the
+AllocBytes of the job's trace with AllocSampled set, to find the jobs behind GC pressure without
+a profiler. The figure is the growth of the process wide count of heap allocations while the job
+ran on its worker, so it is approximate: allocations by other goroutines during the job are
+counted too, and jobs sampled on a busy pool are overstated. Sampling reads runtime/metrics
+before and after the job, jobs which are not sampled cost nothing extra. Jobs are only sampled
+while a trace function is set, see SetTraceFunc. A rate of 0 or less disables sampling.
+*/
+func WithAllocSampling(rate int) Option {
+	return func(pool *WorkPool) {
+		if rate < 0 {
+			rate = 0
+		}
+		pool.allocSampling = uint64(rate)
+	}
+}
+
+/*
+sampleAllocs - Whether the job with sequence seq has its allocations measured.
+*/
+func (pool *WorkPool) sampleAllocs(seq uint64) bool {
+	return pool.allocSampling > 0 && seq%pool.allocSampling == 0
+}
+
+// heapAllocsMetric is the cumulative count of bytes allocated on the heap
+const heapAllocsMetric = "/gc/heap/allocs:bytes"
+
+/*
+heapAllocBytes - The bytes allocated on the heap by the process so far.
+*/
+func heapAllocBytes() uint64 {
+	sample := []metrics.Sample{{Name: heapAllocsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+/*
+measureAllocs - Records the allocations made since before in the result of a sampled job.
+*/
+func (result *jobResult) measureAllocs(before uint64) {
+	result.allocBytes = heapAllocBytes() - before
+	result.allocSampled = true
+}
@@ -0,0 +1,56 @@
+package goroutine
+
+import (
+	"sync"
+	"testing"
+)
+
+var allocSink []byte
+
+func TestAllocSampling(t *testing.T) {
+	for _, rate := range []int{1, 2} {
+		pool, err := CreatePool(1, func(in interface{}) interface{} {
+			for i := 0; i < 16; i++ {
+				allocSink = make([]byte, 64<<10)
+			}
+			return in
+		}, WithAllocSampling(rate)).Open()
+		if err != nil {
+			t.Errorf("Failed to create pool: %v", err)
+			return
+		}
+
+		var mutex sync.Mutex
+		var traces []JobTrace
+		pool.SetTraceFunc(func(trace JobTrace) {
+			mutex.Lock()
+			traces = append(traces, trace)
+			mutex.Unlock()
+		})
+
+		for i := 0; i < 4; i++ {
+			if _, err := pool.SendWork(i); err != nil {
+				t.Errorf("Failed to send work: %v", err)
+			}
+		}
+		pool.Close()
+
+		mutex.Lock()
+		for _, trace := range traces {
+			sampled := trace.Seq%uint64(rate) == 0
+			if trace.AllocSampled != sampled {
+				t.Errorf("Rate %v: Expected job %v sampled to be %v", rate, trace.Seq, sampled)
+			}
+			if sampled && trace.AllocBytes < 16*64<<10 {
+				t.Errorf("Rate %v: Expected job %v to allocate at least 1MB, got %v", rate, trace.Seq, trace.AllocBytes)
+			}
+			if !sampled && trace.AllocBytes != 0 {
+				t.Errorf("Rate %v: Expected no allocations reported for job %v, got %v", rate, trace.Seq, trace.AllocBytes)
+			}
+		}
+		if len(traces) != 4 {
+			t.Errorf("Rate %v: Expected 4 traces, got %v", rate, len(traces))
+		}
+		mutex.Unlock()
+	}
+}
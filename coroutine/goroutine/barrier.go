@@ -0,0 +1,118 @@
+package goroutine
+
+import (
+	"errors"
+	"sync"
+)
+
+/*
+SubmitBarrier - Runs fn exactly once after every job submitted before the barrier has completed,
+whatever its outcome, without closing the pool or holding back the jobs submitted after it. Each
+barrier fires at its own point, barriers fire in the order they were submitted, and a barrier
+submitted while no job is in flight fires straight away.
+
+A job counts from its admission by one of the Send functions until it leaves the pool, so the
+callback of an asynchronous job may still be running when the barrier fires, and jobs run on a
+borrowed or reserved worker are not waited for. Closing the pool does not cancel a barrier, it
+fires once the close has drained the jobs it waits for. fn is called on the goroutine which
+completed the last of those jobs, or on the caller's, and should return quickly.
+*/
+func (pool *WorkPool) SubmitBarrier(fn func()) error {
+	if fn == nil {
+		return errors.New("barrier function is nil")
+	}
+	if !pool.isRunning() {
+		return ErrPoolNotRunning
+	}
+	pool.barriers.add(fn)
+	return nil
+}
+
+/*
+barrierQueue - Splits the admitted jobs into epochs, one per barrier, each epoch counting its
+jobs in flight. The last epoch takes new jobs and has no barrier yet, the barrier of an older
+epoch fires once it and every epoch before it is empty. The zero value is ready to use.
+*/
+type barrierQueue struct {
+	mutex  sync.Mutex
+	epochs []*barrierEpoch
+
+	// ready holds barriers due to fire, firing is set while a goroutine is calling them so that
+	// they are called one at a time and in order
+	ready  []func()
+	firing bool
+}
+
+type barrierEpoch struct {
+	inFlight int
+	barrier  func()
+}
+
+// current returns the epoch taking new jobs, the mutex must be held
+func (queue *barrierQueue) current() *barrierEpoch {
+	if len(queue.epochs) == 0 {
+		queue.epochs = append(queue.epochs, &barrierEpoch{})
+	}
+	return queue.epochs[len(queue.epochs)-1]
+}
+
+/*
+enter - Counts an admitted job in the current epoch, which it must leave once it is done.
+*/
+func (queue *barrierQueue) enter() *barrierEpoch {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	epoch := queue.current()
+	epoch.inFlight++
+	return epoch
+}
+
+/*
+leave - Removes a job from its epoch, firing any barriers it was the last job holding back.
+*/
+func (queue *barrierQueue) leave(epoch *barrierEpoch) {
+	if epoch == nil {
+		return
+	}
+
+	queue.mutex.Lock()
+	epoch.inFlight--
+	queue.fire()
+}
+
+/*
+add - Closes the current epoch with the barrier and starts a new one.
+*/
+func (queue *barrierQueue) add(fn func()) {
+	queue.mutex.Lock()
+	queue.current().barrier = fn
+	queue.epochs = append(queue.epochs, &barrierEpoch{})
+	queue.fire()
+}
+
+// fire calls the barriers which are due, it is called with the mutex held and releases it
+func (queue *barrierQueue) fire() {
+	for len(queue.epochs) > 0 && queue.epochs[0].barrier != nil && queue.epochs[0].inFlight == 0 {
+		queue.ready = append(queue.ready, queue.epochs[0].barrier)
+		queue.epochs[0] = nil
+		queue.epochs = queue.epochs[1:]
+	}
+	if queue.firing {
+		queue.mutex.Unlock()
+		return
+	}
+
+	queue.firing = true
+	for len(queue.ready) > 0 {
+		fn := queue.ready[0]
+		queue.ready = queue.ready[1:]
+		queue.mutex.Unlock()
+
+		fn()
+
+		queue.mutex.Lock()
+	}
+	queue.firing = false
+	queue.mutex.Unlock()
+}
@@ -0,0 +1,123 @@
+package goroutine
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubmitBarrier(t *testing.T) {
+	gates := []chan struct{}{make(chan struct{}), make(chan struct{}), make(chan struct{})}
+
+	pool, err := CreatePool(4, func(in interface{}) interface{} {
+		<-gates[in.(int)]
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	var mutex sync.Mutex
+	var events []string
+	record := func(event string) {
+		mutex.Lock()
+		events = append(events, event)
+		mutex.Unlock()
+	}
+	fired := make(chan string, 2)
+
+	submit := func(n int) {
+		if err := pool.SendWorkAsync(n, func(interface{}, error) {}); err != nil {
+			t.Errorf("Failed to send work: %v", err)
+		}
+	}
+
+	submit(0)
+	if err := pool.SubmitBarrier(func() { record("first"); fired <- "first" }); err != nil {
+		t.Errorf("Failed to submit barrier: %v", err)
+	}
+	submit(1)
+	if err := pool.SubmitBarrier(func() { record("second"); fired <- "second" }); err != nil {
+		t.Errorf("Failed to submit barrier: %v", err)
+	}
+	submit(2)
+
+	// the jobs after a barrier are not held back by it
+	close(gates[2])
+	close(gates[1])
+	select {
+	case name := <-fired:
+		t.Errorf("Expected no barrier to fire while the first job runs, %v did", name)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(gates[0])
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case name := <-fired:
+			if name != expected {
+				t.Errorf("Expected barrier %v to fire, got %v", expected, name)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Timed out waiting for barrier %v", expected)
+		}
+	}
+
+	// a barrier with nothing in flight fires straight away
+	if err := pool.SubmitBarrier(func() { record("idle") }); err != nil {
+		t.Errorf("Failed to submit barrier: %v", err)
+	}
+
+	pool.Close()
+	time.Sleep(10 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(events) != 3 || events[0] != "first" || events[1] != "second" || events[2] != "idle" {
+		t.Errorf("Expected each barrier to fire once and in order, got %v", events)
+	}
+
+	if err := pool.SubmitBarrier(func() {}); err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning, got %v", err)
+	}
+}
+
+func TestSubmitBarrierClose(t *testing.T) {
+	gate := make(chan struct{})
+
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		<-gate
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	if err := pool.SendWorkAsync(0, func(interface{}, error) {}); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	fired := make(chan struct{}, 2)
+	if err := pool.SubmitBarrier(func() { fired <- struct{}{} }); err != nil {
+		t.Errorf("Failed to submit barrier: %v", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(gate)
+	}()
+	pool.Close()
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Errorf("Expected the barrier to fire after the close drained the pool")
+	}
+	select {
+	case <-fired:
+		t.Errorf("Expected the barrier to fire exactly once")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
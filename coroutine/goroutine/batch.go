@@ -0,0 +1,368 @@
+package goroutine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+Map - Sends every input to the pool as a separate job and returns the results in the order of the
+inputs. If submitting a job fails, or a job returns an error as its result, that error is returned
+as soon as it occurs along with a nil slice, the remaining jobs still run to completion in the
+background.
+*/
+func (pool *WorkPool) Map(inputs []interface{}) ([]interface{}, error) {
+	results := make([]interface{}, len(inputs))
+	failed := make(chan error, 1)
+	completed := make(chan struct{}, len(inputs))
+
+	fail := func(err error) {
+		select {
+		case failed <- err:
+		default:
+		}
+	}
+
+	for i, input := range inputs {
+		i := i
+		err := pool.sendWorkAsync(context.Background(), input, func(result interface{}, err error) {
+			if err == nil {
+				err, _ = result.(error)
+			}
+			if err != nil {
+				fail(err)
+			}
+			results[i] = result
+			completed <- struct{}{}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for range inputs {
+		select {
+		case err := <-failed:
+			return nil, err
+		case <-completed:
+		}
+	}
+
+	select {
+	case err := <-failed:
+		return nil, err
+	default:
+	}
+	return results, nil
+}
+
+/*
+Reduce - Folds inputs into initial with fn, spreading the work across the pool. The inputs are split
+into one chunk per worker, each chunk is folded on a worker starting from its first item, and the
+partial results are then folded serially into initial in the order of the chunks.
+
+Because items are combined with partial results and partial results with each other, fn must be
+associative and accept its own results as either argument, for example addition or max. The
+order of the inputs is preserved so fn need not be commutative. The chunks run on the workers of
+the pool in place of their job. If the pool closes before every chunk has been folded
+the error is returned, as is an error matching ErrJobPanicked if fn panics.
+*/
+func (pool *WorkPool) Reduce(inputs []interface{}, initial interface{}, fn func(acc, item interface{}) interface{}) (interface{}, error) {
+	chunks := pool.NumWorkers()
+	if chunks > len(inputs) {
+		chunks = len(inputs)
+	}
+
+	partials := make([]interface{}, chunks)
+	done := make(chan error, chunks)
+
+	for i := 0; i < chunks; i++ {
+		i := i
+		chunk := inputs[i*len(inputs)/chunks : (i+1)*len(inputs)/chunks]
+
+		err := pool.sendWorkAsync(context.Background(), jobCall(func() (interface{}, error) {
+			acc := chunk[0]
+			for _, item := range chunk[1:] {
+				acc = fn(acc, item)
+			}
+			return acc, nil
+		}), func(result interface{}, err error) {
+			partials[i] = result
+			done <- err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 0; i < chunks; i++ {
+		if err := <-done; err != nil {
+			return nil, err
+		}
+	}
+
+	acc := initial
+	for _, partial := range partials {
+		acc = fn(acc, partial)
+	}
+	return acc, nil
+}
+
+/*
+WithTreeReduce - Makes MapReduce fan the results of Map in over several rounds rather than passing
+them all to one call of the reduce function. For a reduce function which is expensive and
+associative this spreads the reducing across the workers, taking log2(N) rounds for N inputs.
+*/
+func WithTreeReduce() Option {
+	return func(pool *WorkPool) {
+		pool.treeReduce = true
+	}
+}
+
+/*
+MapReduce - Sends every input to the pool as Map does and, once every result has been collected,
+runs reduce on a worker with the results in the order of the inputs, returning its output. With
+WithTreeReduce the results are instead reduced in rounds, each round calling reduce in parallel on
+neighbouring pairs of the previous round's outputs until one is left, so reduce must then be
+associative and accept its own outputs among its inputs. An error from Map is returned as Map
+returns it, and an error matching ErrJobPanicked if reduce panics.
+*/
+func (pool *WorkPool) MapReduce(inputs []interface{}, reduce func([]interface{}) interface{}) (interface{}, error) {
+	results, err := pool.Map(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pool.treeReduce || len(results) < 2 {
+		reduced, err := pool.reduceGroups([][]interface{}{results}, reduce)
+		if err != nil {
+			return nil, err
+		}
+		return reduced[0], nil
+	}
+
+	for len(results) > 1 {
+		groups := make([][]interface{}, 0, (len(results)+1)/2)
+		for i := 0; i < len(results); i += 2 {
+			end := i + 2
+			if end > len(results) {
+				end = len(results)
+			}
+			groups = append(groups, results[i:end:end])
+		}
+		if results, err = pool.reduceGroups(groups, reduce); err != nil {
+			return nil, err
+		}
+	}
+	return results[0], nil
+}
+
+/*
+reduceGroups - Runs reduce on each group in parallel on the workers of the pool and returns the
+outputs in the order of the groups.
+*/
+func (pool *WorkPool) reduceGroups(groups [][]interface{}, reduce func([]interface{}) interface{}) ([]interface{}, error) {
+	outputs := make([]interface{}, len(groups))
+	done := make(chan error, len(groups))
+
+	for i, group := range groups {
+		i, group := i, group
+		err := pool.sendWorkAsync(context.Background(), jobCall(func() (interface{}, error) {
+			return reduce(group), nil
+		}), func(result interface{}, err error) {
+			outputs[i] = result
+			done <- err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for range groups {
+		if err := <-done; err != nil {
+			return nil, err
+		}
+	}
+	return outputs, nil
+}
+
+/*
+Batch - A handle on jobs submitted together with ForEach.
+*/
+type Batch struct {
+	remaining int64
+	done      chan struct{}
+}
+
+func newBatch(size int) *Batch {
+	batch := &Batch{
+		remaining: int64(size),
+		done:      make(chan struct{}),
+	}
+	if size == 0 {
+		close(batch.done)
+	}
+	return batch
+}
+
+func (batch *Batch) complete() {
+	if atomic.AddInt64(&batch.remaining, -1) == 0 {
+		close(batch.done)
+	}
+}
+
+/*
+Wait - Blocks until every job of the batch has completed and had its callback called, or ctx is
+done, in which case the error of ctx is returned and the jobs carry on.
+*/
+func (batch *Batch) Wait(ctx context.Context) error {
+	select {
+	case <-batch.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/*
+ForEach - Sends every input to the pool as a separate job without waiting and calls fn with the
+result of each job as it completes, in completion order. A job rejected at submission has fn
+called with the error straight away. The returned Batch can be used to wait for every callback.
+*/
+func (pool *WorkPool) ForEach(inputs []interface{}, fn func(interface{}, error)) *Batch {
+	batch := newBatch(len(inputs))
+
+	for _, input := range inputs {
+		err := pool.SendWorkAsync(input, func(result interface{}, err error) {
+			if fn != nil {
+				fn(result, err)
+			}
+			batch.complete()
+		})
+		if err != nil {
+			if fn != nil {
+				fn(nil, err)
+			}
+			batch.complete()
+		}
+	}
+	return batch
+}
+
+/*
+BatchError - Returned by SendWorkBatch and Pipeline.SendBatch when at least one item of a batch
+failed, it records the error of every failed item by its index in the batch. errors.Is and
+errors.As match the error of any failed item.
+*/
+type BatchError struct {
+	total  int
+	failed map[int]error
+}
+
+func newBatchError(total int) *BatchError {
+	return &BatchError{
+		total:  total,
+		failed: make(map[int]error),
+	}
+}
+
+/*
+Failed - The errors of the failed items keyed by their index in the batch.
+*/
+func (e *BatchError) Failed() map[int]error {
+	failed := make(map[int]error, len(e.failed))
+	for i, err := range e.failed {
+		failed[i] = err
+	}
+	return failed
+}
+
+/*
+Succeeded - The indices of the items which succeeded, in ascending order.
+*/
+func (e *BatchError) Succeeded() []int {
+	succeeded := make([]int, 0, e.total-len(e.failed))
+	for i := 0; i < e.total; i++ {
+		if _, failed := e.failed[i]; !failed {
+			succeeded = append(succeeded, i)
+		}
+	}
+	return succeeded
+}
+
+// Error summarises the failures, naming only the first of them
+func (e *BatchError) Error() string {
+	first := e.indices()[0]
+	return fmt.Sprintf("%d of %d jobs failed, first at index %d: %v", len(e.failed), e.total, first, e.failed[first])
+}
+
+// Unwrap returns the errors of the failed items in the order of their indices
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.failed))
+	for _, i := range e.indices() {
+		errs = append(errs, e.failed[i])
+	}
+	return errs
+}
+
+func (e *BatchError) indices() []int {
+	indices := make([]int, 0, len(e.failed))
+	for i := range e.failed {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	return indices
+}
+
+// errOrNil returns the batch error only if an item failed
+func (e *BatchError) errOrNil() error {
+	if len(e.failed) == 0 {
+		return nil
+	}
+	return e
+}
+
+/*
+SendWorkBatch - Sends every input to the pool as a separate job, waits for all of them and returns
+the results in the order of the inputs. Unlike Map a failure does not cut the batch short, every
+job runs and, if any was rejected or returned an error as its result, a *BatchError is returned
+alongside the results, where the result of each failed item is nil.
+*/
+func (pool *WorkPool) SendWorkBatch(inputs []interface{}) ([]interface{}, error) {
+	results := make([]interface{}, len(inputs))
+	batchErr := newBatchError(len(inputs))
+
+	var mutex sync.Mutex
+	fail := func(i int, err error) {
+		mutex.Lock()
+		batchErr.failed[i] = err
+		mutex.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		i := i
+		wg.Add(1)
+		err := pool.SendWorkAsync(input, func(result interface{}, err error) {
+			defer wg.Done()
+			if err == nil {
+				err, _ = result.(error)
+			}
+			if err != nil {
+				fail(i, err)
+				return
+			}
+			results[i] = result
+		})
+		if err != nil {
+			fail(i, err)
+			wg.Done()
+		}
+	}
+	wg.Wait()
+
+	return results, batchErr.errOrNil()
+}
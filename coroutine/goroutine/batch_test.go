@@ -0,0 +1,284 @@
+package goroutine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	pool, err := CreatePool(4, func(in interface{}) interface{} {
+		n := in.(int)
+		time.Sleep(time.Duration(10-n) * time.Millisecond)
+		return n * n
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	inputs := make([]interface{}, 10)
+	for i := range inputs {
+		inputs[i] = i
+	}
+
+	results, err := pool.Map(inputs)
+	if err != nil {
+		t.Errorf("Map failed: %v", err)
+		return
+	}
+	for i, result := range results {
+		if result != i*i {
+			t.Errorf("Expected %v at %v, got %v", i*i, i, result)
+		}
+	}
+}
+
+func TestMapError(t *testing.T) {
+	errBad := errors.New("bad input")
+	release := make(chan struct{})
+
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		if in == "bad" {
+			return errBad
+		}
+		<-release
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	// The error is returned while the other job is still running
+	results, err := pool.Map([]interface{}{"slow", "bad"})
+	if err != errBad || results != nil {
+		t.Errorf("Expected %v, got %v, %v", errBad, results, err)
+	}
+	close(release)
+}
+
+func TestForEach(t *testing.T) {
+	pool, err := CreatePool(4, func(in interface{}) interface{} {
+		time.Sleep(time.Millisecond)
+		return in.(int) * 2
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	inputs := make([]interface{}, 20)
+	for i := range inputs {
+		inputs[i] = i
+	}
+
+	var sum, calls int64
+	batch := pool.ForEach(inputs, func(result interface{}, err error) {
+		if err != nil {
+			t.Errorf("Job failed: %v", err)
+			return
+		}
+		atomic.AddInt64(&sum, int64(result.(int)))
+		atomic.AddInt64(&calls, 1)
+	})
+	if err := batch.Wait(context.Background()); err != nil {
+		t.Errorf("Wait failed: %v", err)
+	}
+	if calls != 20 || sum != 380 {
+		t.Errorf("Expected 20 callbacks summing to 380, got %v summing to %v", calls, sum)
+	}
+}
+
+func TestForEachWaitContext(t *testing.T) {
+	release := make(chan struct{})
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		<-release
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	batch := pool.ForEach([]interface{}{1, 2}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := batch.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected the context to expire, got %v", err)
+	}
+
+	close(release)
+	if err := batch.Wait(context.Background()); err != nil {
+		t.Errorf("Wait failed: %v", err)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	pool, err := CreatePool(3, func(in interface{}) interface{} { return in }).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	inputs := make([]interface{}, 10)
+	for i := range inputs {
+		inputs[i] = string(rune('a' + i))
+	}
+
+	// Concatenation is associative but not commutative, so this also checks the order
+	result, err := pool.Reduce(inputs, ">", func(acc, item interface{}) interface{} {
+		return acc.(string) + item.(string)
+	})
+	if err != nil || result != ">abcdefghij" {
+		t.Errorf("Expected >abcdefghij, got %v, %v", result, err)
+	}
+
+	result, err = pool.Reduce(inputs[:2], ">", func(acc, item interface{}) interface{} {
+		return acc.(string) + item.(string)
+	})
+	if err != nil || result != ">ab" {
+		t.Errorf("Expected >ab with fewer inputs than workers, got %v, %v", result, err)
+	}
+
+	result, err = pool.Reduce(nil, 7, func(acc, item interface{}) interface{} {
+		return acc.(int) + item.(int)
+	})
+	if err != nil || result != 7 {
+		t.Errorf("Expected the initial value for no inputs, got %v, %v", result, err)
+	}
+
+	result, err = pool.Reduce(inputs, "", func(acc, item interface{}) interface{} {
+		panic("reduce panicked")
+	})
+	if !errors.Is(err, ErrJobPanicked) || result != nil {
+		t.Errorf("Expected ErrJobPanicked, got %v, %v", result, err)
+	}
+
+	pool.Close()
+	if _, err := pool.Reduce(inputs, "", func(acc, item interface{}) interface{} {
+		return acc
+	}); err == nil {
+		t.Errorf("Expected an error reducing on a closed pool")
+	}
+}
+
+func TestMapReduce(t *testing.T) {
+	inputs := make([]interface{}, 10)
+	for i := range inputs {
+		inputs[i] = string(rune('a' + i))
+	}
+
+	// Concatenation is associative but not commutative, so this also checks the order
+	var calls int32
+	concat := func(items []interface{}) interface{} {
+		atomic.AddInt32(&calls, 1)
+		var s string
+		for _, item := range items {
+			s += item.(string)
+		}
+		return s
+	}
+
+	for _, tree := range []bool{false, true} {
+		opts := []Option{}
+		if tree {
+			opts = append(opts, WithTreeReduce())
+		}
+		pool, err := CreatePool(3, func(in interface{}) interface{} {
+			return strings.ToUpper(in.(string))
+		}, opts...).Open()
+		if err != nil {
+			t.Errorf("Failed to create pool: %v", err)
+			return
+		}
+
+		atomic.StoreInt32(&calls, 0)
+		result, err := pool.MapReduce(inputs, concat)
+		if err != nil || result != "ABCDEFGHIJ" {
+			t.Errorf("Expected ABCDEFGHIJ with tree %v, got %v, %v", tree, result, err)
+		}
+
+		// Ten results take 5+3+2+1 calls over four rounds reduced as a tree
+		expected := int32(1)
+		if tree {
+			expected = 11
+		}
+		if n := atomic.LoadInt32(&calls); n != expected {
+			t.Errorf("Expected %v calls of reduce with tree %v, got %v", expected, tree, n)
+		}
+
+		result, err = pool.MapReduce(nil, concat)
+		if err != nil || result != "" {
+			t.Errorf("Expected reduce of no results with tree %v, got %v, %v", tree, result, err)
+		}
+
+		result, err = pool.MapReduce(inputs, func([]interface{}) interface{} {
+			panic("reduce panicked")
+		})
+		if !errors.Is(err, ErrJobPanicked) || result != nil {
+			t.Errorf("Expected ErrJobPanicked with tree %v, got %v, %v", tree, result, err)
+		}
+		pool.Close()
+	}
+}
+
+func TestSendWorkBatch(t *testing.T) {
+	errNegative := errors.New("negative input")
+	pool, err := CreatePool(4, func(in interface{}) interface{} {
+		if in.(int) < 0 {
+			return fmt.Errorf("input %v: %w", in, errNegative)
+		}
+		return in.(int) * 2
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	inputs := []interface{}{1, -2, 3, 4, -5, 6}
+	results, err := pool.SendWorkBatch(inputs)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected a *BatchError, got %v", err)
+	}
+	if !errors.Is(err, errNegative) {
+		t.Errorf("Expected errors.Is to match the error of a failed item")
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "2 of 6 jobs failed, first at index 1") {
+		t.Errorf("Expected a summary of the failures, got %q", msg)
+	}
+
+	failed := batchErr.Failed()
+	if len(failed) != 2 || !errors.Is(failed[1], errNegative) || !errors.Is(failed[4], errNegative) {
+		t.Errorf("Expected indices 1 and 4 to fail, got %v", failed)
+	}
+	if succeeded := batchErr.Succeeded(); !reflect.DeepEqual(succeeded, []int{0, 2, 3, 5}) {
+		t.Errorf("Expected indices 0, 2, 3 and 5 to succeed, got %v", succeeded)
+	}
+	if expected := []interface{}{2, nil, 6, 8, nil, 12}; !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %v, got %v", expected, results)
+	}
+
+	if results, err := pool.SendWorkBatch([]interface{}{1, 2}); err != nil || !reflect.DeepEqual(results, []interface{}{2, 4}) {
+		t.Errorf("Expected [2 4] and no error, got %v, %v", results, err)
+	}
+
+	// Items rejected by a closed pool are failures of the batch too
+	pool.Close()
+	_, err = pool.SendWorkBatch([]interface{}{1})
+	if !errors.As(err, &batchErr) || !errors.Is(err, ErrPoolNotRunning) || len(batchErr.Succeeded()) != 0 {
+		t.Errorf("Expected the item to fail with ErrPoolNotRunning, got %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package goroutine
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCircuitOpen = errors.New("the circuit breaker of the pool is open")
+)
+
+/*
+CircuitState - The state of the circuit breaker of a pool.
+*/
+type CircuitState int32
+
+const (
+	// Jobs are accepted and consecutive failures are counted
+	CircuitClosed CircuitState = iota
+
+	// Jobs are rejected with ErrCircuitOpen until the cooldown has passed
+	CircuitOpen
+
+	// A limited number of probe jobs are accepted to test whether the failures have stopped
+	CircuitHalfOpen
+)
+
+func (state CircuitState) String() string {
+	switch state {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+/*
+CircuitBreakerConfig - Settings of the circuit breaker of a pool. A job fails when it could not be
+run, for example because it timed out or panicked, or when it returns an error as its result.
+*/
+type CircuitBreakerConfig struct {
+	// Consecutive failures which open the breaker
+	FailureThreshold int
+
+	// How long the breaker stays open before letting probe jobs through
+	Cooldown time.Duration
+
+	// Probe jobs accepted while half open, the breaker closes once all of them succeed and opens
+	// again as soon as one fails. Defaults to 1.
+	Probes int
+
+	// Called on every change of state, after the change has been made
+	OnStateChange func(from, to CircuitState)
+}
+
+/*
+WithCircuitBreaker - Stops a pool from grinding through jobs which are bound to fail. After
+FailureThreshold consecutive failures new submissions fail immediately with ErrCircuitOpen for
+the Cooldown, then Probes jobs are let through and the breaker closes again if they all succeed.
+Rejected submissions are counted in Stats as rejected jobs.
+*/
+func WithCircuitBreaker(config CircuitBreakerConfig) Option {
+	return func(pool *WorkPool) {
+		if config.FailureThreshold < 1 {
+			config.FailureThreshold = 1
+		}
+		if config.Probes < 1 {
+			config.Probes = 1
+		}
+		pool.breaker = &circuitBreaker{config: config}
+	}
+}
+
+/*
+CircuitState - The current state of the circuit breaker of the pool, always CircuitClosed for a
+pool created without WithCircuitBreaker.
+*/
+func (pool *WorkPool) CircuitState() CircuitState {
+	return pool.breaker.currentState()
+}
+
+/*
+circuitBreaker - All methods are safe to call on a nil breaker, which accepts every job. Probe
+jobs are identified by the half open round they were accepted in, so that late results of an
+earlier round are ignored.
+*/
+type circuitBreaker struct {
+	mutex     sync.Mutex
+	config    CircuitBreakerConfig
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	clock     Clock
+	round     uint64
+	probes    int
+	successes int
+}
+
+/*
+allow - Decides whether a job may be submitted, returning the half open round for probe jobs or
+zero for any other job.
+*/
+func (breaker *circuitBreaker) allow() (uint64, error) {
+	if breaker == nil {
+		return 0, nil
+	}
+
+	breaker.mutex.Lock()
+	from := breaker.state
+
+	if breaker.state == CircuitOpen {
+		if breaker.clock.Now().Sub(breaker.openedAt) < breaker.config.Cooldown {
+			breaker.mutex.Unlock()
+			return 0, ErrCircuitOpen
+		}
+		breaker.state = CircuitHalfOpen
+		breaker.round++
+		breaker.probes = 0
+		breaker.successes = 0
+	}
+
+	var probe uint64
+	var err error
+	if breaker.state == CircuitHalfOpen {
+		if breaker.probes < breaker.config.Probes {
+			breaker.probes++
+			probe = breaker.round
+		} else {
+			err = ErrCircuitOpen
+		}
+	}
+	to := breaker.state
+	breaker.mutex.Unlock()
+
+	breaker.notify(from, to)
+	return probe, err
+}
+
+/*
+done - Records the outcome of a job accepted by allow.
+*/
+func (breaker *circuitBreaker) done(probe uint64, result interface{}, err error) {
+	if breaker == nil {
+		return
+	}
+	failed := err != nil
+	if !failed {
+		_, failed = result.(error)
+	}
+
+	breaker.mutex.Lock()
+	from := breaker.state
+
+	switch breaker.state {
+	case CircuitClosed:
+		if !failed {
+			breaker.failures = 0
+		} else if breaker.failures++; breaker.failures >= breaker.config.FailureThreshold {
+			breaker.open()
+		}
+	case CircuitHalfOpen:
+		if probe != breaker.round {
+			break
+		}
+		if failed {
+			breaker.open()
+		} else if breaker.successes++; breaker.successes >= breaker.config.Probes {
+			breaker.state = CircuitClosed
+			breaker.failures = 0
+		}
+	}
+	to := breaker.state
+	breaker.mutex.Unlock()
+
+	breaker.notify(from, to)
+}
+
+/*
+cancel - Returns the slot of a probe job which was accepted but never run.
+*/
+func (breaker *circuitBreaker) cancel(probe uint64) {
+	if breaker == nil || probe == 0 {
+		return
+	}
+
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	if breaker.state == CircuitHalfOpen && probe == breaker.round {
+		breaker.probes--
+	}
+}
+
+func (breaker *circuitBreaker) open() {
+	breaker.state = CircuitOpen
+	breaker.openedAt = breaker.clock.Now()
+}
+
+func (breaker *circuitBreaker) notify(from, to CircuitState) {
+	if from != to && breaker.config.OnStateChange != nil {
+		breaker.config.OnStateChange(from, to)
+	}
+}
+
+func (breaker *circuitBreaker) currentState() CircuitState {
+	if breaker == nil {
+		return CircuitClosed
+	}
+
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	return breaker.state
+}
@@ -0,0 +1,102 @@
+package goroutine
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	errDown := errors.New("downstream is down")
+	var down int32 = 1
+	block := make(chan struct{})
+
+	var mutex sync.Mutex
+	var transitions []string
+
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		if in == "block" {
+			<-block
+		}
+		if atomic.LoadInt32(&down) == 1 {
+			return errDown
+		}
+		return in
+	}, WithCircuitBreaker(CircuitBreakerConfig{
+		FailureThreshold: 3,
+		Cooldown:         30 * time.Millisecond,
+		Probes:           2,
+		OnStateChange: func(from, to CircuitState) {
+			mutex.Lock()
+			transitions = append(transitions, fmt.Sprintf("%v->%v", from, to))
+			mutex.Unlock()
+		},
+	})).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	expect := func(step string, jobData interface{}, expected error) {
+		result, err := pool.SendWork(jobData)
+		if err == nil {
+			err, _ = result.(error)
+		}
+		if err != expected {
+			t.Errorf("%v: expected %v, got %v", step, expected, err)
+		}
+	}
+
+	// Three accepted failures open the breaker
+	for i := 0; i < 3; i++ {
+		expect("failing", nil, errDown)
+	}
+	if state := pool.CircuitState(); state != CircuitOpen {
+		t.Errorf("Expected the breaker to be open, got %v", state)
+	}
+	expect("open", nil, ErrCircuitOpen)
+
+	// A failing probe opens the breaker again
+	time.Sleep(40 * time.Millisecond)
+	expect("failing probe", nil, errDown)
+	expect("reopened", nil, ErrCircuitOpen)
+
+	// Two probes are let through, further jobs are rejected until they succeed
+	atomic.StoreInt32(&down, 0)
+	time.Sleep(40 * time.Millisecond)
+
+	probed := make(chan error)
+	go func() {
+		_, err := pool.SendWork("block")
+		probed <- err
+	}()
+	for pool.CircuitState() != CircuitHalfOpen {
+		time.Sleep(time.Millisecond)
+	}
+	expect("second probe", nil, nil)
+	expect("probes exhausted", nil, ErrCircuitOpen)
+
+	close(block)
+	if err := <-probed; err != nil {
+		t.Errorf("Blocked probe failed: %v", err)
+	}
+	if state := pool.CircuitState(); state != CircuitClosed {
+		t.Errorf("Expected the breaker to be closed, got %v", state)
+	}
+	expect("closed", nil, nil)
+
+	if rejected := pool.Stats().RejectedJobs; rejected != 3 {
+		t.Errorf("Expected 3 rejected jobs, got %v", rejected)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	expected := "[closed->open open->half-open half-open->open open->half-open half-open->closed]"
+	if fmt.Sprint(transitions) != expected {
+		t.Errorf("Expected transitions %v, got %v", expected, transitions)
+	}
+}
@@ -0,0 +1,104 @@
+package goroutine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+Broadcast - Sends the same job to every worker of the pool at once, for work that each worker
+must see such as reloading configuration or invalidating per worker state, and returns the result
+of each worker in worker order. Unlike SendWork, which hands a job to whichever worker is free,
+every worker runs the job exactly once, a worker busy with another job runs it once that job is
+done and workers of a lazy pool which have not started yet are started. Workers stopped with
+StopWorker are skipped, leaving their results nil. Each worker gets its own copy of the payload if
+the pool has a payload cloner.
+
+The call blocks until every worker has responded or ctx is done. The first error is returned
+along with the results gathered so far: ErrJobCancelled wrapping the error of ctx, or a
+*PanicError if the job panicked on a worker. Workers still running the job when ctx is done are interrupted and their results
+discarded.
+*/
+func (pool *WorkPool) Broadcast(ctx context.Context, jobData interface{}) ([]interface{}, error) {
+	pool.statusMutex.RLock()
+	defer pool.statusMutex.RUnlock()
+
+	if !pool.isRunning() {
+		return nil, ErrPoolNotRunning
+	}
+	for pool.startWorker() {
+	}
+
+	results := make([]interface{}, len(pool.workers))
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i, worker := range pool.workers {
+		if atomic.LoadUint32(&worker.stopped) == 1 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := pool.broadcastTo(ctx, i, pool.clonePayload(jobData))
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+				})
+				return
+			}
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+
+	return results, firstErr
+}
+
+/*
+broadcastTo - Waits for one particular worker to be ready, then runs a job on it.
+*/
+func (pool *WorkPool) broadcastTo(ctx context.Context, chosen int, jobData interface{}) (interface{}, error) {
+	job, trace, enqueued := pool.newJob(jobData)
+	defer job.tracked.abandon()
+	worker := pool.workers[chosen]
+
+	select {
+	case _, open := <-worker.readyChan:
+		if !open {
+			return nil, ErrWorkerClosed
+		}
+	case <-ctx.Done():
+		err, outcome := expired(ctx, job, false)
+		pool.finishJob(trace, job, -1, enqueued, jobResult{}, outcome)
+		return nil, err
+	}
+
+	output, reply := worker.dispatch(job)
+
+	select {
+	case result, open := <-output:
+		collected(output, reply)
+		if !open {
+			return nil, ErrWorkerClosed
+		}
+		pool.finishJob(trace, job, chosen, enqueued, result, JobOK)
+		if result.panicked {
+			return nil, result.panicError()
+		}
+		return result.data, result.err
+	case <-ctx.Done():
+	}
+
+	err, outcome := expired(ctx, job, true)
+	worker.interruptJob()
+	go func() {
+		result := <-output
+		collected(output, reply)
+		pool.finishJob(trace, job, chosen, enqueued, result, outcome)
+	}()
+	return nil, err
+}
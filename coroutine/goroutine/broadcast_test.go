@@ -0,0 +1,89 @@
+package goroutine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// reloadWorker records the broadcasts it has seen
+type reloadWorker struct {
+	index int
+	seen  []interface{}
+}
+
+func (w *reloadWorker) Job(in interface{}) interface{} {
+	if in == "slow" {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+	w.seen = append(w.seen, in)
+	return w.index
+}
+
+func (w *reloadWorker) Ready() bool {
+	return true
+}
+
+func TestBroadcast(t *testing.T) {
+	workers := make([]*reloadWorker, 4)
+	customWorkers := make([]GoroutineWorker, len(workers))
+	for i := range workers {
+		workers[i] = &reloadWorker{index: i}
+		customWorkers[i] = workers[i]
+	}
+
+	pool, err := CreateCustomPool(customWorkers, WithLazyStart()).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	// A busy worker runs the broadcast once its current job is done
+	pool.SendWorkAsync("slow", nil)
+
+	results, err := pool.Broadcast(context.Background(), "reload")
+	if err != nil {
+		t.Errorf("Failed to broadcast: %v", err)
+	}
+	for i, result := range results {
+		if result != i {
+			t.Errorf("Expected the result of worker %v, got %v", i, result)
+		}
+	}
+
+	pool.Close()
+	for i, worker := range workers {
+		if len(worker.seen) != 1 || worker.seen[0] != "reload" {
+			t.Errorf("Expected worker %v to see the broadcast once, saw %v", i, worker.seen)
+		}
+	}
+
+	if _, err := pool.Broadcast(context.Background(), "reload"); err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning, got %v", err)
+	}
+}
+
+func TestBroadcastContext(t *testing.T) {
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		time.Sleep(in.(time.Duration))
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := pool.Broadcast(ctx, 100*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Expected Broadcast to give up at the deadline, took %v", elapsed)
+	}
+}
@@ -0,0 +1,103 @@
+package goroutine
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrBorrowBuffered = errors.New("workers of a buffered pool cannot be borrowed")
+)
+
+/*
+CreateCustomPoolBuffered - Creates a pool of custom workers as CreateCustomPool does, where each
+worker may have up to bufDepth jobs queued on it. Rather than handing each job to a worker the
+moment it reports ready, the pool queues jobs on workers with room for them, which saves the
+handoff for workers doing very small jobs at the cost of a job possibly waiting behind others
+while a different worker is idle. A bufDepth of 0 or less creates an ordinary pool.
+
+Queued jobs are not abandoned by Close, which waits for every worker to run the jobs queued on
+it. A job that times out while queued still runs, and as the worker may be busy with the job of
+another caller it is not interrupted. Workers of a buffered pool cannot be borrowed.
+*/
+func CreateCustomPoolBuffered(customWorkers []GoroutineWorker, bufDepth int, opts ...Option) *WorkPool {
+	if bufDepth < 0 {
+		bufDepth = 0
+	}
+	pool, _ := newPool(append([]Option{WithCustomWorkers(customWorkers), WithQueueSize(bufDepth)}, opts...))
+	return pool
+}
+
+/*
+replyPool - Result channels for jobs queued on buffered workers, every job needs its own as the
+worker's jobs belong to different callers.
+*/
+var replyPool = sync.Pool{
+	New: func() interface{} {
+		return make(chan jobResult, 1)
+	},
+}
+
+/*
+bufferedLoop - The loop of a buffered worker. The worker keeps one ready signal in its ready
+channel for each job it has room for, so the pool can queue a job on it whenever a signal is
+taken, and it sends each result to the reply channel of its job.
+*/
+func (wrapper *workerWrapper) bufferedLoop() {
+	wrapper.waitReady()
+	wrapper.signalReadied()
+	for i := 0; i < wrapper.bufDepth; i++ {
+		wrapper.readyChan <- 1
+	}
+	atomic.StoreUint32(&wrapper.idle, 1)
+
+	for job := range wrapper.jobChan {
+		result := wrapper.run(job)
+		job.reply <- result
+		if result.panicked {
+			wrapper.reinitialize()
+		}
+		wrapper.waitReady()
+		wrapper.readyChan <- 1
+	}
+
+	atomic.StoreUint32(&wrapper.idle, 0)
+	close(wrapper.readyChan)
+	close(wrapper.outputChan)
+}
+
+/*
+dispatch - Hands a job to a worker that has signalled it is ready and returns the channel its
+result is sent on, along with whether that channel is a reply channel to be recycled.
+*/
+func (wrapper *workerWrapper) dispatch(job jobRequest) (chan jobResult, bool) {
+	if wrapper.bufDepth == 0 && !wrapper.shared {
+		wrapper.jobChan <- job
+		return wrapper.outputChan, false
+	}
+	job.reply = replyPool.Get().(chan jobResult)
+	wrapper.jobChan <- job
+	return job.reply, true
+}
+
+/*
+collected - Called once the result of a dispatched job has been received from output, reply is
+as returned by dispatch. The result of a job given up on may only be received after Close, when
+the next Open may be setting the worker up afresh, so this must not look at the worker.
+*/
+func collected(output chan jobResult, reply bool) {
+	if reply {
+		replyPool.Put(output)
+	}
+}
+
+/*
+interruptJob - Interrupts the worker running a job that has been given up on, unless the worker
+is buffered, in which case the job it is running may not be the one given up on.
+*/
+func (wrapper *workerWrapper) interruptJob() {
+	if wrapper.bufDepth == 0 && !wrapper.shared {
+		wrapper.Interrupt()
+	}
+}
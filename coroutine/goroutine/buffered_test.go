@@ -0,0 +1,142 @@
+package goroutine
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// spinWorker echoes its input after working for a fixed time
+type spinWorker struct {
+	work time.Duration
+}
+
+func (w *spinWorker) Job(in interface{}) interface{} {
+	for start := time.Now(); time.Since(start) < w.work; {
+	}
+	return in
+}
+
+func (w *spinWorker) Ready() bool {
+	return true
+}
+
+func spinWorkers(n int, work time.Duration) []GoroutineWorker {
+	workers := make([]GoroutineWorker, n)
+	for i := range workers {
+		workers[i] = &spinWorker{work: work}
+	}
+	return workers
+}
+
+func TestBufferedPool(t *testing.T) {
+	pool, err := CreateCustomPoolBuffered(spinWorkers(2, 100*time.Microsecond), 4).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	// Results must reach the caller that submitted each job
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if result, err := pool.SendWork(i*100 + j); err != nil || result != i*100+j {
+					t.Errorf("Expected %v, got %v, %v", i*100+j, result, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if _, _, err := pool.BorrowWorker(); err != ErrBorrowBuffered {
+		t.Errorf("Expected ErrBorrowBuffered, got %v", err)
+	}
+
+	// Close runs every job still queued on the workers
+	var completed int32
+	for i := 0; i < 8; i++ {
+		if err := pool.SendWorkAsync(i, func(interface{}, error) {
+			atomic.AddInt32(&completed, 1)
+		}); err != nil {
+			t.Errorf("Failed to send work: %v", err)
+		}
+	}
+	for pool.NumPendingAsyncJobs() > 0 && pool.Stats().IdleWorkers == 2 {
+		time.Sleep(time.Millisecond)
+	}
+	pool.Close()
+	for pool.NumPendingAsyncJobs() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if completed := atomic.LoadInt32(&completed); completed != 8 {
+		t.Errorf("Expected every queued job to complete, got %v", completed)
+	}
+}
+
+// gateWorker signals when it starts a job and blocks until released
+type gateWorker struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *gateWorker) Job(in interface{}) interface{} {
+	w.started <- struct{}{}
+	<-w.release
+	return in
+}
+
+func (w *gateWorker) Ready() bool {
+	return true
+}
+
+func TestBufferedPoolTimeout(t *testing.T) {
+	worker := &gateWorker{started: make(chan struct{}, 3), release: make(chan struct{})}
+	pool, err := CreateCustomPoolBuffered([]GoroutineWorker{worker}, 2).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	// The second job is queued behind the first and gives up before it starts
+	pool.SendWorkAsync("first", nil)
+	<-worker.started
+	if _, err := pool.SendWorkTimed(5*time.Millisecond, "second"); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut, got %v", err)
+	}
+	close(worker.release)
+
+	if result, err := pool.SendWorkTimed(1000*time.Millisecond, "third"); err != nil || result != "third" {
+		t.Errorf("Expected the next job to succeed, got %v, %v", result, err)
+	}
+}
+
+func benchmarkBuffered(b *testing.B, bufDepth int) {
+	pool, err := CreateCustomPoolBuffered(spinWorkers(4, 10*time.Microsecond), bufDepth).Open()
+	if err != nil {
+		b.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pool.SendWork(10)
+		}
+	})
+}
+
+func BenchmarkBufferedDepth0(b *testing.B) {
+	benchmarkBuffered(b, 0)
+}
+
+func BenchmarkBufferedDepth16(b *testing.B) {
+	benchmarkBuffered(b, 16)
+}
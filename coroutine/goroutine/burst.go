@@ -0,0 +1,154 @@
+package goroutine
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrBurstCustom = errors.New("a pool of custom workers cannot have burst workers, it has no job to give them")
+)
+
+/*
+WithBurstWorkers - Lets the pool absorb short bursts with up to extra temporary workers running the
+pool's job. When every worker is busy and a job is waiting, a burst worker is started for it, and
+it goes on to take other waiting jobs until none is left, when it exits, or until it has been
+running for maxBurstDuration, when it exits once its job is done. Burst workers are not counted by
+NumWorkers, see NumBurstWorkers, and they are not handed jobs sent with a timeout or context. A
+pool of custom workers has no job to give burst workers and fails to Open with ErrBurstCustom.
+*/
+func WithBurstWorkers(extra int, maxBurstDuration time.Duration) Option {
+	return func(pool *WorkPool) {
+		if extra <= 0 {
+			pool.config.fail("burst workers %d must be at least 1", extra)
+			return
+		}
+		if maxBurstDuration <= 0 {
+			pool.config.fail("burst duration %v must be positive", maxBurstDuration)
+			return
+		}
+		pool.burst = &burstWorkers{
+			extra:       int32(extra),
+			maxDuration: maxBurstDuration,
+			jobs:        make(chan burstJob),
+		}
+	}
+}
+
+/*
+NumBurstWorkers - Number of burst workers started WithBurstWorkers which are running.
+*/
+func (pool *WorkPool) NumBurstWorkers() int {
+	if pool.burst == nil {
+		return 0
+	}
+	return int(atomic.LoadInt32(&pool.burst.running))
+}
+
+/*
+burstWorkers - The temporary workers of a pool, idle burst workers do not wait around so jobs are
+only handed to those which are finishing a job.
+*/
+type burstWorkers struct {
+	extra       int32
+	maxDuration time.Duration
+	running     int32
+	jobs        chan burstJob
+}
+
+/*
+burstJob - A job handed to a burst worker, the result is sent to reply.
+*/
+type burstJob struct {
+	job   jobRequest
+	reply chan jobResult
+}
+
+/*
+selectWorker - Waits for a worker for a job while every worker is busy, as sendWork does, starting
+a burst worker for the job if there is room for one. Returns the reply channel if the job was
+handed to a burst worker, otherwise the worker chosen as reflect.Select returns it.
+*/
+func (pool *WorkPool) selectWorker(job jobRequest) (int, bool, chan jobResult) {
+	if pool.burst == nil {
+		chosen, _, ok := reflect.Select(pool.selects)
+		return chosen, ok, nil
+	}
+
+	handoff := burstJob{job: job, reply: make(chan jobResult, 1)}
+	if pool.burst.reserve() {
+		go pool.burstLoop(handoff)
+		return -1, true, handoff.reply
+	}
+
+	selectCases := append(pool.selects[:len(pool.selects):len(pool.selects)],
+		reflect.SelectCase{
+			Dir:  reflect.SelectSend,
+			Chan: reflect.ValueOf(pool.burst.jobs),
+			Send: reflect.ValueOf(handoff),
+		},
+	)
+	chosen, _, ok := reflect.Select(selectCases)
+	if chosen == len(pool.selects) {
+		return -1, true, handoff.reply
+	}
+	return chosen, ok, nil
+}
+
+// reserve takes a place for a new burst worker, false if extra are running
+func (burst *burstWorkers) reserve() bool {
+	for {
+		running := atomic.LoadInt32(&burst.running)
+		if running >= burst.extra {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&burst.running, running, running+1) {
+			return true
+		}
+	}
+}
+
+/*
+burstLoop - Runs a burst worker, starting with its first job.
+*/
+func (pool *WorkPool) burstLoop(handoff burstJob) {
+	defer atomic.AddInt32(&pool.burst.running, -1)
+
+	wrapper := &workerWrapper{
+		worker: &(defaultWorker{pool.job}),
+		index:  -1,
+		logger: &pool.logger,
+		clock:  pool.clock,
+		panics: pool.panics,
+	}
+	started := pool.clock.Now()
+	for {
+		handoff.reply <- wrapper.run(handoff.job)
+
+		if pool.clock.Now().Sub(started) >= pool.burst.maxDuration {
+			return
+		}
+		select {
+		case handoff = <-pool.burst.jobs:
+		default:
+			return
+		}
+	}
+}
+
+/*
+collectBurst - Waits for the result of a job handed to a burst worker.
+*/
+func (pool *WorkPool) collectBurst(reply chan jobResult, job jobRequest, trace TraceFunc, enqueued time.Time) (interface{}, error) {
+	result := <-reply
+	if result.skipped {
+		return nil, result.err
+	}
+	pool.finishJob(trace, job, -1, enqueued, result, JobOK)
+	if result.panicked {
+		return nil, result.panicError()
+	}
+	return result.data, result.err
+}
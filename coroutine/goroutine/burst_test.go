@@ -0,0 +1,76 @@
+package goroutine
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBurstWorkers(t *testing.T) {
+	var running, peak int32
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return in
+	}, WithBurstWorkers(4, time.Second)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	const jobs = 100
+	var wg sync.WaitGroup
+	wg.Add(jobs)
+	start := time.Now()
+	for i := 0; i < jobs; i++ {
+		if err := pool.SendWorkAsync(i, func(interface{}, error) { wg.Done() }); err != nil {
+			t.Errorf("Failed to send job: %v", err)
+			wg.Done()
+		}
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// 100 jobs of 10ms take 500ms on 2 workers and about 170ms on 6
+	if elapsed > 350*time.Millisecond {
+		t.Errorf("Burst took %v, expected about 170ms", elapsed)
+	}
+	if p := atomic.LoadInt32(&peak); p != 6 {
+		t.Errorf("Expected 6 jobs running at the peak, got %v", p)
+	}
+	if n := pool.NumWorkers(); n != 2 {
+		t.Errorf("Expected burst workers to be left out of NumWorkers, got %v", n)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for pool.NumBurstWorkers() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := pool.NumBurstWorkers(); n != 0 {
+		t.Errorf("Expected the burst workers to be gone, got %v", n)
+	}
+
+	// A lone job runs on a regular worker
+	if result, err := pool.SendWork(7); err != nil || result != 7 {
+		t.Errorf("Expected 7, got %v, %v", result, err)
+	}
+	if n := pool.NumBurstWorkers(); n != 0 {
+		t.Errorf("Expected no burst worker for a lone job, got %v", n)
+	}
+}
+
+func TestBurstWorkersCustom(t *testing.T) {
+	pool := CreateCustomPool([]GoroutineWorker{&reloadWorker{}}, WithBurstWorkers(2, time.Second))
+	if _, err := pool.Open(); err != ErrBurstCustom {
+		t.Errorf("Expected ErrBurstCustom, got %v", err)
+	}
+}
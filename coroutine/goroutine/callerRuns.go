@@ -0,0 +1,106 @@
+package goroutine
+
+import (
+	"errors"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrCallerRunsCustomWorkers = errors.New("caller runs policy requires a pool created with a job function")
+)
+
+/*
+WithCallerRunsPolicy - When SendWork finds every worker busy the job function is run directly on
+the caller's goroutine instead of waiting for a worker, such jobs are counted in Stats as caller
+ran jobs. Workers which have been started but are yet to report ready, just after Open or when a
+lazily started pool brings up another worker, are not busy and the job waits for them. This does not apply to SendWorkAsync and the other asynchronous calls, their caller has
+already moved on and running the job on the goroutine waiting for a worker would only lift the
+bound the pool places on concurrent jobs. As the job runs outside of any worker the policy is only
+available for pools created with a job function, Open fails with ErrCallerRunsCustomWorkers for
+pools of custom workers, which may carry per worker state.
+*/
+func WithCallerRunsPolicy() Option {
+	return func(pool *WorkPool) {
+		pool.callerRuns = true
+	}
+}
+
+/*
+workersStarting - Reports whether a started worker has yet to report ready for the first time, a
+job then waits for it rather than running on the caller, as no worker is busy with a job.
+*/
+func (pool *WorkPool) workersStarting() bool {
+	for _, workerWrapper := range pool.workers {
+		if atomic.LoadUint32(&workerWrapper.started) == 1 && atomic.LoadUint32(&workerWrapper.starting) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+callerRunsJob - Finds the job function shared by every worker, checked by Open.
+*/
+func (pool *WorkPool) callerRunsJob() (func(interface{}) interface{}, error) {
+	var job *func(interface{}) interface{}
+	for _, workerWrapper := range pool.workers {
+		worker, ok := workerWrapper.worker.(*defaultWorker)
+		if !ok || (job != nil && worker.job != job) {
+			return nil, ErrCallerRunsCustomWorkers
+		}
+		job = worker.job
+	}
+	if job == nil {
+		return nil, ErrCallerRunsCustomWorkers
+	}
+	return *job, nil
+}
+
+/*
+runOnCaller - Runs a job on the calling goroutine and reports it as completed.
+*/
+func (pool *WorkPool) runOnCaller(job jobRequest, trace TraceFunc, enqueued time.Time) (interface{}, error) {
+	result := pool.runInline(job)
+	pool.finishJob(trace, job, -1, enqueued, result, JobOK)
+	if result.panicked {
+		return nil, result.panicError()
+	}
+	return result.data, result.err
+}
+
+/*
+runInline - Runs a job with the job function of the pool on the current goroutine, recovering it
+if it panics.
+*/
+func (pool *WorkPool) runInline(job jobRequest) (result jobResult) {
+	result.started = pool.clock.Now()
+	job.tracked.start(-1, result.started)
+	defer job.tracked.finish()
+	if job.sampleAllocs {
+		defer result.measureAllocs(heapAllocBytes())
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			result.data = nil
+			result.panicked = true
+			result.panic = &jobPanic{value: r, stack: debug.Stack()}
+		}
+		result.finished = pool.clock.Now()
+		if result.panicked {
+			pool.panics.add(result.panic.record(-1, inspected(job.data), result.finished))
+		}
+	}()
+
+	if call, ok := job.data.(jobCall); ok {
+		result.data, result.err = call()
+		return
+	}
+	if call, ok := job.data.(*typedCall); ok {
+		call.run()
+		return
+	}
+	result.data = pool.inlineJob(job.data)
+	return
+}
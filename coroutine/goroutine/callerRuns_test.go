@@ -0,0 +1,88 @@
+package goroutine
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCallerRunsPolicy(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		if in == "block" {
+			started <- struct{}{}
+			<-release
+		}
+		return in
+	}, WithCallerRunsPolicy()).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	// Saturate the pool once the workers are up, before then jobs wait for them
+	for deadline := time.Now().Add(time.Second); pool.NumIdleWorkers() != 2 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.SendWork("block")
+		}()
+	}
+	<-started
+	<-started
+
+	// Every worker is busy, so these run on the caller straight away
+	before := time.Now()
+	for i := 0; i < 5; i++ {
+		if result, err := pool.SendWork(i); err != nil || result != i {
+			t.Errorf("Expected %v, got %v, %v", i, result, err)
+		}
+	}
+	if elapsed := time.Since(before); elapsed > 500*time.Millisecond {
+		t.Errorf("Caller ran jobs waited for %v", elapsed)
+	}
+	if ran := pool.Stats().CallerRanJobs; ran != 5 {
+		t.Errorf("Expected 5 caller ran jobs, got %v", ran)
+	}
+
+	close(release)
+	wg.Wait()
+}
+
+func TestCallerRunsPolicyStartingWorkers(t *testing.T) {
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		return in
+	}, WithCallerRunsPolicy(), WithLazyStart()).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	// No worker is busy, the jobs wait for the workers started for them
+	for i := 0; i < 10; i++ {
+		if result, err := pool.SendWork(i); err != nil || result != i {
+			t.Errorf("Expected %v, got %v, %v", i, result, err)
+		}
+	}
+	if ran := pool.Stats().CallerRanJobs; ran != 0 {
+		t.Errorf("Expected no caller ran jobs, got %v", ran)
+	}
+}
+
+func TestCallerRunsPolicyCustomWorkers(t *testing.T) {
+	_, err := CreateCustomPool([]GoroutineWorker{
+		&taggedExtWorker{},
+	}, WithCallerRunsPolicy()).Open()
+	if err != ErrCallerRunsCustomWorkers {
+		t.Errorf("Expected ErrCallerRunsCustomWorkers, got %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package goroutine
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Clock - The source of time of a pool. Every timeout, poll interval, watchdog tick and idle timer of
+the pool and its workers goes through the clock, as do the timestamps of traces and snapshots, so
+that tests can replace the real clock with a FakeClock. Deadlines of contexts given to the pool
+are the exception, they are always kept by the real clock.
+*/
+type Clock interface {
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends the current time on the channel
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer creates a timer which sends the current time on its channel after d
+	NewTimer(d time.Duration) Timer
+
+	// AfterFunc calls f on its own goroutine after d, the timer it returns has no channel
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+/*
+Timer - A single event created by a Clock, it behaves as a time.Timer.
+*/
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+/*
+WithClock - Sets the clock of the pool, the real clock is used by default or when clock is nil.
+*/
+func WithClock(clock Clock) Option {
+	return func(pool *WorkPool) {
+		if clock != nil {
+			pool.clock = clock
+		}
+	}
+}
+
+// realClock is the default clock, it defers to the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return &realTimer{time.AfterFunc(d, f)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (timer *realTimer) C() <-chan time.Time {
+	return timer.Timer.C
+}
+
+/*
+FakeClock - A Clock for tests which only moves when told to. Timers fire, in the order they are
+due, as Advance moves the clock past them, so a test can make a timeout expire without waiting for
+it. The zero value is not usable, create one with NewFakeClock.
+*/
+type FakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+
+	// changed is closed and replaced whenever a timer is added
+	changed chan struct{}
+}
+
+/*
+NewFakeClock - Creates a fake clock reading now.
+*/
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now:     now,
+		changed: make(chan struct{}),
+	}
+}
+
+func (clock *FakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+func (clock *FakeClock) After(d time.Duration) <-chan time.Time {
+	return clock.NewTimer(d).C()
+}
+
+func (clock *FakeClock) NewTimer(d time.Duration) Timer {
+	timer := &fakeTimer{
+		clock: clock,
+		c:     make(chan time.Time, 1),
+	}
+	timer.Reset(d)
+	return timer
+}
+
+func (clock *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	timer := &fakeTimer{
+		clock: clock,
+		f:     f,
+	}
+	timer.Reset(d)
+	return timer
+}
+
+/*
+Advance - Moves the clock forward by d, firing every timer which falls due on the way.
+*/
+func (clock *FakeClock) Advance(d time.Duration) {
+	clock.mutex.Lock()
+	clock.now = clock.now.Add(d)
+
+	var due []*fakeTimer
+	pending := clock.timers[:0]
+	for _, timer := range clock.timers {
+		if timer.when.After(clock.now) {
+			pending = append(pending, timer)
+		} else {
+			due = append(due, timer)
+		}
+	}
+	clock.timers = pending
+	clock.mutex.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].when.Before(due[j].when)
+	})
+	for _, timer := range due {
+		timer.fire()
+	}
+}
+
+/*
+BlockUntil - Waits until at least n timers are waiting to fire, so that a test can be sure the
+code under test has set its timer before advancing the clock.
+*/
+func (clock *FakeClock) BlockUntil(n int) {
+	for {
+		clock.mutex.Lock()
+		waiting, changed := len(clock.timers), clock.changed
+		clock.mutex.Unlock()
+
+		if waiting >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// remove takes the timer off the clock, the mutex must be held. Returns whether it was waiting.
+func (clock *FakeClock) remove(timer *fakeTimer) bool {
+	for i, waiting := range clock.timers {
+		if waiting == timer {
+			clock.timers = append(clock.timers[:i], clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	when  time.Time
+	c     chan time.Time
+	f     func()
+}
+
+func (timer *fakeTimer) C() <-chan time.Time {
+	return timer.c
+}
+
+func (timer *fakeTimer) Stop() bool {
+	timer.clock.mutex.Lock()
+	defer timer.clock.mutex.Unlock()
+
+	return timer.clock.remove(timer)
+}
+
+func (timer *fakeTimer) Reset(d time.Duration) bool {
+	clock := timer.clock
+	clock.mutex.Lock()
+
+	active := clock.remove(timer)
+	timer.when = clock.now.Add(d)
+
+	// As with time.Timer a timer which is already due fires straight away
+	if d <= 0 {
+		clock.mutex.Unlock()
+		timer.fire()
+		return active
+	}
+
+	clock.timers = append(clock.timers, timer)
+	close(clock.changed)
+	clock.changed = make(chan struct{})
+	clock.mutex.Unlock()
+	return active
+}
+
+// fire delivers the timer once it is due, a tick left unreceived is dropped as by time.Timer
+func (timer *fakeTimer) fire() {
+	if timer.f != nil {
+		go timer.f()
+		return
+	}
+	select {
+	case timer.c <- timer.when:
+	default:
+	}
+}
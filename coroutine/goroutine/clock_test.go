@@ -0,0 +1,87 @@
+package goroutine
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewFakeClock(start)
+
+	late := clock.NewTimer(2 * time.Second)
+	early := clock.NewTimer(time.Second)
+	stopped := clock.NewTimer(time.Second)
+	if !stopped.Stop() {
+		t.Errorf("Expected Stop to report the timer was waiting")
+	}
+	called := make(chan time.Time, 1)
+	clock.AfterFunc(1500*time.Millisecond, func() {
+		called <- clock.Now()
+	})
+
+	clock.Advance(time.Second)
+	select {
+	case now := <-early.C():
+		if !now.Equal(start.Add(time.Second)) {
+			t.Errorf("Expected the timer to fire at %v, got %v", start.Add(time.Second), now)
+		}
+	default:
+		t.Errorf("Expected the early timer to fire")
+	}
+	select {
+	case <-late.C():
+		t.Errorf("Expected the late timer not to fire yet")
+	case <-stopped.C():
+		t.Errorf("Expected the stopped timer never to fire")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	if now := <-called; !now.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("Expected the function to be called once the clock passed it, got %v", now)
+	}
+	<-late.C()
+
+	// A timer which is reset is due from the current time
+	if late.Reset(time.Second) {
+		t.Errorf("Expected Reset of a fired timer to report it was not waiting")
+	}
+	clock.Advance(999 * time.Millisecond)
+	select {
+	case <-late.C():
+		t.Errorf("Expected the reset timer not to fire yet")
+	default:
+	}
+	clock.Advance(time.Millisecond)
+	<-late.C()
+}
+
+func TestSendWorkTimedFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	release := make(chan struct{})
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		<-release
+		return in
+	}, WithClock(clock)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+	defer close(release)
+
+	// A timeout of a minute expires as soon as the clock is moved past it
+	go func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+	}()
+	started := time.Now()
+	if _, err := pool.SendWorkTimed(60000*time.Millisecond, "slow"); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut, got %v", err)
+	}
+	if waited := time.Since(started); waited > 5*time.Second {
+		t.Errorf("Expected the timeout to fire without waiting for it, took %v", waited)
+	}
+}
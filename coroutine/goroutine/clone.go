@@ -0,0 +1,56 @@
+package goroutine
+
+/*
+Cloner - Implemented by payloads which know how to copy themselves, used by ClonePayload.
+*/
+type Cloner interface {
+	Clone() interface{}
+}
+
+/*
+WithPayloadCloner - Copies every job payload on the caller's goroutine when it is submitted, before
+it is queued, so that the worker sees a copy the caller is free to keep mutating. Each job is
+cloned exactly once and the copy is what the payload checks and the worker see. A nil cloner keeps
+the default of passing payloads through without copying. ClonePayload covers the common cases.
+*/
+func WithPayloadCloner(clone func(interface{}) interface{}) Option {
+	return func(pool *WorkPool) {
+		pool.cloner = clone
+	}
+}
+
+/*
+ClonePayload - A cloner for WithPayloadCloner which copies []byte, copies map[string]interface{}
+recursively, calls Clone() on payloads implementing Cloner and returns anything else as is.
+*/
+func ClonePayload(jobData interface{}) interface{} {
+	switch data := jobData.(type) {
+	case Cloner:
+		return data.Clone()
+	case []byte:
+		if data == nil {
+			return data
+		}
+		return append([]byte(nil), data...)
+	case map[string]interface{}:
+		if data == nil {
+			return data
+		}
+		clone := make(map[string]interface{}, len(data))
+		for key, value := range data {
+			clone[key] = ClonePayload(value)
+		}
+		return clone
+	}
+	return jobData
+}
+
+/*
+clonePayload - Applies the cloner of the pool, if any, to a payload being submitted.
+*/
+func (pool *WorkPool) clonePayload(jobData interface{}) interface{} {
+	if pool.cloner == nil {
+		return jobData
+	}
+	return pool.cloner(jobData)
+}
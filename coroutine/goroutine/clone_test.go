@@ -0,0 +1,73 @@
+package goroutine
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPayloadCloner(t *testing.T) {
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		time.Sleep(10 * time.Millisecond)
+		payload := in.(map[string]interface{})
+		if payload["name"] != "original" {
+			return false
+		}
+		return bytes.Equal(payload["data"].([]byte), []byte("original"))
+	}, WithPayloadCloner(ClonePayload)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		payload := map[string]interface{}{
+			"name": "original",
+			"data": []byte("original"),
+		}
+
+		wg.Add(1)
+		err := pool.SendWorkAsync(payload, func(result interface{}, err error) {
+			defer wg.Done()
+			if err != nil || result != true {
+				t.Errorf("Worker saw a mutated payload: %v, %v", result, err)
+			}
+		})
+		if err != nil {
+			t.Errorf("Failed to send work: %v", err)
+		}
+
+		payload["name"] = "mutated"
+		copy(payload["data"].([]byte), "mutated!")
+	}
+	wg.Wait()
+}
+
+type clonerPayload struct {
+	clones int
+}
+
+func (payload *clonerPayload) Clone() interface{} {
+	return &clonerPayload{clones: payload.clones + 1}
+}
+
+func TestClonePayloadOnce(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		return in.(*clonerPayload).clones
+	}, WithPayloadCloner(ClonePayload)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if result, err := pool.SendWork(&clonerPayload{}); err != nil || result != 1 {
+		t.Errorf("Expected the payload to be cloned once, got %v, %v", result, err)
+	}
+	if result, err := pool.SendWorkTimed(1000*time.Millisecond, &clonerPayload{}); err != nil || result != 1 {
+		t.Errorf("Expected the payload to be cloned once, got %v, %v", result, err)
+	}
+}
@@ -0,0 +1,77 @@
+package goroutine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/*
+ClosingChan - Returns a channel which is closed as soon as the pool begins to close, before it
+waits for the jobs in progress. Jobs that run for a long time can capture the pool and check the
+channel as they go, so that they bail out early instead of holding up Close. A pool which is
+opened again after closing gets a new channel.
+*/
+func (pool *WorkPool) ClosingChan() <-chan struct{} {
+	return pool.closingChan()
+}
+
+func (pool *WorkPool) closingChan() chan struct{} {
+	for {
+		if closing, ok := pool.closing.Load().(chan struct{}); ok {
+			return closing
+		}
+		pool.closing.CompareAndSwap(nil, make(chan struct{}))
+	}
+}
+
+/*
+resetClosing - Replaces the closing channel of a pool that has been closed, called by Open.
+*/
+func (pool *WorkPool) resetClosing() {
+	pool.closingMutex.Lock()
+	defer pool.closingMutex.Unlock()
+
+	select {
+	case <-pool.closingChan():
+		pool.closing.Store(make(chan struct{}))
+	default:
+	}
+}
+
+/*
+WithCloseGracePeriod - Once Close has begun, workers whose job has still not returned after grace
+are interrupted, if they implement GoroutineInterruptable. Jobs that watch ClosingChan
+can stop sooner without being interrupted.
+*/
+func WithCloseGracePeriod(grace time.Duration) Option {
+	return func(pool *WorkPool) {
+		pool.closeGrace = grace
+	}
+}
+
+/*
+signalClosing - Closes the closing channel, unless it has already been closed since the pool was
+last opened.
+*/
+func (pool *WorkPool) signalClosing() {
+	pool.closingMutex.Lock()
+	defer pool.closingMutex.Unlock()
+
+	closing := pool.closingChan()
+	select {
+	case <-closing:
+	default:
+		close(closing)
+	}
+}
+
+/*
+interruptBusyWorkers - Interrupts every worker that is in the middle of a job.
+*/
+func (pool *WorkPool) interruptBusyWorkers() {
+	for _, workerWrapper := range pool.lockFreeWorkers() {
+		if atomic.LoadUint32(&workerWrapper.busy) == 1 {
+			workerWrapper.Interrupt()
+		}
+	}
+}
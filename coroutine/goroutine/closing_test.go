@@ -0,0 +1,99 @@
+package goroutine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClosingChan(t *testing.T) {
+	var pool *WorkPool
+	pool = CreatePool(2, func(in interface{}) interface{} {
+		// Nominally runs for a second, checking for shutdown on every iteration
+		for i := 0; i < 100; i++ {
+			select {
+			case <-pool.ClosingChan():
+				return "stopped"
+			default:
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return "finished"
+	})
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	results := make(chan interface{}, 2)
+	for i := 0; i < 2; i++ {
+		pool.SendWorkAsync(nil, func(result interface{}, err error) {
+			results <- result
+		})
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	closing := pool.ClosingChan()
+	start := time.Now()
+	pool.Close()
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Expected Close to stop the jobs early, took %v", elapsed)
+	}
+	for i := 0; i < 2; i++ {
+		if result := <-results; result != "stopped" {
+			t.Errorf("Expected the job to notice the pool closing, got %v", result)
+		}
+	}
+
+	// Opening the pool again gives a new channel
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to reopen pool: %v", err)
+		return
+	}
+	defer pool.Close()
+	select {
+	case <-closing:
+	default:
+		t.Error("Expected the old channel to stay closed")
+	}
+	select {
+	case <-pool.ClosingChan():
+		t.Error("Expected a new channel once the pool is reopened")
+	default:
+	}
+}
+
+// blockedWorker runs jobs until it is interrupted
+type blockedWorker struct {
+	interrupted chan struct{}
+}
+
+func (w *blockedWorker) Job(in interface{}) interface{} {
+	<-w.interrupted
+	return in
+}
+
+func (w *blockedWorker) Ready() bool {
+	return true
+}
+
+func (w *blockedWorker) Interrupt() {
+	close(w.interrupted)
+}
+
+func TestCloseGracePeriod(t *testing.T) {
+	worker := &blockedWorker{interrupted: make(chan struct{})}
+	pool, err := CreateCustomPool([]GoroutineWorker{worker}, WithCloseGracePeriod(20*time.Millisecond)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	pool.SendWorkAsync(nil, nil)
+	time.Sleep(10 * time.Millisecond)
+
+	start := time.Now()
+	pool.Close()
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected the job to be interrupted after the grace period, Close took %v", elapsed)
+	}
+}
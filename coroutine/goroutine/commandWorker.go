@@ -0,0 +1,73 @@
+package goroutine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os/exec"
+)
+
+/*
+CommandResult - The result of a job run by a worker created with NewCommandWorker. A command
+which ran and exited with a non-zero status is reported through ExitCode, it is not an error.
+*/
+type CommandResult struct {
+	Output   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+/*
+Implementation of a worker which runs an external command for each job.
+*/
+type commandWorker struct {
+	name string
+	args []string
+}
+
+/*
+NewCommandWorker - Creates a worker which runs the command name with args for each job, the job
+data is written to the standard input of the command as JSON and the result is a *CommandResult
+holding what the command wrote. A fresh process is started for every job, so the worker is always
+ready. If the job data cannot be marshalled or the command cannot be started the error is
+returned as the result.
+*/
+func NewCommandWorker(name string, args ...string) GoroutineWorker {
+	return &commandWorker{name: name, args: args}
+}
+
+func (worker *commandWorker) Job(data interface{}) interface{} {
+	input, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(worker.name, worker.args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+	}
+
+	return &CommandResult{
+		Output:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		ExitCode: cmd.ProcessState.ExitCode(),
+	}
+}
+
+func (worker *commandWorker) Ready() bool {
+	return true
+}
+
+func (worker *commandWorker) Initialize() {
+}
+
+func (worker *commandWorker) Terminate() {
+}
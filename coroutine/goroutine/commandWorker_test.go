@@ -0,0 +1,59 @@
+package goroutine
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCommandWorker(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	pool, err := CreateCustomPool([]GoroutineWorker{
+		NewCommandWorker("sh", "-c", "cat; echo oops >&2; exit 3"),
+		NewCommandWorker("sh", "-c", "cat; echo oops >&2; exit 3"),
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	result, err := pool.SendWork(map[string]interface{}{"n": 1})
+	if err != nil {
+		t.Errorf("Failed to send work: %v", err)
+		return
+	}
+	cmdResult, ok := result.(*CommandResult)
+	if !ok {
+		t.Errorf("Expected a *CommandResult, got %v", result)
+		return
+	}
+	if string(cmdResult.Output) != `{"n":1}` {
+		t.Errorf("Expected the JSON input echoed, got %q", cmdResult.Output)
+	}
+	if strings.TrimSpace(string(cmdResult.Stderr)) != "oops" {
+		t.Errorf("Expected stderr to be captured, got %q", cmdResult.Stderr)
+	}
+	if cmdResult.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %v", cmdResult.ExitCode)
+	}
+}
+
+func TestCommandWorkerMissing(t *testing.T) {
+	pool, err := CreateCustomPool([]GoroutineWorker{
+		NewCommandWorker("/nonexistent/command"),
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	result, err := pool.SendWork(nil)
+	if _, ok := result.(error); err != nil || !ok {
+		t.Errorf("Expected the start error as the result, got %v, %v", result, err)
+	}
+}
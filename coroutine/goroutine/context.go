@@ -0,0 +1,75 @@
+package goroutine
+
+import (
+	"context"
+	"time"
+)
+
+/*
+jobCall - A closure submitted with Do, workers call it in place of their own Job.
+*/
+type jobCall func() (interface{}, error)
+
+/*
+SendWorkContext - Send a job to a worker and return the result, this is a synchronous call which
+gives up when ctx is done, returning the error of ctx. A job given up on after a worker took it is
+interrupted, if the worker supports it, and its result is discarded.
+*/
+func (pool *WorkPool) SendWorkContext(ctx context.Context, jobData interface{}) (interface{}, error) {
+	jobData = pool.clonePayload(jobData)
+	ticket, err := pool.admitUntil(ctx, time.Time{}, jobData)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.release(jobData, ticket)
+
+	result, err := pool.sendWorkUntil(ctx, time.Time{}, jobData)
+	pool.breaker.done(ticket.probe, result, err)
+	return result, err
+}
+
+/*
+SendWorkAsyncCtx - Send a job to a worker without blocking, carrying ctx alongside it. The job is
+skipped if ctx is done before a worker takes it, and is given up on as with SendWorkContext if ctx
+is done while it runs, in both cases the error of ctx is passed to after. The callback receives
+ctx itself, so values such as trace IDs can be read from it, and may be nil if no further actions
+are required. Workers implementing GoroutineContextWorker receive ctx with the job. An error is
+returned if the job is rejected at submission.
+*/
+func (pool *WorkPool) SendWorkAsyncCtx(
+	ctx context.Context,
+	jobData interface{},
+	after func(ctx context.Context, result interface{}, err error),
+) error {
+	jobData = pool.clonePayload(jobData)
+	ticket, err := pool.admit(jobData)
+	if err != nil {
+		return err
+	}
+
+	seq := pool.ordered.reserve()
+
+	var callback func(interface{}, error)
+	submitted := jobData
+	if after != nil {
+		callback = func(result interface{}, err error) {
+			after(ctx, result, err)
+		}
+	} else {
+		submitted = uncollectedJob{jobData}
+	}
+
+	pool.runAsync(jobData, ticket, seq, callback, func() (interface{}, error) {
+		return pool.sendWorkUntil(ctx, time.Time{}, submitted)
+	})
+	return nil
+}
+
+/*
+Do - Runs fn on a worker of the pool and returns what it returns, fn takes the place of the job
+of the worker for this one call so it should capture whatever data it needs. This is a
+synchronous call which gives up when ctx is done, in the same way as SendWorkContext.
+*/
+func (pool *WorkPool) Do(ctx context.Context, fn func() (interface{}, error)) (interface{}, error) {
+	return pool.SendWorkContext(ctx, jobCall(fn))
+}
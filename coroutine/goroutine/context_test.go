@@ -0,0 +1,167 @@
+package goroutine
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendWorkContext(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		if d, ok := in.(time.Duration); ok {
+			time.Sleep(d)
+		}
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if result, err := pool.SendWorkContext(context.Background(), 10); err != nil || result != 10 {
+		t.Errorf("Expected 10, got %v, %v", result, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.SendWorkContext(ctx, 100*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context deadline, got %v", err)
+	}
+
+	// The only worker is still busy, so this job is cancelled while waiting for it
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := pool.SendWorkContext(ctx, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context to be cancelled, got %v", err)
+	}
+
+	if _, err := pool.SendWorkContext(ctx, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected an already cancelled context to fail, got %v", err)
+	}
+}
+
+func TestDo(t *testing.T) {
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		return "worker job"
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	a, b := 2, 3
+	result, err := pool.Do(context.Background(), func() (interface{}, error) {
+		return a * b, nil
+	})
+	if err != nil || result != 6 {
+		t.Errorf("Expected 6, got %v, %v", result, err)
+	}
+
+	errFailed := errors.New("failed")
+	if _, err := pool.Do(context.Background(), func() (interface{}, error) {
+		return nil, errFailed
+	}); err != errFailed {
+		t.Errorf("Expected %v, got %v", errFailed, err)
+	}
+
+	if result, _ := pool.SendWork(nil); result != "worker job" {
+		t.Errorf("Expected the worker's own job after Do, got %v", result)
+	}
+}
+
+type traceKey struct{}
+
+// contextWorker reports the trace value of the context it is given, or waits for the context to
+// be done when the job is "wait"
+type contextWorker struct {
+	cancelled chan error
+	jobs      int32
+}
+
+func (w *contextWorker) Job(in interface{}) interface{} {
+	return "no context"
+}
+
+func (w *contextWorker) JobCtx(ctx context.Context, in interface{}) interface{} {
+	atomic.AddInt32(&w.jobs, 1)
+	if in == "wait" {
+		<-ctx.Done()
+		w.cancelled <- ctx.Err()
+		return nil
+	}
+	return ctx.Value(traceKey{})
+}
+
+func (w *contextWorker) Ready() bool {
+	return true
+}
+
+func TestSendWorkAsyncCtx(t *testing.T) {
+	worker := &contextWorker{cancelled: make(chan error, 1)}
+	pool, err := CreateCustomPool([]GoroutineWorker{worker}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	type callback struct {
+		ctx    context.Context
+		result interface{}
+		err    error
+	}
+	done := make(chan callback, 1)
+	after := func(ctx context.Context, result interface{}, err error) {
+		done <- callback{ctx, result, err}
+	}
+
+	// The trace value reaches both the job and the callback
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace-1")
+	if err := pool.SendWorkAsyncCtx(ctx, "trace", after); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	got := <-done
+	if got.err != nil || got.result != "trace-1" || got.ctx != ctx {
+		t.Errorf("Expected the trace value and the submitted context, got %+v", got)
+	}
+
+	// Jobs without a context are given context.Background()
+	if result, err := pool.SendWork("trace"); err != nil || result != nil {
+		t.Errorf("Expected no trace value, got %v, %v", result, err)
+	}
+
+	// A job cancelled before it starts is skipped
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	jobs := atomic.LoadInt32(&worker.jobs)
+	if err := pool.SendWorkAsyncCtx(cancelled, "trace", after); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	if got := <-done; !errors.Is(got.err, context.Canceled) || got.ctx != cancelled {
+		t.Errorf("Expected context.Canceled, got %+v", got)
+	}
+	if atomic.LoadInt32(&worker.jobs) != jobs {
+		t.Error("Expected the cancelled job to be skipped")
+	}
+
+	// A job cancelled while it runs sees its context done
+	running, cancel := context.WithCancel(context.Background())
+	if err := pool.SendWorkAsyncCtx(running, "wait", after); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if got := <-done; !errors.Is(got.err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %+v", got)
+	}
+	if err := <-worker.cancelled; err != context.Canceled {
+		t.Errorf("Expected the job to see its context cancelled, got %v", err)
+	}
+}
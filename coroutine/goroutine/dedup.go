@@ -0,0 +1,58 @@
+package goroutine
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+/*
+dedupCall - A job submitted with SendWorkDedup that other submissions of the same key wait on.
+*/
+type dedupCall struct {
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+/*
+inflightJobs - The jobs submitted with SendWorkDedup that are queued or running, by key.
+*/
+type inflightJobs struct {
+	mutex     sync.Mutex
+	calls     map[string]*dedupCall
+	coalesced uint64
+}
+
+/*
+SendWorkDedup - Send a job to a worker and return the result, like SendWork, unless a job with the
+same key is already queued or running. In that case no job is sent, the call waits for the job in
+flight and returns its result and error, so every caller shares the one result value. There is no
+caching, the key can be used again as soon as its job completes. Calls that join a job in flight
+are counted in Stats as coalesced jobs.
+*/
+func (pool *WorkPool) SendWorkDedup(key string, jobData interface{}) (interface{}, error) {
+	inflight := &pool.inflight
+
+	inflight.mutex.Lock()
+	if call, ok := inflight.calls[key]; ok {
+		inflight.mutex.Unlock()
+		atomic.AddUint64(&inflight.coalesced, 1)
+		<-call.done
+		return call.result, call.err
+	}
+	if inflight.calls == nil {
+		inflight.calls = map[string]*dedupCall{}
+	}
+	call := &dedupCall{done: make(chan struct{})}
+	inflight.calls[key] = call
+	inflight.mutex.Unlock()
+
+	call.result, call.err = pool.SendWork(jobData)
+
+	inflight.mutex.Lock()
+	delete(inflight.calls, key)
+	inflight.mutex.Unlock()
+	close(call.done)
+
+	return call.result, call.err
+}
@@ -0,0 +1,57 @@
+package goroutine
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendWorkDedup(t *testing.T) {
+	var runs int32
+	release := make(chan struct{})
+
+	pool, err := CreatePool(4, func(in interface{}) interface{} {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if result, err := pool.SendWorkDedup("key", "result"); err != nil || result != "result" {
+				t.Errorf("Expected the shared result, got %v, %v", result, err)
+			}
+		}()
+	}
+
+	// Let every submission reach the job in flight before it completes
+	for deadline := time.Now().Add(time.Second); pool.Stats().CoalescedJobs < 19 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if runs := atomic.LoadInt32(&runs); runs != 1 {
+		t.Errorf("Expected the job to run once, ran %v times", runs)
+	}
+	if coalesced := pool.Stats().CoalescedJobs; coalesced != 19 {
+		t.Errorf("Expected 19 coalesced jobs, got %v", coalesced)
+	}
+
+	// Once the job has completed the key is free again
+	if result, err := pool.SendWorkDedup("key", "again"); err != nil || result != "again" {
+		t.Errorf("Expected a new job for a reused key, got %v, %v", result, err)
+	}
+	if runs := atomic.LoadInt32(&runs); runs != 2 {
+		t.Errorf("Expected the job to run again, ran %v times", runs)
+	}
+}
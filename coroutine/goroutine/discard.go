@@ -0,0 +1,99 @@
+package goroutine
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+/*
+uncollectedJob - Wraps the payload of an async job submitted without a callback, marking its
+result as one that nobody will receive.
+*/
+type uncollectedJob struct {
+	data interface{}
+}
+
+type discardedResult struct {
+	seq    uint64
+	result interface{}
+}
+
+/*
+discardedResults - Results computed by the pool which no caller will ever receive, these are
+counted and handed to the handler set with SetDiscardedResultHandler in the order they occur.
+*/
+type discardedResults struct {
+	count uint64
+
+	mutex    sync.Mutex
+	handler  func(jobSeq uint64, result interface{})
+	queue    []discardedResult
+	draining bool
+}
+
+/*
+SetDiscardedResultHandler - Calls handler with the sequence number and result of every job whose
+result no caller will ever receive, to surface expensive work being computed and thrown away.
+A result is discarded when it arrives after its caller gave up on a timeout or cancellation,
+including callers given up on while the pool closes, or when its job was submitted with
+SendWorkAsync or SendWorkTimedAsync without a callback. Results of jobs which panicked are not
+passed to the handler.
+
+The handler is called on a goroutine of its own, one result at a time, so that a slow handler
+never holds up a worker. Pass nil to remove the handler, discarded results are counted in Stats
+either way.
+*/
+func (pool *WorkPool) SetDiscardedResultHandler(handler func(jobSeq uint64, result interface{})) {
+	d := &pool.discarded
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.handler = handler
+	if handler == nil {
+		d.queue = nil
+	}
+}
+
+// discardedJob reports whether a finished job computed a result nobody will receive
+func discardedJob(job jobRequest, result jobResult, outcome JobOutcome) bool {
+	if result.finished.IsZero() || result.panicked {
+		return false
+	}
+	return outcome != JobOK || job.uncollected
+}
+
+// add counts a discarded result and queues it for the handler
+func (d *discardedResults) add(seq uint64, result interface{}) {
+	atomic.AddUint64(&d.count, 1)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.handler == nil {
+		return
+	}
+	d.queue = append(d.queue, discardedResult{seq: seq, result: result})
+	if !d.draining {
+		d.draining = true
+		go d.drain()
+	}
+}
+
+// drain calls the handler for queued results until the queue is empty
+func (d *discardedResults) drain() {
+	for {
+		d.mutex.Lock()
+		handler := d.handler
+		if len(d.queue) == 0 || handler == nil {
+			d.queue = nil
+			d.draining = false
+			d.mutex.Unlock()
+			return
+		}
+		next := d.queue[0]
+		d.queue = d.queue[1:]
+		d.mutex.Unlock()
+
+		handler(next.seq, next.result)
+	}
+}
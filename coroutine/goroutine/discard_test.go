@@ -0,0 +1,127 @@
+package goroutine
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// discardLog records the results passed to a discarded result handler
+type discardLog struct {
+	mutex   sync.Mutex
+	results map[uint64]interface{}
+	calls   int
+}
+
+func (l *discardLog) handle(jobSeq uint64, result interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.results == nil {
+		l.results = map[uint64]interface{}{}
+	}
+	l.results[jobSeq] = result
+	l.calls++
+}
+
+func (l *discardLog) waitFor(calls int) map[uint64]interface{} {
+	deadline := time.Now().Add(time.Second)
+	for {
+		l.mutex.Lock()
+		if l.calls >= calls || time.Now().After(deadline) {
+			defer l.mutex.Unlock()
+			return l.results
+		}
+		l.mutex.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDiscardedLateResult(t *testing.T) {
+	release := make(chan struct{})
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		<-release
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	log := &discardLog{}
+	pool.SetDiscardedResultHandler(log.handle)
+
+	if _, err := pool.SendWorkTimed(10*time.Millisecond, "late"); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut, got %v", err)
+	}
+	close(release)
+
+	results := log.waitFor(1)
+	if len(results) != 1 || results[1] != "late" {
+		t.Errorf("Expected the late result of job 1, got %v", results)
+	}
+
+	// Results that are received are not discarded
+	if _, err := pool.SendWork("received"); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if results := log.waitFor(1); log.calls != 1 {
+		t.Errorf("Expected the handler to be called exactly once, got %v", results)
+	}
+	if stats := pool.Stats(); stats.DiscardedResults != 1 {
+		t.Errorf("Expected 1 discarded result, got %v", stats.DiscardedResults)
+	}
+}
+
+func TestDiscardedWithoutCallback(t *testing.T) {
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		if in == "slow" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	log := &discardLog{}
+	pool.SetDiscardedResultHandler(log.handle)
+
+	if err := pool.SendWorkAsync("forgotten", nil); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	if err := pool.SendWorkTimedAsync(time.Second, "forgotten timed", nil); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	done := make(chan struct{})
+	if err := pool.SendWorkAsync("collected", func(interface{}, error) { close(done) }); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	<-done
+
+	results := log.waitFor(2)
+	seen := map[interface{}]bool{}
+	for _, result := range results {
+		seen[result] = true
+	}
+	if len(results) != 2 || !seen["forgotten"] || !seen["forgotten timed"] {
+		t.Errorf("Expected the results of the jobs without callbacks, got %v", results)
+	}
+
+	// Removing the handler still counts discarded results
+	pool.SetDiscardedResultHandler(nil)
+	if _, err := pool.SendWorkTimed(time.Millisecond, "slow"); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut, got %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for pool.Stats().DiscardedResults < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := pool.Stats(); stats.DiscardedResults != 3 {
+		t.Errorf("Expected 3 discarded results, got %v", stats.DiscardedResults)
+	}
+}
@@ -0,0 +1,93 @@
+package goroutine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+/*
+The errors of a job which did not complete fall into a few categories, each with a sentinel to
+test for with errors.Is. Errors carrying details of the failure wrap the sentinel of their
+category, and the pool wraps rather than replaces errors as it passes them on, so errors.Is and
+errors.As see through a BatchError, StageError or the error of a KeyedPool alike:
+
+	ErrPoolNotRunning    - the pool was closed, or never opened
+	ErrJobTimedOut       - the job's timeout passed, a *TimeoutError
+	ErrJobCancelled      - the job's context was done, also matching the error of the context
+	ErrQueueFull         - a limit on the jobs queued was reached, ErrTooManyInFlight or
+	                       ErrQueueMemoryExceeded
+	ErrJobPanicked       - the job panicked, a *PanicError
+	ErrAdmissionRejected - the function set WithAdmissionFunc refused the job, also matching
+	                       the error it returned
+*/
+var (
+	ErrPoolAlreadyRunning = errors.New("the pool is already running")
+	ErrPoolNotRunning     = errors.New("the pool is not running")
+	ErrUnsupportedPayload = errors.New("generic worker given an unsupported payload")
+	ErrWorkerClosed       = errors.New("worker was closed")
+	ErrJobTimedOut        = errors.New("job request timed out")
+	ErrJobCancelled       = errors.New("job cancelled")
+	ErrQueueFull          = errors.New("job queue is full")
+	ErrJobPanicked        = errors.New("job panicked")
+	ErrAdmissionRejected  = errors.New("job rejected by the admission function")
+	ErrWorkerNil          = errors.New("worker is nil")
+
+	ErrPayloadTooLarge     = errors.New("job payload exceeds the size limit")
+	ErrQueueMemoryExceeded = fmt.Errorf("%w, queued job payloads exceed the memory limit", ErrQueueFull)
+	ErrTooManyInFlight     = fmt.Errorf("%w, too many jobs in flight", ErrQueueFull)
+
+	// Deprecated: generic pools now accept several payload shapes, use ErrUnsupportedPayload.
+	ErrJobNotFunc = ErrUnsupportedPayload
+)
+
+/*
+TimeoutError - The error of a job given up on once its timeout passed, it matches
+ErrJobTimedOut. Seq is the sequence number of the job, as logged and traced, or 0 if the job
+timed out before it was queued, and Running reports whether it had reached a worker.
+*/
+type TimeoutError struct {
+	Seq     uint64
+	Running bool
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Running {
+		return ErrJobTimedOut.Error() + " while running"
+	}
+	return ErrJobTimedOut.Error() + " while queued"
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return ErrJobTimedOut
+}
+
+/*
+PanicError - The error of a job which panicked, it matches ErrJobPanicked. Value is what the job
+panicked with and Stack the stack of the worker when the panic was recovered.
+*/
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrJobPanicked, e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrJobPanicked
+}
+
+// panicError returns the error of a job which panicked
+func (result jobResult) panicError() error {
+	if result.panic == nil {
+		return ErrJobPanicked
+	}
+	return &PanicError{Value: result.panic.value, Stack: result.panic.stack}
+}
+
+// cancelled returns the error of a job whose context is done, matching the error of ctx as well
+func cancelled(ctx context.Context) error {
+	return fmt.Errorf("%w: %w", ErrJobCancelled, ctx.Err())
+}
@@ -0,0 +1,163 @@
+package goroutine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestErrorTaxonomy(t *testing.T) {
+	release := make(chan struct{})
+	job := func(in interface{}) interface{} {
+		switch in {
+		case "panic":
+			panic("job exploded")
+		case "block":
+			<-release
+		case "slow":
+			time.Sleep(50 * time.Millisecond)
+		}
+		return in
+	}
+	open := func(opts ...Option) *WorkPool {
+		pool, err := CreatePool(1, job, opts...).Open()
+		if err != nil {
+			t.Fatalf("Failed to create pool: %v", err)
+		}
+		return pool
+	}
+
+	tests := []struct {
+		name     string
+		run      func() error
+		sentinel error
+		check    func(err error) bool
+	}{
+		{
+			name: "closed pool",
+			run: func() error {
+				pool := open()
+				pool.Close()
+				_, err := pool.SendWork(nil)
+				return err
+			},
+			sentinel: ErrPoolNotRunning,
+		},
+		{
+			name: "timeout",
+			run: func() error {
+				pool := open()
+				defer pool.Close()
+				_, err := pool.SendWorkTimed(5*time.Millisecond, "slow")
+				return err
+			},
+			sentinel: ErrJobTimedOut,
+			check: func(err error) bool {
+				var timeout *TimeoutError
+				return errors.As(err, &timeout) && timeout.Running && timeout.Seq == 1
+			},
+		},
+		{
+			name: "cancelled",
+			run: func() error {
+				pool := open()
+				defer pool.Close()
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(5*time.Millisecond, cancel)
+				_, err := pool.SendWorkContext(ctx, "slow")
+				return err
+			},
+			sentinel: ErrJobCancelled,
+			check: func(err error) bool {
+				return errors.Is(err, context.Canceled)
+			},
+		},
+		{
+			name: "queue full",
+			run: func() error {
+				pool := open(WithMaxInFlight(1))
+				defer pool.Close()
+				defer close(release)
+				if err := pool.SendWorkAsync("block", nil); err != nil {
+					return err
+				}
+				return pool.SendWorkAsync(nil, nil)
+			},
+			sentinel: ErrQueueFull,
+			check: func(err error) bool {
+				return err == ErrTooManyInFlight
+			},
+		},
+		{
+			name: "panic",
+			run: func() error {
+				pool := open()
+				defer pool.Close()
+				_, err := pool.SendWork("panic")
+				return err
+			},
+			sentinel: ErrJobPanicked,
+			check: func(err error) bool {
+				var panicked *PanicError
+				return errors.As(err, &panicked) && panicked.Value == "job exploded" && len(panicked.Stack) > 0
+			},
+		},
+		{
+			name: "admission",
+			run: func() error {
+				pool := open(WithAdmissionFunc(func(int, int, interface{}) error {
+					return errors.New("shedding")
+				}))
+				defer pool.Close()
+				_, err := pool.SendWork(nil)
+				return err
+			},
+			sentinel: ErrAdmissionRejected,
+		},
+		{
+			name: "panic in a batch",
+			run: func() error {
+				pool := open()
+				defer pool.Close()
+				_, err := pool.SendWorkBatch([]interface{}{1, "panic", 3})
+				return err
+			},
+			sentinel: ErrJobPanicked,
+			check: func(err error) bool {
+				var batch *BatchError
+				var panicked *PanicError
+				return errors.As(err, &batch) && errors.As(err, &panicked)
+			},
+		},
+		{
+			name: "timeout in a pipeline",
+			run: func() error {
+				first, second := open(), open(WithDefaultTimeout(5*time.Millisecond))
+				defer first.Close()
+				defer second.Close()
+				_, err := ChainPools(first, second).Send("slow")
+				return err
+			},
+			sentinel: ErrJobTimedOut,
+			check: func(err error) bool {
+				var stage *StageError
+				var timeout *TimeoutError
+				return errors.As(err, &stage) && stage.Stage == 1 && errors.As(err, &timeout)
+			},
+		},
+	}
+
+	sentinels := []error{ErrPoolNotRunning, ErrJobTimedOut, ErrJobCancelled, ErrQueueFull, ErrJobPanicked, ErrAdmissionRejected}
+	for _, test := range tests {
+		err := test.run()
+		for _, sentinel := range sentinels {
+			if errors.Is(err, sentinel) != (sentinel == test.sentinel) {
+				t.Errorf("%v: expected errors.Is(%v, %v) to be %v", test.name, err, sentinel, sentinel == test.sentinel)
+			}
+		}
+		if test.check != nil && !test.check(err) {
+			t.Errorf("%v: unexpected details of %#v", test.name, err)
+		}
+	}
+}
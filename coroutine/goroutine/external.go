@@ -0,0 +1,133 @@
+package goroutine
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	ErrExternalWorkers = errors.New("the workers of an external pool are attached with RunWorker")
+	ErrNotExternal     = errors.New("the pool was not created with external workers")
+)
+
+/*
+CreatePoolExternal - Creates a pool which starts no goroutines of its own, for hosts which budget
+their goroutines strictly. Open only marks the pool as running, and the host then donates
+goroutines by calling RunWorker from as many of them as it chooses, each serving jobs until its
+context is cancelled or the pool closes. NumWorkers counts the RunWorker calls attached.
+
+Every submission call works unchanged. While no worker is attached jobs wait for one, so a job sent
+with a timeout or context, or while WithDefaultTimeout is set, fails once it expires and any other
+job waits until a worker attaches. Resize, StopWorker, ReplaceWorker and BorrowWorker return
+ErrExternalWorkers.
+*/
+func CreatePoolExternal(job func(interface{}) interface{}, opts ...Option) *WorkPool {
+	pool, _ := newPool(append([]Option{WithJob(job), WithExternalWorkers()}, opts...))
+	return pool
+}
+
+/*
+WithExternalWorkers - Creates the pool without workers of its own, as described by
+CreatePoolExternal. It needs WithJob or WithGenericJobs and cannot be combined with WithWorkers,
+WithCustomWorkers, WithQueueSize, WithLazyStart or WithBurstWorkers.
+*/
+func WithExternalWorkers() Option {
+	return func(pool *WorkPool) {
+		pool.external = &externalWorkers{}
+	}
+}
+
+/*
+externalWorkers - The RunWorker calls of an external pool. They all serve the one worker slot of
+the pool, sharing its channels, so each job carries a reply channel as on a buffered worker.
+*/
+type externalWorkers struct {
+	attached int32
+	idle     int32
+	busy     int32
+
+	// slot holds the channels of the slot from the last Open, a *externalSlot
+	slot atomic.Value
+}
+
+/*
+externalSlot - The channels of the worker slot, copied on Open so that RunWorker never reads the
+slot while a reopen replaces them.
+*/
+type externalSlot struct {
+	ready   chan int
+	jobs    chan jobRequest
+	closing chan struct{}
+}
+
+// open records the channels of the slot just opened
+func (external *externalWorkers) open(workers []*workerWrapper) {
+	if external == nil || len(workers) == 0 {
+		return
+	}
+	slot := workers[0]
+	atomic.StoreUint32(&slot.idle, 1)
+	external.slot.Store(&externalSlot{
+		ready:   slot.readyChan,
+		jobs:    slot.jobChan,
+		closing: slot.closing,
+	})
+}
+
+/*
+RunWorker - Donates the calling goroutine to a pool created with CreatePoolExternal, it serves jobs
+as a worker of the pool would until ctx is cancelled, returning ctx.Err(), or the pool closes,
+returning nil. A job being run when ctx is cancelled is finished first, so detaching never loses a
+job. Returns ErrPoolNotRunning if the pool is not open and ErrNotExternal for other pools.
+*/
+func (pool *WorkPool) RunWorker(ctx context.Context) error {
+	external := pool.external
+	if external == nil {
+		return ErrNotExternal
+	}
+	slot, _ := external.slot.Load().(*externalSlot)
+	if slot == nil || !pool.isRunning() {
+		return ErrPoolNotRunning
+	}
+
+	wrapper := &workerWrapper{
+		worker: &(defaultWorker{pool.job}),
+		logger: &pool.logger,
+		clock:  pool.clock,
+		panics: pool.panics,
+		hooks:  pool.hooks,
+	}
+	atomic.AddInt32(&external.attached, 1)
+	defer atomic.AddInt32(&external.attached, -1)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		atomic.AddInt32(&external.idle, 1)
+		select {
+		case slot.ready <- 1:
+		case <-slot.closing:
+			atomic.AddInt32(&external.idle, -1)
+			return nil
+		case <-ctx.Done():
+			atomic.AddInt32(&external.idle, -1)
+			return ctx.Err()
+		}
+		atomic.AddInt32(&external.idle, -1)
+
+		// Every ready signal taken is followed by a job, though it may be taken by another call
+		job, open := <-slot.jobs
+		if !open {
+			return nil
+		}
+		if job.returned {
+			continue
+		}
+		atomic.AddInt32(&external.busy, 1)
+		job.reply <- wrapper.run(job)
+		atomic.AddInt32(&external.busy, -1)
+	}
+}
@@ -0,0 +1,132 @@
+package goroutine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitNumWorkers(t *testing.T, pool *WorkPool, n int) {
+	deadline := time.Now().Add(time.Second)
+	for pool.NumWorkers() != n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if actual := pool.NumWorkers(); actual != n {
+		t.Errorf("Expected %d workers attached, got %d", n, actual)
+	}
+}
+
+func TestExternalWorkers(t *testing.T) {
+	pool, err := CreatePoolExternal(func(in interface{}) interface{} {
+		time.Sleep(time.Millisecond)
+		return in.(int) * 2
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	if n := pool.NumWorkers(); n != 0 {
+		t.Errorf("Expected no workers before RunWorker, got %v", n)
+	}
+	if n := pool.NumStartedWorkers(); n != 0 {
+		t.Errorf("Expected Open to start no workers, got %v", n)
+	}
+
+	var workers sync.WaitGroup
+	attach := func() context.CancelFunc {
+		ctx, cancel := context.WithCancel(context.Background())
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			if err := pool.RunWorker(ctx); err != nil && err != context.Canceled {
+				t.Errorf("Unexpected error from RunWorker: %v", err)
+			}
+		}()
+		return cancel
+	}
+
+	detach := []context.CancelFunc{attach(), attach(), attach()}
+	waitNumWorkers(t, pool, 3)
+
+	const jobs = 200
+	var jobsDone sync.WaitGroup
+	var completed int32
+	jobsDone.Add(jobs)
+	for i := 0; i < jobs; i++ {
+		i := i
+		if err := pool.SendWorkAsync(i, func(result interface{}, err error) {
+			defer jobsDone.Done()
+			if err != nil || result != i*2 {
+				t.Errorf("Expected %v, got %v, %v", i*2, result, err)
+				return
+			}
+			atomic.AddInt32(&completed, 1)
+		}); err != nil {
+			t.Errorf("Failed to send job: %v", err)
+			jobsDone.Done()
+		}
+
+		// Workers come and go while the jobs flow
+		switch i {
+		case 50:
+			detach[0]()
+			detach = append(detach, attach())
+		case 100:
+			detach[1]()
+		case 150:
+			detach = append(detach, attach(), attach())
+		}
+	}
+
+	// With the last worker detached the remaining jobs wait for the next one
+	for _, cancel := range detach {
+		cancel()
+	}
+	waitNumWorkers(t, pool, 0)
+	if _, err := pool.SendWorkTimed(20*time.Millisecond, 1); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut without workers, got %v", err)
+	}
+
+	attach()
+	jobsDone.Wait()
+	if n := atomic.LoadInt32(&completed); n != jobs {
+		t.Errorf("Expected %d jobs to complete, got %d", jobs, n)
+	}
+
+	pool.Close()
+	workers.Wait()
+	if n := pool.NumWorkers(); n != 0 {
+		t.Errorf("Expected the workers to detach on Close, got %v", n)
+	}
+	if err := pool.RunWorker(context.Background()); err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning, got %v", err)
+	}
+}
+
+func TestExternalWorkersOptions(t *testing.T) {
+	pool := CreatePool(1, func(in interface{}) interface{} { return in })
+	if err := pool.RunWorker(context.Background()); err != ErrNotExternal {
+		t.Errorf("Expected ErrNotExternal, got %v", err)
+	}
+
+	if _, err := NewPool(WithJob(func(in interface{}) interface{} { return in }), WithWorkers(2), WithExternalWorkers()); !errors.Is(err, ErrInvalidPoolOptions) {
+		t.Errorf("Expected ErrInvalidPoolOptions, got %v", err)
+	}
+
+	external, err := CreatePoolExternal(func(in interface{}) interface{} { return in }).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer external.Close()
+	if err := external.Resize(1); err != ErrExternalWorkers {
+		t.Errorf("Expected ErrExternalWorkers, got %v", err)
+	}
+	if err := external.StopWorker(0); err != ErrExternalWorkers {
+		t.Errorf("Expected ErrExternalWorkers, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package goroutine
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrInsufficientWorkers = errors.New("fewer workers than requested became available")
+)
+
+/*
+FanOut - Sends the same job to n distinct workers at once and returns all of their results, for
+redundant processing such as voting between the results of several workers. Where Broadcast runs
+a job on every worker, FanOut runs it on the first n workers to become ready, each with its own
+copy of the payload if the pool has a payload cloner, and the results are in the order the workers
+were taken.
+
+Workers are waited for until the timeout set WithDefaultTimeout, if there is one. If fewer than n
+workers are taken by then, or n is more than the workers of the pool, the job still runs on those
+that were taken and their results are returned along with ErrInsufficientWorkers. Otherwise the
+first error of a job is returned along with all of the results, the results of failed jobs left
+nil. As with Broadcast the job is not subject to the pool's admission limits.
+*/
+func (pool *WorkPool) FanOut(work interface{}, n int) ([]interface{}, error) {
+	pool.statusMutex.RLock()
+	defer pool.statusMutex.RUnlock()
+
+	if !pool.isRunning() {
+		return nil, ErrPoolNotRunning
+	}
+	for pool.startWorker() {
+	}
+
+	chosen := pool.takeWorkers(n)
+	results := make([]interface{}, len(chosen))
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i, worker := range chosen {
+		wg.Add(1)
+		go func(i, worker int) {
+			defer wg.Done()
+			job, trace, enqueued := pool.newJob(pool.clonePayload(work))
+			defer job.tracked.abandon()
+			result, err := pool.runJob(worker, job, trace, enqueued)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+				})
+				return
+			}
+			results[i] = result
+		}(i, worker)
+	}
+	wg.Wait()
+
+	if len(chosen) < n {
+		return results, ErrInsufficientWorkers
+	}
+	return results, firstErr
+}
+
+/*
+takeWorkers - Takes the ready signals of up to n distinct workers, waiting until the default
+timeout if there is one, and returns the indexes of the workers taken.
+*/
+func (pool *WorkPool) takeWorkers(n int) []int {
+	want := n
+	if live := pool.numLiveWorkers(); want > live {
+		want = live
+	}
+	if want <= 0 {
+		return nil
+	}
+
+	selectCases := make([]reflect.SelectCase, len(pool.selects), len(pool.selects)+1)
+	copy(selectCases, pool.selects)
+	for i, worker := range pool.workers {
+		if atomic.LoadUint32(&worker.stopped) == 1 {
+			selectCases[i].Chan = reflect.Value{}
+		}
+	}
+	if timeout := pool.Timeout(); timeout > 0 {
+		timer := getTimer(pool.clock, timeout)
+		defer putTimer(timer)
+		selectCases = append(selectCases, reflect.SelectCase{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(timer.C()),
+		})
+	}
+
+	chosen := make([]int, 0, want)
+	for len(chosen) < want {
+		taken, _, ok := reflect.Select(selectCases)
+		if taken >= len(pool.selects) {
+			break
+		}
+
+		// A worker is taken at most once, a closed worker is not waited for again
+		selectCases[taken].Chan = reflect.Value{}
+		if !ok {
+			want--
+			continue
+		}
+		chosen = append(chosen, taken)
+	}
+	return chosen
+}
@@ -0,0 +1,62 @@
+package goroutine
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFanOut(t *testing.T) {
+	customWorkers := make([]GoroutineWorker, 4)
+	for i := range customWorkers {
+		customWorkers[i] = &reloadWorker{index: i}
+	}
+
+	pool, err := CreateCustomPool(customWorkers, WithDefaultTimeout(20*time.Millisecond)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	results, err := pool.FanOut("vote", 3)
+	if err != nil {
+		t.Errorf("Failed to fan out: %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("Wrong number of results: %v != %v", len(results), 3)
+	}
+	seen := map[interface{}]bool{}
+	for _, result := range results {
+		if seen[result] {
+			t.Errorf("Worker %v ran the job twice", result)
+		}
+		seen[result] = true
+	}
+
+	// More workers than the pool has
+	results, err = pool.FanOut("vote", 5)
+	if !errors.Is(err, ErrInsufficientWorkers) {
+		t.Errorf("Expected ErrInsufficientWorkers, got %v", err)
+	}
+	if len(results) != 4 {
+		t.Errorf("Wrong number of results: %v != %v", len(results), 4)
+	}
+
+	// A worker busy past the timeout is not waited for
+	pool.SendWorkAsync("slow", nil)
+	time.Sleep(5 * time.Millisecond)
+
+	results, err = pool.FanOut("vote", 4)
+	if !errors.Is(err, ErrInsufficientWorkers) {
+		t.Errorf("Expected ErrInsufficientWorkers, got %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("Wrong number of results: %v != %v", len(results), 3)
+	}
+
+	pool.Close()
+	if _, err := pool.FanOut("vote", 1); !errors.Is(err, ErrPoolNotRunning) {
+		t.Errorf("Expected ErrPoolNotRunning, got %v", err)
+	}
+}
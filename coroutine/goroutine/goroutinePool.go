@@ -1,9 +1,12 @@
 package goroutine
 
 import (
+	"context"
 	"errors"
 	"expvar"
 	"reflect"
+	"runtime"
+	"runtime/debug"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -18,14 +21,6 @@ func init() {
 
 }
 
-var (
-	ErrPoolAlreadyRunning = errors.New("the pool is already running")
-	ErrPoolNotRunning     = errors.New("the pool is not running")
-	ErrJobNotFunc         = errors.New("generic worker not given a func()")
-	ErrWorkerClosed       = errors.New("worker was closed")
-	ErrJobTimedOut        = errors.New("job request timed out")
-)
-
 type GoroutineWorker interface {
 
 	// Called for each job, expects the result to be returned synchronously
@@ -33,8 +28,8 @@ type GoroutineWorker interface {
 
 	// Called after each job, this indicates whether the worker is ready for the next job.
 	// The default implementation is to return true always. If false is returned then the
-	// method is called every five milliseconds until either true is returned or the pool
-	// is closed.
+	// method is called every five milliseconds, see WithReadyPollInterval, until either true
+	// is returned or the pool is closed.
 	Ready() bool
 }
 
@@ -61,6 +56,42 @@ type GoroutineInterruptable interface {
 	Interrupt()
 }
 
+/*
+GoroutineContextWorker - An optional interface that can be implemented by workers which need the
+context of the caller, JobCtx is then called for each job in place of Job. Jobs submitted with
+SendWorkContext or SendWorkAsyncCtx receive the context they were submitted with, and other jobs
+receive context.Background().
+*/
+type GoroutineContextWorker interface {
+
+	// Called for each job with the context of its caller, expects the result to be returned
+	// synchronously
+	JobCtx(ctx context.Context, in interface{}) interface{}
+}
+
+/*
+GoroutineFlushableWorker - An optional interface that can be implemented by workers which buffer
+state that must be written out before they terminate.
+*/
+type GoroutineFlushableWorker interface {
+
+	// Called when the pool is closed, after the last job and before Terminate. Errors are
+	// reported by CloseErr.
+	Flush() error
+}
+
+/*
+GoroutineReinitializableWorker - An optional interface that can be implemented by workers whose
+state may be left corrupt by a job which panicked. Reinitialize is called after the panic has been
+recovered and before the worker is given another job, and if it fails the worker is replaced, see
+WithWorkerFactory.
+*/
+type GoroutineReinitializableWorker interface {
+
+	// Called after a job has panicked, a non-nil error means the worker cannot carry on.
+	Reinitialize() error
+}
+
 /*
 Default and very basic implementation of a tunny worker. This worker holds a closure which
 is assigned at construction, and this closure is called on each job.
@@ -85,11 +116,72 @@ You may open and close a pool as many times as you wish, calling close is a bloc
 guarantees all goroutines are stopped.
 */
 type WorkPool struct {
+	// workers is read and written with statusMutex held, see setWorkers for other readers
 	workers          []*workerWrapper
+	workerList       atomic.Value
 	selects          []reflect.SelectCase
 	statusMutex      sync.RWMutex
 	running          uint32
 	pendingAsyncJobs int32
+	startedWorkers   int32
+	lazyStart        bool
+	treeReduce       bool
+	scaleDownDelay   time.Duration
+	job              *func(interface{}) interface{}
+	intJob           func(int) int
+	stringJob        func(string) string
+	jobSeq           uint64
+	traceFunc        atomic.Value
+	name             string
+	payloadChecks    []func(interface{}) error
+	admission        func(pending int, running int, jobData interface{}) error
+	queueMemory      *queueMemoryLimit
+	rejectedJobs     uint64
+	shedJobs         uint64
+	borrowedWorkers  int32
+	stoppedWorkers   int32
+	stoppingWorkers  int32
+	adaptive         *adaptiveLimiter
+	watchdog         *stuckWatchdog
+	ordered          *callbackOrderer
+	cloner           func(interface{}) interface{}
+	perCPUJob        func(interface{}) interface{}
+	lockOSThread     bool
+	breaker          *circuitBreaker
+	groups           *isolationGroups
+	nextScan         uint32
+	callerRuns       bool
+	inlineJob        func(interface{}) interface{}
+	callerRanJobs    uint64
+	semaphore        Semaphore
+	metrics          jobMetrics
+	inflight         inflightJobs
+	closing          atomic.Value
+	closingMutex     sync.Mutex
+	closeGrace       time.Duration
+	synchronous      bool
+	bufDepth         int
+	defaultTimeout   int64
+	logger           poolLogger
+	openTimeout      time.Duration
+	unhealthyAfter   time.Duration
+	hooks            *LifecycleHooks
+	reservationHold  time.Duration
+	discarded        discardedResults
+	inFlight         *inFlightGate
+	allocSampling    uint64
+	barriers         barrierQueue
+	panics           *panicHistory
+	workerFactory    func() GoroutineWorker
+	readyTicker      *readyTicker
+	progressInterval time.Duration
+	pending          *pendingQueue
+	burst            *burstWorkers
+	external         *externalWorkers
+	rebalance        rebalancer
+	inspector        *jobInspector
+	clock            Clock
+	config           *poolConfig
 }
 
 func (pool *WorkPool) isRunning() bool {
@@ -112,76 +204,323 @@ func (pool *WorkPool) Open() (*WorkPool, error) {
 	defer pool.statusMutex.Unlock()
 
 	if !pool.isRunning() {
+		if pool.burst != nil && pool.job == nil {
+			return nil, ErrBurstCustom
+		}
+		pool.resetClosing()
+		atomic.StoreUint64(&pool.jobSeq, 0)
+		pool.resizePerCPU()
+		if err := pool.groups.open(pool.numLiveWorkers()); err != nil {
+			return nil, err
+		}
+		if pool.callerRuns && !pool.synchronous {
+			job, err := pool.callerRunsJob()
+			if err != nil {
+				return nil, err
+			}
+			pool.inlineJob = job
+		}
 
 		pool.selects = make([]reflect.SelectCase, len(pool.workers))
 
 		for i, workerWrapper := range pool.workers {
-			workerWrapper.Open()
+			pool.selects[i] = pool.openWorker(i, workerWrapper)
+		}
 
-			pool.selects[i] = reflect.SelectCase{
-				Dir:  reflect.SelectRecv,
-				Chan: reflect.ValueOf(workerWrapper.readyChan),
+		pool.external.open(pool.workers)
+
+		if !pool.lazyStart && pool.external == nil {
+			for pool.startWorker() {
+			}
+		}
+		if pool.openTimeout > 0 {
+			if err := pool.waitWorkersReady(); err != nil {
+				pool.stopWorkers()
+				return nil, err
 			}
 		}
+		pool.watchdog.start(pool.workers)
 
 		pool.setRunning(true)
+		pool.logger.printf("pool opened with %d workers", pool.numLiveWorkers())
 		return pool, nil
 
 	}
 	return nil, ErrPoolAlreadyRunning
 }
 
+/*
+openWorker - Applies the pool's settings to the worker at index and opens its channels, returning
+the case on which the pool selects the worker's ready signal.
+*/
+func (pool *WorkPool) openWorker(index int, workerWrapper *workerWrapper) reflect.SelectCase {
+	workerWrapper.index = index
+	workerWrapper.logger = &pool.logger
+	workerWrapper.watched = pool.watchdog != nil
+	workerWrapper.lockOSThread = pool.lockOSThread
+	workerWrapper.bufDepth = pool.bufDepth
+	workerWrapper.unhealthyAfter = pool.unhealthyAfter
+	workerWrapper.clock = pool.clock
+	workerWrapper.panics = pool.panics
+	workerWrapper.factory = pool.workerFactory
+	workerWrapper.ticker = pool.readyTicker
+	workerWrapper.shared = pool.external != nil
+	if pool.lazyStart {
+		workerWrapper.scaleDownDelay = pool.scaleDownDelay
+		workerWrapper.onRetire = pool.retireWorker
+	}
+	if pool.hooks != nil {
+		workerWrapper.SetHooks(*pool.hooks)
+	}
+	if indexed, ok := workerWrapper.worker.(indexedWorker); ok {
+		indexed.setIndex(index)
+	}
+	if atomic.LoadUint32(&workerWrapper.stopped) == 1 {
+		// A zero channel is ignored by reflect.Select
+		return reflect.SelectCase{Dir: reflect.SelectRecv}
+	}
+	workerWrapper.Open()
+
+	return reflect.SelectCase{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(workerWrapper.readyChan),
+	}
+}
+
+/*
+newJob - Prepares a job for dispatch, assigning its sequence number and capturing the enqueue
+time when the job is being traced.
+*/
+func (pool *WorkPool) newJob(jobData interface{}) (jobRequest, TraceFunc, time.Time) {
+	job := jobRequest{
+		data: jobData,
+		seq:  atomic.AddUint64(&pool.jobSeq, 1),
+	}
+	if uncollected, ok := jobData.(uncollectedJob); ok {
+		job.data = uncollected.data
+		job.uncollected = true
+	}
+	if sequenced, ok := job.data.(sequencedJob); ok {
+		job.data = sequenced.data
+		*sequenced.seq = job.seq
+	}
+	if progress, ok := job.data.(progressJob); ok {
+		job.data = progress.data
+		job.progress = progress.reporter
+	}
+	if pending, ok := job.data.(pendingJob); ok {
+		job.data = pending.data
+		job.pending = pending.entry
+	}
+	if pool.inspector != nil {
+		var labels LabeledJob
+		if described, ok := job.data.(describedJob); ok {
+			job.data = described.data
+			labels = described.labels
+		}
+		job.tracked = pool.inspector.add(job.seq, pool.clock.Now(), job.data, labels)
+	}
+
+	var enqueued time.Time
+	trace := pool.getTraceFunc()
+	if trace != nil {
+		enqueued = pool.clock.Now()
+		job.sampleAllocs = pool.sampleAllocs(job.seq)
+	}
+	return job, trace, enqueued
+}
+
 /*
 Close all channels and goroutines managed by the pool.
 */
 func (pool *WorkPool) Close() error {
+	_, err := pool.close()
+	return err
+}
+
+/*
+CloseErr - Close the pool as Close does, and also return the errors of any workers which failed
+to flush, joined together with the panics recovered from their methods, see CloseReport. Every
+worker is flushed even when an earlier one fails.
+*/
+func (pool *WorkPool) CloseErr() error {
+	workers, err := pool.close()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, worker := range workers {
+		errs = append(errs, worker.errs()...)
+	}
+	return errors.Join(errs...)
+}
+
+func (pool *WorkPool) close() ([]WorkerShutdown, error) {
+	// Synchronous jobs hold the status lock until they complete, so jobs are told the pool is
+	// closing before waiting for it
+	if pool.isRunning() {
+		pool.logger.printf("pool closing")
+		pool.signalClosing()
+		if pool.closeGrace > 0 {
+			timer := pool.clock.AfterFunc(pool.closeGrace, pool.interruptBusyWorkers)
+			defer timer.Stop()
+		}
+	}
+
 	pool.statusMutex.Lock()
 	defer pool.statusMutex.Unlock()
 
 	if pool.isRunning() {
-		for _, workerWrapper := range pool.workers {
-			workerWrapper.Close()
+		pool.watchdog.close()
+		workers := pool.stopWorkers()
+		pool.setRunning(false)
+		pool.logger.printf("pool closed")
+		return workers, nil
+	}
+	return nil, ErrPoolNotRunning
+}
+
+/*
+stopWorkers - Closes every worker and waits for them to terminate, reporting how each one did.
+*/
+func (pool *WorkPool) stopWorkers() []WorkerShutdown {
+	closed := pool.clock.Now()
+	for _, workerWrapper := range pool.workers {
+		workerWrapper.Close()
+	}
+	workers := make([]WorkerShutdown, len(pool.workers))
+	for i, workerWrapper := range pool.workers {
+		running := atomic.LoadUint32(&workerWrapper.started) == 1
+		workerWrapper.Join()
+		if running {
+			workers[i] = workerWrapper.shutdown(closed)
+		} else {
+			workers[i] = WorkerShutdown{Index: i}
 		}
-		for _, workerWrapper := range pool.workers {
-			workerWrapper.Join()
+	}
+	atomic.StoreInt32(&pool.startedWorkers, 0)
+	return workers
+}
+
+/*
+startWorker - Launches the goroutine of the next worker which has not yet been started, returns
+false if every worker is already running.
+*/
+func (pool *WorkPool) startWorker() bool {
+	for _, workerWrapper := range pool.workers {
+		if workerWrapper.Start() {
+			atomic.AddInt32(&pool.startedWorkers, 1)
+			return true
 		}
-		pool.setRunning(false)
-		return nil
 	}
-	return ErrPoolNotRunning
+	return false
 }
 
 /*
-CreatePool - Creates a pool of workers, and takes a closure argument which is the action
-to perform for each job.
+takeIdleWorker - Attempts to take an idle worker without blocking and without going through
+reflect.Select, which allocates on every call. Workers are scanned from a rotating start so that
+jobs are spread over them. Returns the index of the worker taken, or -1 if none was idle.
 */
-func CreatePool(numWorkers int, job func(interface{}) interface{}) *WorkPool {
-	pool := WorkPool{running: 0}
+func (pool *WorkPool) takeIdleWorker() (int, bool) {
+	numWorkers := len(pool.workers)
+	start := int(atomic.AddUint32(&pool.nextScan, 1) % uint32(numWorkers))
 
-	pool.workers = make([]*workerWrapper, numWorkers)
-	for i := range pool.workers {
-		newWorker := workerWrapper{
-			worker: &(defaultWorker{&job}),
+	for i := 0; i < numWorkers; i++ {
+		index := (start + i) % numWorkers
+		workerWrapper := pool.workers[index]
+		if atomic.LoadUint32(&workerWrapper.idle) == 0 {
+			continue
+		}
+		if workerWrapper.shared && atomic.LoadInt32(&pool.external.idle) == 0 {
+			continue
+		}
+		select {
+		case _, ok := <-workerWrapper.readyChan:
+			return index, ok
+		default:
 		}
-		pool.workers[i] = &newWorker
 	}
-	return &pool
+	return -1, true
+}
+
+/*
+demandWorker - Attempts to take an idle worker without blocking and, for lazily started pools
+where none of the started workers are idle, starts another one. Returns the index of the idle
+worker taken, or -1 if the caller should wait on the pool as normal.
+*/
+func (pool *WorkPool) demandWorker() (int, bool) {
+	if len(pool.workers) == 0 {
+		return -1, true
+	}
+	if chosen, ok := pool.takeIdleWorker(); chosen >= 0 {
+		return chosen, ok
+	}
+
+	// A worker which has just finished a job is usually about to report ready again
+	runtime.Gosched()
+	if chosen, ok := pool.takeIdleWorker(); chosen >= 0 {
+		return chosen, ok
+	}
+
+	if pool.lazyStart && int(atomic.LoadInt32(&pool.startedWorkers)) < pool.numLiveWorkers() {
+		pool.startWorker()
+	}
+	return -1, true
+}
+
+/*
+CreatePool - Creates a pool of workers, and takes a closure argument which is the action
+to perform for each job.
+*/
+func CreatePool(numWorkers int, job func(interface{}) interface{}, opts ...Option) *WorkPool {
+	pool, _ := newPool(append([]Option{WithWorkers(numWorkers), WithJob(job)}, opts...))
+	return pool
+}
+
+/*
+GenericJob - A payload for generic pools which carries its argument alongside the function, this
+allows the same function to be submitted many times without allocating a closure for each job.
+*/
+type GenericJob struct {
+	Fn  func(interface{}) interface{}
+	Arg interface{}
 }
 
 /*
 CreatePoolGeneric - Creates a pool of generic workers. When sending work to a pool of
-generic workers you send a closure (func()) which is the job to perform.
+generic workers you send the job to perform, which may be one of:
+
+	func()                  - the job is called and the result is nil
+	func() interface{}      - the job is called and its return value is the result
+	GenericJob, *GenericJob - Fn is called with Arg and its return value is the result
+
+Any other payload results in ErrUnsupportedPayload.
 */
-func CreatePoolGeneric(numWorkers int) *WorkPool {
+func CreatePoolGeneric(numWorkers int, opts ...Option) *WorkPool {
+	pool, _ := newPool(append([]Option{WithWorkers(numWorkers), WithGenericJobs()}, opts...))
+	return pool
+}
 
-	return CreatePool(numWorkers, func(jobCall interface{}) interface{} {
-		if method, ok := jobCall.(func()); ok {
-			method()
-			return nil
+/*
+genericJob - The job of generic workers, which runs the payload sent to them.
+*/
+func genericJob(jobCall interface{}) interface{} {
+	switch method := jobCall.(type) {
+	case func():
+		method()
+		return nil
+	case func() interface{}:
+		return method()
+	case GenericJob:
+		if method.Fn != nil {
+			return method.Fn(method.Arg)
 		}
-		return ErrJobNotFunc
-	})
-
+	case *GenericJob:
+		if method != nil && method.Fn != nil {
+			return method.Fn(method.Arg)
+		}
+	}
+	return ErrUnsupportedPayload
 }
 
 /*
@@ -189,130 +528,462 @@ CreateCustomPool - Creates a pool for an array of custom workers. The custom wor
 must implement TunnyWorker, and may also optionally implement TunnyExtendedWorker and
 TunnyInterruptable.
 */
-func CreateCustomPool(customWorkers []GoroutineWorker) *WorkPool {
-	pool := WorkPool{running: 0}
+func CreateCustomPool(customWorkers []GoroutineWorker, opts ...Option) *WorkPool {
+	pool, _ := newPool(append([]Option{WithCustomWorkers(customWorkers)}, opts...))
+	return pool
+}
 
-	pool.workers = make([]*workerWrapper, len(customWorkers))
-	for i := range pool.workers {
-		newWorker := workerWrapper{
-			worker: customWorkers[i],
-		}
-		pool.workers[i] = &newWorker
+/*
+SendWorkTimed - Send a job to a worker and return the result, this is a synchronous
+call with a timeout.
+*/
+func (pool *WorkPool) SendWorkTimed(timeout time.Duration, jobData interface{}) (interface{}, error) {
+	deadline := pool.clock.Now().Add(timeout)
+
+	jobData = pool.clonePayload(jobData)
+	ticket, err := pool.admitUntil(context.Background(), deadline, jobData)
+	if err != nil {
+		return nil, err
 	}
+	defer pool.release(jobData, ticket)
 
-	return &pool
+	result, err := pool.sendWorkUntil(context.Background(), deadline, jobData)
+	pool.breaker.done(ticket.probe, result, err)
+	return result, err
 }
 
 /*
-SendWorkTimed - Send a job to a worker and return the result, this is a synchronous
-call with a timeout.
+SendWorkTimedMs - Send a job to a worker with a timeout in milliseconds, as SendWorkTimed did before
+it took a time.Duration.
+
+Deprecated: Use SendWorkTimed, SendWorkTimedMs(50, job) is SendWorkTimed(50*time.Millisecond, job).
+*/
+func (pool *WorkPool) SendWorkTimedMs(ms int, jobData interface{}) (interface{}, error) {
+	return pool.SendWorkTimed(time.Duration(ms)*time.Millisecond, jobData)
+}
+
+func (pool *WorkPool) sendWorkTimed(timeout time.Duration, jobData interface{}) (interface{}, error) {
+	return pool.sendWorkUntil(context.Background(), pool.clock.Now().Add(timeout), jobData)
+}
+
+/*
+sendWorkUntil - Sends a job to a worker and waits for the result until either the deadline, if
+not zero, passes or ctx is done, whichever comes first. A job given up on once it has been
+dispatched is interrupted and its result collected in the background so that the worker can move
+on.
 */
-func (pool *WorkPool) SendWorkTimed(milliTimeout time.Duration, jobData interface{}) (interface{}, error) {
+func (pool *WorkPool) sendWorkUntil(ctx context.Context, deadline time.Time, jobData interface{}) (interface{}, error) {
 	pool.statusMutex.RLock()
 	defer pool.statusMutex.RUnlock()
 
-	if pool.isRunning() {
-		before := time.Now()
+	if !pool.isRunning() {
+		return nil, ErrPoolNotRunning
+	}
+	if ctx.Err() != nil {
+		return nil, cancelled(ctx)
+	}
 
-		// Create new selectcase[] and add time out case
-		selectCases := append(pool.selects[:], reflect.SelectCase{
-			Dir:  reflect.SelectRecv,
-			Chan: reflect.ValueOf(time.After(milliTimeout * time.Millisecond)),
-		})
+	job, trace, enqueued := pool.newJob(jobData)
+	defer job.tracked.abandon()
+	job.ctx = ctx
+	cancel := ctx.Done()
 
-		// Wait for workers, or time out
-		if chosen, _, ok := reflect.Select(selectCases); ok {
-
-			// Check if the selected index is a worker, otherwise we timed out
-			if chosen < (len(selectCases) - 1) {
-				pool.workers[chosen].jobChan <- jobData
-
-				// Wait for response, or time out
-				select {
-				case data, open := <-pool.workers[chosen].outputChan:
-					if !open {
-						return nil, ErrWorkerClosed
-					}
-					return data, nil
-				case <-time.After((milliTimeout * time.Millisecond) - time.Since(before)):
-					/* If we time out here we also need to ensure that the output is still
-					 * collected and that the worker can move on. Therefore, we fork the
-					 * waiting process into a new goroutine.
-					 */
-					go func() {
-						pool.workers[chosen].Interrupt()
-						<-pool.workers[chosen].outputChan
-					}()
-					return nil, ErrJobTimedOut
-				}
-			} else {
-				return nil, ErrJobTimedOut
-			}
-		} else {
-			// This means the chosen channel was closed
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := getTimer(pool.clock, deadline.Sub(pool.clock.Now()))
+		defer putTimer(timer)
+		timeout = timer.C()
+	}
+
+	if !pool.groups.acquire("", timeout, cancel) {
+		err, outcome := expired(ctx, job, false)
+		pool.finishJob(trace, job, -1, enqueued, jobResult{}, outcome)
+		return nil, err
+	}
+	if !pool.adaptive.acquire(timeout, cancel) {
+		pool.groups.release("")
+		err, outcome := expired(ctx, job, false)
+		pool.finishJob(trace, job, -1, enqueued, jobResult{}, outcome)
+		return nil, err
+	}
+	if pool.acquireSemaphoreUntil(ctx, deadline) != nil {
+		pool.adaptive.release(time.Time{})
+		pool.groups.release("")
+		err, outcome := expired(ctx, job, false)
+		pool.finishJob(trace, job, -1, enqueued, jobResult{}, outcome)
+		return nil, err
+	}
+
+	if pool.synchronous {
+		return pool.runSynchronous(ctx, job, trace, enqueued, timeout)
+	}
+
+	// Wait for workers, or time out
+	chosen, ok := pool.demandWorker()
+	if chosen < 0 {
+		// Create new selectcase[] and add the time out and cancel cases
+		selectCases := append(pool.selects[:len(pool.selects):len(pool.selects)],
+			reflect.SelectCase{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(timeout),
+			},
+			reflect.SelectCase{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(cancel),
+			},
+		)
+		chosen, _, ok = reflect.Select(selectCases)
+	}
+
+	// Check if the selected index is a worker, otherwise we timed out or were cancelled
+	if chosen >= len(pool.selects) {
+		pool.adaptive.release(time.Time{})
+		pool.releaseSemaphore()
+		pool.groups.release("")
+		err, outcome := expired(ctx, job, false)
+		pool.finishJob(trace, job, -1, enqueued, jobResult{}, outcome)
+		return nil, err
+	}
+	if !ok {
+		// This means the chosen channel was closed
+		pool.adaptive.release(time.Time{})
+		pool.releaseSemaphore()
+		pool.groups.release("")
+		return nil, ErrWorkerClosed
+	}
+
+	worker := pool.workers[chosen]
+	output, reply := worker.dispatch(job)
+	dispatched := pool.adaptive.now()
+
+	// Wait for response, or time out
+	select {
+	case result, open := <-output:
+		collected(output, reply)
+		pool.adaptive.release(dispatched)
+		pool.releaseSemaphore()
+		pool.groups.release("")
+		if !open {
 			return nil, ErrWorkerClosed
 		}
-	} else {
-		return nil, ErrPoolNotRunning
+		pool.finishJob(trace, job, chosen, enqueued, result, JobOK)
+		if result.panicked {
+			return nil, result.panicError()
+		}
+		return result.data, result.err
+	case <-timeout:
+	case <-cancel:
 	}
+
+	/* If we give up here we also need to ensure that the output is still collected and that
+	 * the worker can move on. Therefore, we fork the waiting process into a new goroutine. It
+	 * may outlive Close, so the worker is interrupted before and not touched by the goroutine.
+	 */
+	err, outcome := expired(ctx, job, true)
+	pool.inFlight.extend()
+	worker.interruptJob()
+	go func() {
+		result := <-output
+		collected(output, reply)
+		pool.adaptive.release(dispatched)
+		pool.releaseSemaphore()
+		pool.groups.release("")
+		pool.inFlight.leave()
+		pool.finishJob(trace, job, chosen, enqueued, result, outcome)
+	}()
+	return nil, err
+}
+
+/*
+expired - The error and trace outcome of a job given up on, running if it had reached a worker.
+Cancellation of the context takes precedence over a time out.
+*/
+func expired(ctx context.Context, job jobRequest, running bool) (error, JobOutcome) {
+	if ctx.Err() != nil {
+		return cancelled(ctx), JobCancelled
+	}
+	return &TimeoutError{Seq: job.seq, Running: running}, JobTimedOut
 }
 
 /*
 SendWorkTimedAsync - Send a timed job to a worker without blocking, and optionally
 send the result to a receiving closure. You may set the closure to nil if no
-further actions are required.
+further actions are required. An error is returned if the job is rejected at submission.
 */
 func (pool *WorkPool) SendWorkTimedAsync(
-	milliTimeout time.Duration,
+	timeout time.Duration,
 	jobData interface{},
 	after func(interface{}, error),
-) {
-	atomic.AddInt32(&pool.pendingAsyncJobs, 1)
-	go func() {
-		defer atomic.AddInt32(&pool.pendingAsyncJobs, -1)
-		result, err := pool.SendWorkTimed(milliTimeout, jobData)
-		if after != nil {
-			after(result, err)
-		}
-	}()
+) error {
+	jobData = pool.clonePayload(jobData)
+	ticket, err := pool.admit(jobData)
+	if err != nil {
+		return err
+	}
+
+	seq := pool.ordered.reserve()
+
+	submitted := jobData
+	if after == nil {
+		submitted = uncollectedJob{jobData}
+	}
+	pool.runAsync(jobData, ticket, seq, after, func() (interface{}, error) {
+		return pool.sendWorkTimed(timeout, submitted)
+	})
+	return nil
 }
 
 /*
-SendWork - Send a job to a worker and return the result, this is a synchronous call.
+SendWork - Send a job to a worker and return the result, this is a synchronous call. If the job
+panics the panic is recovered on the worker and a *PanicError is returned. A pool with a default
+timeout, see WithDefaultTimeout, gives up with a *TimeoutError once it has passed, see errors.go.
 */
 func (pool *WorkPool) SendWork(jobData interface{}) (interface{}, error) {
+	return pool.send(pool.clonePayload(jobData))
+}
+
+/*
+send - Admits a job and waits for its result as SendWork does, the payload is already cloned.
+*/
+func (pool *WorkPool) send(jobData interface{}) (interface{}, error) {
+	deadline := pool.defaultDeadline()
+
+	ticket, err := pool.admitUntil(context.Background(), deadline, jobData)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.release(jobData, ticket)
+
+	result, err := pool.sendWorkDefault(deadline, jobData)
+	pool.breaker.done(ticket.probe, result, err)
+	return result, err
+}
+
+/*
+sendWork - Waits for a worker to run a job, a job of a group first waits for a slot of its group.
+With callerRuns the job is run on the calling goroutine if every worker is busy, rather than
+still coming up after Open or a lazy start.
+*/
+func (pool *WorkPool) sendWork(group string, callerRuns bool, jobData interface{}) (interface{}, error) {
 	pool.statusMutex.RLock()
 	defer pool.statusMutex.RUnlock()
 
 	if pool.isRunning() {
-		if chosen, _, ok := reflect.Select(pool.selects); ok && chosen >= 0 {
-			pool.workers[chosen].jobChan <- jobData
-			result, open := <-pool.workers[chosen].outputChan
+		job, trace, enqueued := pool.newJob(jobData)
+		defer job.tracked.abandon()
 
-			if !open {
-				return nil, ErrWorkerClosed
-			}
-			return result, nil
+		pool.groups.acquire(group, nil, nil)
+		defer pool.groups.release(group)
+
+		pool.adaptive.acquire(nil, nil)
+		if err := pool.acquireSemaphore(context.Background()); err != nil {
+			pool.adaptive.release(time.Time{})
+			return nil, err
+		}
+		defer pool.releaseSemaphore()
+
+		chosen, ok := pool.demandWorker()
+		if chosen < 0 && pool.synchronous {
+			pool.adaptive.release(time.Time{})
+			return pool.runOnCaller(job, trace, enqueued)
+		}
+		if chosen < 0 && callerRuns && !pool.workersStarting() {
+			pool.adaptive.release(time.Time{})
+			atomic.AddUint64(&pool.callerRanJobs, 1)
+			return pool.runOnCaller(job, trace, enqueued)
 		}
+		var reply chan jobResult
+		if chosen < 0 {
+			chosen, ok, reply = pool.selectWorker(job)
+		}
+		if reply != nil {
+			dispatched := pool.adaptive.now()
+			result, err := pool.collectBurst(reply, job, trace, enqueued)
+			pool.adaptive.release(dispatched)
+			return result, err
+		}
+		if ok && chosen >= 0 {
+			dispatched := pool.adaptive.now()
+			result, err := pool.runJob(chosen, job, trace, enqueued)
+			pool.adaptive.release(dispatched)
+			return result, err
+		}
+		pool.adaptive.release(time.Time{})
 		return nil, ErrWorkerClosed
 	}
 	return nil, ErrPoolNotRunning
 }
 
+/*
+runJob - Hands a job to the chosen worker, which must have signalled that it is ready, and waits
+for the result.
+*/
+func (pool *WorkPool) runJob(chosen int, job jobRequest, trace TraceFunc, enqueued time.Time) (interface{}, error) {
+	output, reply := pool.workers[chosen].dispatch(job)
+	result, open := <-output
+	collected(output, reply)
+
+	if !open {
+		return nil, ErrWorkerClosed
+	}
+	if result.skipped {
+		return nil, result.err
+	}
+	pool.finishJob(trace, job, chosen, enqueued, result, JobOK)
+	if result.panicked {
+		return nil, result.panicError()
+	}
+	return result.data, result.err
+}
+
+/*
+SendWorkOrDrop - Send a job to a worker only if one is idle right now and return the result and
+true, this is a synchronous call once a worker has been found. If every worker is busy the job is
+dropped and nil, false is returned immediately without queuing the job or creating a goroutine.
+A job which panics returns a nil result.
+*/
+func (pool *WorkPool) SendWorkOrDrop(jobData interface{}) (interface{}, bool) {
+	jobData = pool.clonePayload(jobData)
+	ticket, err := pool.admit(jobData)
+	if err != nil {
+		return nil, false
+	}
+	defer pool.release(jobData, ticket)
+
+	pool.statusMutex.RLock()
+	defer pool.statusMutex.RUnlock()
+
+	if !pool.isRunning() || !pool.groups.tryAcquire("") {
+		pool.breaker.cancel(ticket.probe)
+		return nil, false
+	}
+	defer pool.groups.release("")
+
+	if pool.acquireSemaphore(cancelledContext) != nil {
+		pool.breaker.cancel(ticket.probe)
+		return nil, false
+	}
+	defer pool.releaseSemaphore()
+
+	if pool.synchronous {
+		job, trace, enqueued := pool.newJob(jobData)
+		result, err := pool.runOnCaller(job, trace, enqueued)
+		pool.breaker.done(ticket.probe, result, err)
+		return result, true
+	}
+
+	chosen, ok := -1, true
+	if len(pool.workers) > 0 {
+		chosen, ok = pool.takeIdleWorker()
+	}
+	if chosen < 0 || !ok {
+		// Lazily started pools bring up another worker for the next submission
+		if pool.lazyStart {
+			pool.startWorker()
+		}
+		pool.breaker.cancel(ticket.probe)
+		return nil, false
+	}
+
+	job, trace, enqueued := pool.newJob(jobData)
+	defer job.tracked.abandon()
+	result, err := pool.runJob(chosen, job, trace, enqueued)
+	pool.breaker.done(ticket.probe, result, err)
+	if err == ErrWorkerClosed {
+		return nil, false
+	}
+	return result, true
+}
+
 /*
 SendWorkAsync - Send a job to a worker without blocking, and optionally send the
 result to a receiving closure. You may set the closure to nil if no further actions
-are required.
+are required. An error is returned if the job is rejected at submission.
 */
-func (pool *WorkPool) SendWorkAsync(jobData interface{}, after func(interface{}, error)) {
+func (pool *WorkPool) SendWorkAsync(jobData interface{}, after func(interface{}, error)) error {
+	return pool.sendWorkAsync(cancelledContext, jobData, after)
+}
+
+/*
+sendWorkAsync - Submits a job for SendWorkAsync, waiting for a slot under WithMaxInFlight until
+ctx is done.
+*/
+func (pool *WorkPool) sendWorkAsync(ctx context.Context, jobData interface{}, after func(interface{}, error)) error {
+	labeled, isLabeled := jobData.(LabeledJob)
+	if isLabeled {
+		jobData = labeled.Data
+	}
+	jobData = pool.clonePayload(jobData)
+	ticket, err := pool.admitUntil(ctx, time.Time{}, jobData)
+	if err != nil {
+		return err
+	}
+
+	seq := pool.ordered.reserve()
+
+	submitted := jobData
+	if isLabeled && pool.inspector != nil {
+		submitted = describedJob{jobData, labeled}
+	}
+	var entry *pendingEntry
+	if pool.pending != nil && !pool.synchronous {
+		labeled.Data = jobData
+		entry = pool.pending.add(labeled)
+		submitted = pendingJob{submitted, entry}
+	}
+	if after == nil {
+		submitted = uncollectedJob{submitted}
+	}
+	send := func() (interface{}, error) {
+		return pool.sendWork("", false, submitted)
+	}
+	if entry != nil {
+		send = entry.send(pool, send)
+	}
+	pool.runAsync(jobData, ticket, seq, after, send)
+	return nil
+}
+
+/*
+runAsync - Runs an admitted asynchronous job on a goroutine of its own, or inline on a synchronous
+pool, with send submitting it to a worker. The job is counted as pending from now until its
+callback has returned, and whatever becomes of the job, whether it completes, times out, is
+cancelled or finds the pool closed, the count is lowered exactly once. A callback which panics is
+recovered and logged, so it cannot take the goroutine down before the count is lowered or stall
+the callbacks of an ordered pool.
+*/
+func (pool *WorkPool) runAsync(jobData interface{}, ticket admission, seq uint64, after func(interface{}, error), send func() (interface{}, error)) {
 	atomic.AddInt32(&pool.pendingAsyncJobs, 1)
-	go func() {
+	run := func() {
 		defer atomic.AddInt32(&pool.pendingAsyncJobs, -1)
-		result, err := pool.SendWork(jobData)
-		if after != nil {
-			after(result, err)
-		}
-	}()
+		result, err := send()
+		pool.release(jobData, ticket)
+		pool.breaker.done(ticket.probe, result, err)
+		pool.ordered.complete(seq, pool.guardCallback(after), result, err)
+	}
+	if pool.synchronous {
+		run()
+	} else {
+		go run()
+	}
+}
+
+/*
+guardCallback - Wraps the callback of an asynchronous job so that a panic in it is recovered and
+logged rather than crashing the program, nil stays nil.
+*/
+func (pool *WorkPool) guardCallback(after func(interface{}, error)) func(interface{}, error) {
+	if after == nil {
+		return nil
+	}
+	return func(result interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				pool.logger.printf("callback of an async job panicked: %v\n%s", r, debug.Stack())
+			}
+		}()
+		after(result, err)
+	}
 }
 
 /*
@@ -323,10 +994,224 @@ func (pool *WorkPool) NumPendingAsyncJobs() int32 {
 }
 
 /*
-NumWorkers - Number of workers in the pool
+NumWorkers - Number of workers in the pool, excluding any borrowed with BorrowWorker or stopped
+with StopWorker
 */
 func (pool *WorkPool) NumWorkers() int {
-	return len(pool.workers)
+	if pool.external != nil {
+		return int(atomic.LoadInt32(&pool.external.attached))
+	}
+	return pool.numLiveWorkers() - int(atomic.LoadInt32(&pool.borrowedWorkers))
+}
+
+/*
+numLiveWorkers - Number of workers in the pool which have not been stopped with StopWorker.
+*/
+func (pool *WorkPool) numLiveWorkers() int {
+	return len(pool.lockFreeWorkers()) - int(atomic.LoadInt32(&pool.stoppedWorkers))
+}
+
+/*
+setWorkers - Replaces the workers of the pool, called with statusMutex locked or before the pool is
+shared. The slice is also published for readers which do not lock statusMutex, such as NumWorkers
+or Snapshot, so it must not be modified in place once set.
+*/
+func (pool *WorkPool) setWorkers(workers []*workerWrapper) {
+	pool.workers = workers
+	pool.workerList.Store(workers)
+}
+
+/*
+lockFreeWorkers - The workers of the pool as last set by setWorkers, for reading them without
+locking statusMutex while Resize may be adding to them.
+*/
+func (pool *WorkPool) lockFreeWorkers() []*workerWrapper {
+	workers, _ := pool.workerList.Load().([]*workerWrapper)
+	return workers
+}
+
+/*
+BorrowWorker - Waits for an idle worker and removes it from the pool's rotation so that the caller
+can use it exclusively, for example for a long running task which owns the worker for its whole
+duration. The worker is returned along with a release function which hands it back to the pool,
+no jobs are dispatched to the worker until then and it is not initialized again on release.
+
+Closing the pool waits for every borrowed worker to be released, and the borrower must not submit
+work to the same pool while a Close may be pending.
+*/
+func (pool *WorkPool) BorrowWorker() (GoroutineWorker, func(), error) {
+	if pool.bufDepth > 0 {
+		return nil, nil, ErrBorrowBuffered
+	}
+	if pool.external != nil {
+		return nil, nil, ErrExternalWorkers
+	}
+
+	pool.statusMutex.RLock()
+
+	if !pool.isRunning() {
+		pool.statusMutex.RUnlock()
+		return nil, nil, ErrPoolNotRunning
+	}
+
+	chosen, ok := pool.demandWorker()
+	if chosen < 0 {
+		chosen, _, ok = reflect.Select(pool.selects)
+	}
+	if !ok || chosen < 0 {
+		pool.statusMutex.RUnlock()
+		return nil, nil, ErrWorkerClosed
+	}
+
+	wrapper := pool.workers[chosen]
+	atomic.AddInt32(&pool.borrowedWorkers, 1)
+
+	releaseOnce := sync.Once{}
+	release := func() {
+		releaseOnce.Do(func() {
+			wrapper.jobChan <- jobRequest{returned: true}
+			atomic.AddInt32(&pool.borrowedWorkers, -1)
+			pool.statusMutex.RUnlock()
+		})
+	}
+	return wrapper.currentWorker(), release, nil
+}
+
+/*
+SetWorker - Hot-swaps the worker at index for worker without closing the pool, for example for a
+canary rollout of a new worker implementation. The call waits for the current worker to finish
+its job, terminates it and initializes the new worker if they are extended workers, and the slot
+stays in rotation so that its next job runs on the new worker after a brief stall at most. Use
+ReplaceWorker to take the slot out of rotation for the swap instead.
+
+Returns ErrPoolNotRunning on a closed pool, ErrWorkerIndex for an index outside the pool and
+ErrWorkerStopped for a worker stopped with StopWorker.
+*/
+func (pool *WorkPool) SetWorker(index int, worker GoroutineWorker) error {
+	if worker == nil {
+		return ErrWorkerNil
+	}
+	if pool.external != nil {
+		return ErrExternalWorkers
+	}
+
+	pool.statusMutex.RLock()
+	defer pool.statusMutex.RUnlock()
+
+	if !pool.isRunning() {
+		return ErrPoolNotRunning
+	}
+	if index < 0 || index >= len(pool.workers) {
+		return ErrWorkerIndex
+	}
+	wrapper := pool.workers[index]
+	if atomic.LoadUint32(&wrapper.stopped) == 1 {
+		return ErrWorkerStopped
+	}
+
+	if indexed, ok := worker.(indexedWorker); ok {
+		indexed.setIndex(index)
+	}
+	return wrapper.SetWorker(worker)
+}
+
+/*
+Name - The name given to the pool with WithPoolName, empty if the pool was not named.
+*/
+func (pool *WorkPool) Name() string {
+	return pool.name
+}
+
+/*
+NumStartedWorkers - Number of workers whose goroutines have been launched, this is NumWorkers
+for a running pool unless it was created WithLazyStart.
+*/
+func (pool *WorkPool) NumStartedWorkers() int {
+	return int(atomic.LoadInt32(&pool.startedWorkers))
+}
+
+/*
+NumIdleWorkers - Number of workers currently waiting for a job, so that callers can tell whether a
+job sent now would start straight away or wait. Each worker flags itself idle while it signals that
+it is ready and clears the flag once it is given a job, so the count is read without locking the
+pool and may be out of date as soon as it is returned. A buffered worker counts as idle while it
+has room for another job.
+*/
+func (pool *WorkPool) NumIdleWorkers() int {
+	idle := 0
+	for _, workerWrapper := range pool.lockFreeWorkers() {
+		if workerWrapper.shared {
+			idle += int(atomic.LoadInt32(&pool.external.idle))
+			continue
+		}
+		if workerWrapper.bufDepth > 0 {
+			// A buffered worker is idle while it has room for another job
+			if len(workerWrapper.readyChan) > 0 {
+				idle++
+			}
+			continue
+		}
+		if atomic.LoadUint32(&workerWrapper.idle) == 1 {
+			idle++
+		}
+	}
+	return idle
+}
+
+/*
+numRunningJobs - Number of workers currently running a job.
+*/
+func (pool *WorkPool) numRunningJobs() int {
+	running := 0
+	for _, workerWrapper := range pool.lockFreeWorkers() {
+		if workerWrapper.shared {
+			running += int(atomic.LoadInt32(&pool.external.busy))
+			continue
+		}
+		if atomic.LoadUint32(&workerWrapper.busy) == 1 {
+			running++
+		}
+	}
+	return running
+}
+
+/*
+GoroutineWorkerPool - A pool which can itself be used as the worker of another pool, this allows
+stages of a job to be processed by sub-pools with their own concurrency.
+*/
+type GoroutineWorkerPool interface {
+	GoroutineWorker
+
+	SendWork(interface{}) (interface{}, error)
+	NumWorkers() int
+}
+
+var _ GoroutineWorkerPool = (*WorkPool)(nil)
+
+/*
+Job - Implements GoroutineWorker by sending the job to this pool and blocking until it completes,
+errors from the pool are returned as the result.
+*/
+func (pool *WorkPool) Job(jobData interface{}) interface{} {
+	result, err := pool.SendWork(jobData)
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+/*
+Ready - Implements GoroutineWorker, the pool is ready when it is running and has an idle worker
+or, for lazily started pools, a worker which has yet to be started.
+*/
+func (pool *WorkPool) Ready() bool {
+	if !pool.isRunning() {
+		return false
+	}
+	if pool.lazyStart && pool.NumStartedWorkers() < pool.numLiveWorkers() {
+		return true
+	}
+	return pool.NumIdleWorkers() > 0
 }
 
 type liveVarAccessor func() string
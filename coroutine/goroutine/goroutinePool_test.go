@@ -1,7 +1,13 @@
 package goroutine
 
 import (
+	"context"
+	"errors"
+	"math/rand"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -203,12 +209,63 @@ func TestDummyExtIntWorker(t *testing.T) {
 	defer pool.Close()
 
 	for i := 0; i < 100; i++ {
-		if _, err := pool.SendWorkTimed(1, nil); err == nil {
+		if _, err := pool.SendWorkTimed(time.Millisecond, nil); err == nil {
 			t.Errorf("Expected timeout from dummyExtIntWorker.")
 		}
 	}
 }
 
+func TestSendWorkTimedMs(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		time.Sleep(in.(time.Duration))
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if _, err := pool.SendWorkTimedMs(5, 50*time.Millisecond); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut, got %v", err)
+	}
+	if result, err := pool.SendWorkTimedMs(1000, time.Duration(0)); err != nil || result != time.Duration(0) {
+		t.Errorf("Expected 0, got %v, %v", result, err)
+	}
+}
+
+func TestSendWorkTimedAsync(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		time.Sleep(in.(time.Duration))
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	errs := make(chan error, 1)
+	before := time.Now()
+	if err := pool.SendWorkTimedAsync(20*time.Millisecond, time.Second, func(_ interface{}, err error) {
+		errs <- err
+	}); err != nil {
+		t.Fatalf("Failed to send work: %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrJobTimedOut) {
+			t.Errorf("Expected ErrJobTimedOut, got %v", err)
+		}
+		if elapsed := time.Since(before); elapsed > 500*time.Millisecond {
+			t.Errorf("Expected the job to time out after 20ms, took %v", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Timed async job did not time out")
+	}
+}
+
 func TestNumWorkers(t *testing.T) {
 	numWorkers := 10
 	pool, err := CreatePoolGeneric(numWorkers).Open()
@@ -223,6 +280,91 @@ func TestNumWorkers(t *testing.T) {
 	}
 }
 
+func TestNumIdleWorkers(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	pool, err := CreatePool(3, func(in interface{}) interface{} {
+		started <- struct{}{}
+		<-release
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	waitIdle := func(n int) {
+		for i := 0; i < 1000 && pool.NumIdleWorkers() != n; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		if actual := pool.NumIdleWorkers(); actual != n {
+			t.Errorf("Expected %d idle workers, got %d", n, actual)
+		}
+	}
+	waitIdle(3)
+
+	pool.SendWorkAsync(1, nil)
+	pool.SendWorkAsync(2, nil)
+	<-started
+	<-started
+	waitIdle(1)
+
+	close(release)
+	waitIdle(3)
+}
+
+func TestGenericPayloads(t *testing.T) {
+	pool, err := CreatePoolGeneric(2).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	called := false
+	if result, err := pool.SendWork(func() { called = true }); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	} else if result != nil || !called {
+		t.Errorf("Expected func() to be called with a nil result, got %v", result)
+	}
+
+	if result, err := pool.SendWork(func() interface{} { return 42 }); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	} else if result != 42 {
+		t.Errorf("Wrong return value: %v != %v", 42, result)
+	}
+
+	double := func(in interface{}) interface{} { return in.(int) * 2 }
+	for i, job := range []interface{}{
+		GenericJob{Fn: double, Arg: 10},
+		&GenericJob{Fn: double, Arg: 10},
+	} {
+		if result, err := pool.SendWork(job); err != nil {
+			t.Errorf("Failed to send work: %v", err)
+		} else if result != 20 {
+			t.Errorf("Wrong return value for job %v: %v != %v", i, 20, result)
+		}
+	}
+}
+
+func TestGenericUnsupportedPayload(t *testing.T) {
+	pool, err := CreatePoolGeneric(1).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	for _, job := range []interface{}{10, "job", func(int) {}, GenericJob{}, (*GenericJob)(nil)} {
+		if result, err := pool.SendWork(job); err != nil {
+			t.Errorf("Failed to send work: %v", err)
+		} else if result != ErrUnsupportedPayload {
+			t.Errorf("Expected ErrUnsupportedPayload for %T, got %v", job, result)
+		}
+	}
+}
+
 var waitHalfSecond = func() {
 	time.Sleep(500 * time.Millisecond)
 }
@@ -256,3 +398,664 @@ func TestNumPendingReportsNotAllWorkersWhenSomeBusy(t *testing.T) {
 		t.Errorf("Expected to get %d pending jobs when pool has work, but got %d", expected, actual)
 	}
 }
+
+func TestNumPendingReturnsToZero(t *testing.T) {
+	jobs := 20000
+	if testing.Short() {
+		jobs = 2000
+	}
+
+	pool, err := CreatePoolGeneric(4).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var accepted, called int64
+	callback := func(panics bool) func(interface{}, error) {
+		return func(interface{}, error) {
+			atomic.AddInt64(&called, 1)
+			if panics {
+				panic("callback failed")
+			}
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		job := func() interface{} { return nil }
+		switch rnd.Intn(8) {
+		case 0:
+			job = func() interface{} { panic("job failed") }
+		case 1:
+			job = func() interface{} { time.Sleep(time.Millisecond); return nil }
+		}
+		after := callback(rnd.Intn(4) == 0)
+
+		var err error
+		switch rnd.Intn(5) {
+		case 0:
+			err = pool.SendWorkAsync(job, nil)
+			after = nil
+		case 1:
+			err = pool.SendWorkTimedAsync(time.Millisecond, job, after)
+		case 2:
+			err = pool.SendWorkAsyncCtx(cancelled, job, func(_ context.Context, result interface{}, err error) {
+				after(result, err)
+			})
+		default:
+			err = pool.SendWorkAsync(job, after)
+		}
+		if err == nil && after != nil {
+			atomic.AddInt64(&accepted, 1)
+		}
+
+		// Close and reopen the pool now and then with jobs still pending
+		if rnd.Intn(1000) == 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				pool.Close()
+				pool.Open()
+			}()
+		}
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for pool.NumPendingAsyncJobs() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if pending := pool.NumPendingAsyncJobs(); pending != 0 {
+		t.Errorf("Expected no pending jobs, got %v", pending)
+	}
+	if accepted != atomic.LoadInt64(&called) {
+		t.Errorf("Expected %v callbacks, got %v", accepted, called)
+	}
+	pool.Close()
+}
+
+/*--------------------------------------------------------------------------------------------------
+ */
+
+// Extended worker which counts its lifecycle calls
+type countingExtWorker struct {
+	initialized int32
+	terminated  int32
+}
+
+func (c *countingExtWorker) Job(in interface{}) interface{} {
+	if f, ok := in.(func()); ok {
+		f()
+	}
+	return in
+}
+
+func (c *countingExtWorker) Ready() bool {
+	return true
+}
+
+func (c *countingExtWorker) Initialize() {
+	atomic.AddInt32(&c.initialized, 1)
+}
+
+func (c *countingExtWorker) Terminate() {
+	atomic.AddInt32(&c.terminated, 1)
+}
+
+func TestLazyStart(t *testing.T) {
+	numWorkers := 4
+	workers := make([]GoroutineWorker, numWorkers)
+	counters := make([]*countingExtWorker, numWorkers)
+	for i := range workers {
+		counters[i] = &countingExtWorker{}
+		workers[i] = counters[i]
+	}
+
+	pool, err := CreateCustomPool(workers, WithLazyStart()).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	if actual := pool.NumStartedWorkers(); actual != 0 {
+		t.Errorf("Expected no started workers after Open, but got %d", actual)
+	}
+	if actual := pool.NumWorkers(); actual != numWorkers {
+		t.Errorf("Expected to get %d workers, but got %d", numWorkers, actual)
+	}
+
+	if _, err := pool.SendWork(nil); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	if actual := pool.NumStartedWorkers(); actual != 1 {
+		t.Errorf("Expected one started worker after a single job, but got %d", actual)
+	}
+
+	// Block every worker at once so that each concurrent job demands a new worker
+	release := make(chan struct{})
+	started := sync.WaitGroup{}
+	done := sync.WaitGroup{}
+	started.Add(numWorkers)
+	done.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			defer done.Done()
+			if _, err := pool.SendWork(func() {
+				started.Done()
+				<-release
+			}); err != nil {
+				t.Errorf("Failed to send work: %v", err)
+			}
+		}()
+	}
+	started.Wait()
+	close(release)
+	done.Wait()
+
+	if actual := pool.NumStartedWorkers(); actual != numWorkers {
+		t.Errorf("Expected %d started workers under load, but got %d", numWorkers, actual)
+	}
+
+	pool.Close()
+	for i, c := range counters {
+		if c.initialized != 1 || c.terminated != 1 {
+			t.Errorf("Worker %d initialized %d and terminated %d times", i, c.initialized, c.terminated)
+		}
+	}
+}
+
+func TestLazyStartCloseUnstarted(t *testing.T) {
+	worker := &countingExtWorker{}
+	pool, err := CreateCustomPool([]GoroutineWorker{worker}, WithLazyStart()).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	if err := pool.Close(); err != nil {
+		t.Errorf("Failed to close pool: %v", err)
+	}
+	if worker.initialized != 0 || worker.terminated != 0 {
+		t.Errorf("Unstarted worker initialized %d and terminated %d times", worker.initialized, worker.terminated)
+	}
+
+	// Reopening the pool should start lazily again
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to reopen pool: %v", err)
+		return
+	}
+	if _, err := pool.SendWork(nil); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	pool.Close()
+	if worker.initialized != 1 || worker.terminated != 1 {
+		t.Errorf("Worker initialized %d and terminated %d times", worker.initialized, worker.terminated)
+	}
+}
+
+func TestPoolName(t *testing.T) {
+	if name := CreatePoolGeneric(1).Name(); name != "" {
+		t.Errorf("Expected an unnamed pool, got %q", name)
+	}
+	if name := CreatePoolGeneric(1, WithPoolName("uploads")).Name(); name != "uploads" {
+		t.Errorf("Wrong pool name: %q != %q", "uploads", name)
+	}
+}
+
+func TestSendWorkOrDrop(t *testing.T) {
+	pool, err := CreatePoolGeneric(1).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	// Wait for the worker to become idle
+	for i := 0; i < 100 && pool.NumIdleWorkers() == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if result, ok := pool.SendWorkOrDrop(func() interface{} { return 1 }); !ok || result != 1 {
+		t.Errorf("Expected job to run on an idle worker, got %v, %v", result, ok)
+	}
+
+	release := make(chan struct{})
+	pool.SendWorkAsync(func() { <-release }, nil)
+	for i := 0; i < 100 && pool.NumIdleWorkers() > 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	ran := false
+	before := time.Now()
+	if result, ok := pool.SendWorkOrDrop(func() { ran = true }); ok || result != nil {
+		t.Errorf("Expected job to be dropped, got %v, %v", result, ok)
+	}
+	if time.Since(before) > 50*time.Millisecond {
+		t.Errorf("Expected dropping a job not to block")
+	}
+	close(release)
+	if ran {
+		t.Errorf("Expected dropped job not to run")
+	}
+}
+
+func TestNestedPools(t *testing.T) {
+	inner, err := CreatePool(2, func(in interface{}) interface{} {
+		return in.(int) * 2
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer inner.Close()
+
+	outer, err := CreateCustomPool([]GoroutineWorker{inner, inner}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer outer.Close()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if result, err := outer.SendWork(i); err != nil {
+				t.Errorf("Failed to send work: %v", err)
+			} else if result != i*2 {
+				t.Errorf("Wrong return value: %v != %v", i*2, result)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestNestedPoolReady(t *testing.T) {
+	release := make(chan struct{})
+	inner := CreatePoolGeneric(1)
+	if inner.Ready() {
+		t.Errorf("Expected a closed pool not to be ready")
+	}
+	if _, err := inner.Open(); err != nil {
+		t.Errorf("Failed to open pool: %v", err)
+		return
+	}
+	defer inner.Close()
+
+	// Wait for the worker to report idle
+	for i := 0; i < 100 && !inner.Ready(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if !inner.Ready() {
+		t.Errorf("Expected a pool with an idle worker to be ready")
+	}
+
+	inner.SendWorkAsync(func() { <-release }, nil)
+	for i := 0; i < 100 && inner.Ready(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if inner.Ready() {
+		t.Errorf("Expected a pool with no idle workers not to be ready")
+	}
+	close(release)
+
+	if result := inner.Job(10); result != ErrUnsupportedPayload {
+		t.Errorf("Expected ErrUnsupportedPayload from nested job, got %v", result)
+	}
+}
+
+func TestTerminateCalledOnce(t *testing.T) {
+	// Closing the pool twice
+	worker := &countingExtWorker{}
+	pool, err := CreateCustomPool([]GoroutineWorker{worker}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	pool.Close()
+	if err := pool.Close(); err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning from second Close, got %v", err)
+	}
+	if worker.terminated != 1 {
+		t.Errorf("Expected Terminate once after closing twice, got %v", worker.terminated)
+	}
+
+	// Closing a worker twice and joining from two goroutines at once
+	worker = &countingExtWorker{}
+	wrapper := &workerWrapper{worker: worker}
+	wrapper.Open()
+	wrapper.Start()
+	wrapper.Close()
+	wrapper.Close()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wrapper.Join()
+		}()
+	}
+	wg.Wait()
+	if worker.terminated != 1 {
+		t.Errorf("Expected Terminate once after concurrent joins, got %v", worker.terminated)
+	}
+
+	// Closing a worker without joining still terminates it once its job is done
+	worker = &countingExtWorker{}
+	wrapper = &workerWrapper{worker: worker}
+	wrapper.Open()
+	wrapper.Start()
+	<-wrapper.readyChan
+	wrapper.jobChan <- jobRequest{data: func() { time.Sleep(10 * time.Millisecond) }}
+	wrapper.Close()
+	<-wrapper.outputChan
+	<-wrapper.done
+	if terminated := atomic.LoadInt32(&worker.terminated); terminated != 1 {
+		t.Errorf("Expected Terminate once without Join, got %v", terminated)
+	}
+}
+
+func TestJoinSlowShutdown(t *testing.T) {
+	slow := 2 * time.Second
+	if testing.Short() {
+		slow = 200 * time.Millisecond
+	}
+
+	// The worker is closed in the middle of its final job, Join must wait for the job without
+	// spinning on a channel that is already closed
+	worker := &countingExtWorker{}
+	wrapper := &workerWrapper{worker: worker}
+	wrapper.Open()
+	wrapper.Start()
+	<-wrapper.readyChan
+	wrapper.jobChan <- jobRequest{data: func() { time.Sleep(slow) }}
+	wrapper.Close()
+
+	start := time.Now()
+	wrapper.Join()
+	if waited := time.Since(start); waited < slow/2 {
+		t.Errorf("Expected Join to wait for the final job, returned after %v", waited)
+	}
+	if wakeups := atomic.LoadUint64(&wrapper.joinWakeups); wakeups > 5 {
+		t.Errorf("Expected Join to block while waiting, woke %v times", wakeups)
+	}
+	if terminated := atomic.LoadInt32(&worker.terminated); terminated != 1 {
+		t.Errorf("Expected Terminate once before Join returned, got %v", terminated)
+	}
+}
+
+// Extended worker which tags its results so that a swap can be observed
+type taggedExtWorker struct {
+	countingExtWorker
+
+	tag string
+}
+
+func (w *taggedExtWorker) Job(in interface{}) interface{} {
+	return w.tag
+}
+
+func TestSetWorker(t *testing.T) {
+	oldWorker := &taggedExtWorker{tag: "old"}
+	newWorker := &taggedExtWorker{tag: "new"}
+
+	pool, err := CreateCustomPool([]GoroutineWorker{oldWorker}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if result, _ := pool.SendWork(nil); result != "old" {
+		t.Errorf("Wrong return value: %v != %v", "old", result)
+	}
+
+	// Swap while jobs are flowing
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if _, err := pool.SendWork(nil); err != nil {
+				t.Errorf("Failed to send work: %v", err)
+			}
+		}
+	}()
+
+	if err := pool.SetWorker(0, newWorker); err != nil {
+		t.Errorf("Failed to set worker: %v", err)
+	}
+	close(stop)
+	wg.Wait()
+
+	if result, _ := pool.SendWork(nil); result != "new" {
+		t.Errorf("Wrong return value: %v != %v", "new", result)
+	}
+	if oldWorker.initialized != 1 || oldWorker.terminated != 1 {
+		t.Errorf("Old worker initialized %d and terminated %d times", oldWorker.initialized, oldWorker.terminated)
+	}
+	if newWorker.initialized != 1 || newWorker.terminated != 0 {
+		t.Errorf("New worker initialized %d and terminated %d times", newWorker.initialized, newWorker.terminated)
+	}
+
+	if err := pool.SetWorker(0, nil); err != ErrWorkerNil {
+		t.Errorf("Expected ErrWorkerNil, got %v", err)
+	}
+	if err := pool.SetWorker(1, &taggedExtWorker{}); err != ErrWorkerIndex {
+		t.Errorf("Expected ErrWorkerIndex, got %v", err)
+	}
+
+	pool.Close()
+	if err := pool.SetWorker(0, &taggedExtWorker{}); err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning, got %v", err)
+	}
+}
+
+func TestBorrowWorker(t *testing.T) {
+	workers := []*taggedExtWorker{{tag: "first"}, {tag: "second"}}
+	pool, err := CreateCustomPool([]GoroutineWorker{workers[0], workers[1]}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	borrowed, release, err := pool.BorrowWorker()
+	if err != nil {
+		t.Errorf("Failed to borrow worker: %v", err)
+		return
+	}
+	if actual := pool.NumWorkers(); actual != 1 {
+		t.Errorf("Expected 1 worker while one is borrowed, but got %d", actual)
+	}
+
+	// Jobs only go to the worker left in rotation
+	other := "first"
+	if borrowed.Job(nil) == "first" {
+		other = "second"
+	}
+	for i := 0; i < 20; i++ {
+		if result, _ := pool.SendWork(nil); result != other {
+			t.Errorf("Job dispatched to a borrowed worker: %v != %v", other, result)
+		}
+	}
+
+	release()
+	release()
+	if actual := pool.NumWorkers(); actual != 2 {
+		t.Errorf("Expected 2 workers after release, but got %d", actual)
+	}
+
+	// Both workers serve jobs again once the borrowed worker is back
+	if _, release2, err := pool.BorrowWorker(); err != nil {
+		t.Errorf("Failed to borrow worker: %v", err)
+	} else {
+		defer release2()
+	}
+	if _, release3, err := pool.BorrowWorker(); err != nil {
+		t.Errorf("Failed to borrow worker: %v", err)
+	} else {
+		release3()
+	}
+
+	for i, worker := range workers {
+		if worker.initialized != 1 {
+			t.Errorf("Worker %d initialized %d times", i, worker.initialized)
+		}
+	}
+}
+
+// Extended worker which records the order of its calls and can fail to flush
+type flushingWorker struct {
+	calls    []string
+	flushErr error
+}
+
+func (f *flushingWorker) Job(in interface{}) interface{} {
+	f.calls = append(f.calls, "Job")
+	return in
+}
+
+func (f *flushingWorker) Ready() bool {
+	return true
+}
+
+func (f *flushingWorker) Initialize() {
+	f.calls = append(f.calls, "Initialize")
+}
+
+func (f *flushingWorker) Flush() error {
+	f.calls = append(f.calls, "Flush")
+	return f.flushErr
+}
+
+func (f *flushingWorker) Terminate() {
+	f.calls = append(f.calls, "Terminate")
+}
+
+func TestFlushOrder(t *testing.T) {
+	errFlush := errors.New("flush failed")
+	failing := &flushingWorker{flushErr: errFlush}
+	healthy := &flushingWorker{}
+
+	pool, err := CreateCustomPool([]GoroutineWorker{failing, healthy}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	for i := 0; i < 10; i++ {
+		pool.SendWork(nil)
+	}
+
+	if err := pool.CloseErr(); !errors.Is(err, errFlush) {
+		t.Errorf("Expected the flush error from CloseErr, got %v", err)
+	}
+	if err := pool.CloseErr(); err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning from second CloseErr, got %v", err)
+	}
+
+	for i, worker := range []*flushingWorker{failing, healthy} {
+		calls := strings.Join(worker.calls, ",")
+		calls = strings.Replace(calls, "Job,", "", -1)
+		if calls != "Initialize,Flush,Terminate" {
+			t.Errorf("Wrong call order for worker %d: %v", i, worker.calls)
+		}
+	}
+
+	// A worker which never started is never flushed
+	unstarted := &flushingWorker{}
+	pool, _ = CreateCustomPool([]GoroutineWorker{unstarted}, WithLazyStart()).Open()
+	if err := pool.CloseErr(); err != nil {
+		t.Errorf("Failed to close pool: %v", err)
+	}
+	if len(unstarted.calls) != 0 {
+		t.Errorf("Expected no calls on an unstarted worker, got %v", unstarted.calls)
+	}
+}
+
+func benchmarkIdlePools(b *testing.B, opts ...Option) {
+	numPools := 1000
+	pools := make([]*WorkPool, numPools)
+
+	b.ReportAllocs()
+	var used uint64
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		for j := range pools {
+			pools[j], _ = CreatePoolGeneric(10, opts...).Open()
+		}
+
+		runtime.ReadMemStats(&after)
+		used += (after.HeapInuse + after.StackInuse) - (before.HeapInuse + before.StackInuse)
+
+		for _, pool := range pools {
+			pool.Close()
+		}
+	}
+	b.ReportMetric(float64(used)/float64(b.N*numPools), "bytes/pool")
+}
+
+func BenchmarkIdlePoolsEager(b *testing.B) {
+	benchmarkIdlePools(b)
+}
+
+func BenchmarkIdlePoolsLazy(b *testing.B) {
+	benchmarkIdlePools(b, WithLazyStart())
+}
+
+func TestDispatchStress(t *testing.T) {
+	pool := CreatePool(4, func(in interface{}) interface{} {
+		if d, ok := in.(time.Duration); ok {
+			time.Sleep(d)
+		}
+		return in
+	})
+
+	for round := 0; round < 5; round++ {
+		if _, err := pool.Open(); err != nil {
+			t.Errorf("Failed to open pool: %v", err)
+			return
+		}
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					switch (i + j) % 3 {
+					case 0:
+						if result, err := pool.SendWork(j); err == nil && result != j {
+							t.Errorf("Expected %v, got %v", j, result)
+						}
+					case 1:
+						// Times out and leaves the worker to be drained in the background
+						pool.SendWorkTimed(time.Millisecond, 5*time.Millisecond)
+					case 2:
+						if result, err := pool.SendWorkTimed(1000*time.Millisecond, j); err == nil && result != j {
+							t.Errorf("Expected %v, got %v", j, result)
+						}
+					}
+				}
+			}(i)
+		}
+
+		// Close while jobs are still being submitted
+		time.Sleep(10 * time.Millisecond)
+		pool.Close()
+		wg.Wait()
+	}
+}
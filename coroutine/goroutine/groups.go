@@ -0,0 +1,170 @@
+package goroutine
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrUnknownGroup         = errors.New("no isolation group with that name")
+	ErrGroupsOversubscribed = errors.New("isolation group caps add up to more than the number of workers")
+)
+
+/*
+WithIsolationGroups - Partitions the workers of the pool among named classes of jobs. Jobs sent
+with SendWorkGroup only run while fewer than the cap of their group are in flight, whatever the
+number of idle workers. Jobs sent without a group may use the workers left over once every cap is
+taken into account, so each group can always reach its cap. If the caps take up every worker
+ungrouped jobs are not limited. Open fails with ErrGroupsOversubscribed if the caps add up to
+more than the number of workers, unless WithOversubscribedGroups is also given.
+*/
+func WithIsolationGroups(groups map[string]int) Option {
+	return func(pool *WorkPool) {
+		if pool.groups == nil {
+			pool.groups = &isolationGroups{}
+		}
+		pool.groups.caps = make(map[string]int, len(groups))
+		for name, cap := range groups {
+			pool.groups.caps[name] = cap
+		}
+	}
+}
+
+/*
+WithOversubscribedGroups - Allows the caps given to WithIsolationGroups to add up to more than the
+number of workers, groups then compete for the workers beyond their share.
+*/
+func WithOversubscribedGroups() Option {
+	return func(pool *WorkPool) {
+		if pool.groups == nil {
+			pool.groups = &isolationGroups{}
+		}
+		pool.groups.oversubscribe = true
+	}
+}
+
+/*
+isolationGroups - The in flight slots of each group, an empty name stands for ungrouped jobs. All
+methods are safe to call on nil, which never limits a job.
+*/
+type isolationGroups struct {
+	caps          map[string]int
+	oversubscribe bool
+	slots         map[string]chan struct{}
+}
+
+/*
+open - Validates the caps against the number of workers and creates the slots of every group.
+*/
+func (groups *isolationGroups) open(numWorkers int) error {
+	if groups == nil {
+		return nil
+	}
+
+	total := 0
+	slots := make(map[string]chan struct{}, len(groups.caps)+1)
+	for name, cap := range groups.caps {
+		if cap < 0 {
+			cap = 0
+		}
+		total += cap
+		slots[name] = make(chan struct{}, cap)
+	}
+	if total > numWorkers && !groups.oversubscribe {
+		return ErrGroupsOversubscribed
+	}
+	if leftover := numWorkers - total; leftover > 0 {
+		slots[""] = make(chan struct{}, leftover)
+	}
+	groups.slots = slots
+	return nil
+}
+
+/*
+has - Whether a group of that name was defined.
+*/
+func (groups *isolationGroups) has(group string) bool {
+	if groups == nil {
+		return false
+	}
+	_, ok := groups.caps[group]
+	return ok
+}
+
+/*
+acquire - Takes a slot of the group, waiting until timeout or cancel fires. Nil channels never
+fire.
+*/
+func (groups *isolationGroups) acquire(group string, timeout <-chan time.Time, cancel <-chan struct{}) bool {
+	if groups == nil {
+		return true
+	}
+	slots, ok := groups.slots[group]
+	if !ok {
+		return true
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timeout:
+		return false
+	case <-cancel:
+		return false
+	}
+}
+
+/*
+tryAcquire - Takes a slot of the group only if one is free right now.
+*/
+func (groups *isolationGroups) tryAcquire(group string) bool {
+	if groups == nil {
+		return true
+	}
+	slots, ok := groups.slots[group]
+	if !ok {
+		return true
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+release - Frees a slot taken by acquire or tryAcquire.
+*/
+func (groups *isolationGroups) release(group string) {
+	if groups == nil {
+		return
+	}
+	if slots, ok := groups.slots[group]; ok {
+		<-slots
+	}
+}
+
+/*
+SendWorkGroup - Sends a job to a worker on behalf of an isolation group and returns the result,
+this is a synchronous call which waits while the group has as many jobs in flight as its cap.
+Returns ErrUnknownGroup if the pool has no group of that name.
+*/
+func (pool *WorkPool) SendWorkGroup(group string, jobData interface{}) (interface{}, error) {
+	if !pool.groups.has(group) {
+		return nil, ErrUnknownGroup
+	}
+
+	jobData = pool.clonePayload(jobData)
+	ticket, err := pool.admitUntil(context.Background(), time.Time{}, jobData)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.release(jobData, ticket)
+
+	result, err := pool.sendWork(group, false, jobData)
+	pool.breaker.done(ticket.probe, result, err)
+	return result, err
+}
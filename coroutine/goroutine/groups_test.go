@@ -0,0 +1,97 @@
+package goroutine
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsolationGroups(t *testing.T) {
+	release := make(chan struct{})
+	var bulkRunning, maxBulkRunning int32
+
+	pool, err := CreatePool(4, func(in interface{}) interface{} {
+		if in == "bulk" {
+			running := atomic.AddInt32(&bulkRunning, 1)
+			defer atomic.AddInt32(&bulkRunning, -1)
+			for {
+				max := atomic.LoadInt32(&maxBulkRunning)
+				if running <= max || atomic.CompareAndSwapInt32(&maxBulkRunning, max, running) {
+					break
+				}
+			}
+			<-release
+		}
+		return in
+	}, WithIsolationGroups(map[string]int{"bulk": 2, "interactive": 1})).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	// Saturate the bulk group with more jobs than its cap
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := pool.SendWorkGroup("bulk", "bulk"); err != nil {
+				t.Errorf("Bulk job failed: %v", err)
+			}
+		}()
+	}
+	for atomic.LoadInt32(&bulkRunning) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan interface{})
+	go func() {
+		result, _ := pool.SendWorkGroup("interactive", "interactive")
+		done <- result
+	}()
+	select {
+	case result := <-done:
+		if result != "interactive" {
+			t.Errorf("Unexpected result: %v", result)
+		}
+	case <-time.After(time.Second):
+		t.Error("Interactive job did not start while bulk jobs held their cap")
+	}
+
+	// Ungrouped jobs use the one worker left over
+	if result, err := pool.SendWorkTimed(1000*time.Millisecond, "ungrouped"); err != nil || result != "ungrouped" {
+		t.Errorf("Ungrouped job failed: %v, %v", result, err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if maxBulkRunning != 2 {
+		t.Errorf("Expected at most 2 bulk jobs at once, got %v", maxBulkRunning)
+	}
+	if _, err := pool.SendWorkGroup("missing", nil); err != ErrUnknownGroup {
+		t.Errorf("Expected ErrUnknownGroup, got %v", err)
+	}
+}
+
+func TestIsolationGroupsOversubscribed(t *testing.T) {
+	job := func(in interface{}) interface{} { return in }
+
+	groups := map[string]int{"a": 2, "b": 2}
+	if _, err := CreatePool(3, job, WithIsolationGroups(groups)).Open(); err != ErrGroupsOversubscribed {
+		t.Errorf("Expected ErrGroupsOversubscribed, got %v", err)
+	}
+
+	pool, err := CreatePool(3, job, WithIsolationGroups(groups), WithOversubscribedGroups()).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if result, err := pool.SendWork("ungrouped"); err != nil || result != "ungrouped" {
+		t.Errorf("Ungrouped job failed: %v, %v", result, err)
+	}
+}
@@ -0,0 +1,86 @@
+package goroutine
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrOpenTimeout = errors.New("worker did not become ready")
+)
+
+/*
+WithOpenTimeout - Makes Open wait for every worker it starts to report ready for the first time.
+If a worker is still not ready once timeout has passed Open terminates the workers it started and
+fails with an error wrapping ErrOpenTimeout which names the first such worker. Without this
+option Open does not wait, and a worker that never becomes ready simply never takes a job.
+*/
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(pool *WorkPool) {
+		pool.openTimeout = timeout
+	}
+}
+
+/*
+WithUnhealthyAfter - Marks a worker as unhealthy while it has been waiting to report ready for
+longer than threshold between jobs, so that a worker which drops out of rotation shows up in
+UnhealthyWorkers and the pool's log instead of going unnoticed. The mark is cleared once the
+worker is ready again.
+*/
+func WithUnhealthyAfter(threshold time.Duration) Option {
+	return func(pool *WorkPool) {
+		pool.unhealthyAfter = threshold
+	}
+}
+
+/*
+UnhealthyWorkers - The indexes of the workers currently marked as unhealthy, see
+WithUnhealthyAfter.
+*/
+func (pool *WorkPool) UnhealthyWorkers() []int {
+	var unhealthy []int
+	for i, workerWrapper := range pool.lockFreeWorkers() {
+		if atomic.LoadUint32(&workerWrapper.unhealthy) == 1 {
+			unhealthy = append(unhealthy, i)
+		}
+	}
+	return unhealthy
+}
+
+/*
+waitWorkersReady - Waits for every started worker to report ready for the first time, returning
+an error naming the first that did not within the open timeout.
+*/
+func (pool *WorkPool) waitWorkersReady() error {
+	timer := pool.clock.NewTimer(pool.openTimeout)
+	defer timer.Stop()
+
+	for i, workerWrapper := range pool.workers {
+		if atomic.LoadUint32(&workerWrapper.started) == 0 {
+			continue
+		}
+		select {
+		case <-workerWrapper.readied:
+		case <-timer.C():
+			return fmt.Errorf("%w: worker %d not ready after %v", ErrOpenTimeout, i, pool.openTimeout)
+		}
+	}
+	return nil
+}
+
+/*
+markUnhealthy - Records whether the worker has been waiting to report ready for too long.
+*/
+func (wrapper *workerWrapper) markUnhealthy(unhealthy bool, waited time.Duration) {
+	if unhealthy {
+		if atomic.CompareAndSwapUint32(&wrapper.unhealthy, 0, 1) {
+			wrapper.logger.printf("worker %d unhealthy, not ready for %v", wrapper.index, waited)
+		}
+		return
+	}
+	if atomic.CompareAndSwapUint32(&wrapper.unhealthy, 1, 0) {
+		wrapper.logger.printf("worker %d ready again after %v", wrapper.index, waited)
+	}
+}
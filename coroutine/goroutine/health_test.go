@@ -0,0 +1,97 @@
+package goroutine
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// toggleWorker is ready only while its ready flag is set
+type toggleWorker struct {
+	ready       int32
+	terminated  int32
+	initialized int32
+}
+
+func (w *toggleWorker) Job(in interface{}) interface{} {
+	return in
+}
+
+func (w *toggleWorker) Ready() bool {
+	return atomic.LoadInt32(&w.ready) == 1
+}
+
+func (w *toggleWorker) Initialize() {
+	atomic.AddInt32(&w.initialized, 1)
+}
+
+func (w *toggleWorker) Terminate() {
+	atomic.AddInt32(&w.terminated, 1)
+}
+
+func TestOpenTimeout(t *testing.T) {
+	ready := &toggleWorker{ready: 1}
+	stuck := &toggleWorker{}
+
+	pool := CreateCustomPool([]GoroutineWorker{ready, stuck}, WithOpenTimeout(20*time.Millisecond))
+	start := time.Now()
+	_, err := pool.Open()
+	if !errors.Is(err, ErrOpenTimeout) || !strings.Contains(err.Error(), "worker 1") {
+		t.Errorf("Expected ErrOpenTimeout naming worker 1, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Open to give up after the timeout, took %v", elapsed)
+	}
+	if atomic.LoadInt32(&ready.terminated) != 1 || atomic.LoadInt32(&stuck.terminated) != 1 {
+		t.Errorf("Expected the started workers to be terminated, got %v and %v", ready.terminated, stuck.terminated)
+	}
+	if _, err := pool.SendWork(nil); err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning, got %v", err)
+	}
+
+	// Once the worker is ready the pool can be opened
+	atomic.StoreInt32(&stuck.ready, 1)
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to open pool: %v", err)
+		return
+	}
+	pool.Close()
+}
+
+func TestUnhealthyAfter(t *testing.T) {
+	worker := &toggleWorker{ready: 1}
+	pool, err := CreateCustomPool([]GoroutineWorker{worker}, WithUnhealthyAfter(10*time.Millisecond)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if unhealthy := pool.UnhealthyWorkers(); len(unhealthy) != 0 {
+		t.Errorf("Expected no unhealthy workers, got %v", unhealthy)
+	}
+
+	// The worker stops reporting ready after its next job
+	for pool.Stats().IdleWorkers == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	atomic.StoreInt32(&worker.ready, 0)
+	pool.SendWork(nil)
+
+	for deadline := time.Now().Add(time.Second); len(pool.UnhealthyWorkers()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if unhealthy := pool.UnhealthyWorkers(); len(unhealthy) != 1 || unhealthy[0] != 0 {
+		t.Errorf("Expected worker 0 to be unhealthy, got %v", unhealthy)
+	}
+
+	atomic.StoreInt32(&worker.ready, 1)
+	if _, err := pool.SendWorkTimed(1000*time.Millisecond, nil); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	if unhealthy := pool.UnhealthyWorkers(); len(unhealthy) != 0 {
+		t.Errorf("Expected the worker to be healthy again, got %v", unhealthy)
+	}
+}
@@ -0,0 +1,63 @@
+package goroutine
+
+import (
+	"net/http"
+)
+
+/*
+HTTPJob - The job expected by workers created with NewHTTPWorker. Done is closed once the
+handler has finished serving the request, it may be nil if the caller does not need it.
+*/
+type HTTPJob struct {
+	W    http.ResponseWriter
+	R    *http.Request
+	Done chan struct{}
+}
+
+/*
+Implementation of a worker which serves each job with an http.Handler.
+*/
+type httpWorker struct {
+	handler http.Handler
+}
+
+/*
+NewHTTPWorker - Creates a worker which serves *HTTPJob jobs with handler, this allows HTTP
+servers to dispatch requests to a bounded pool created with CreateCustomPool.
+*/
+func NewHTTPWorker(handler http.Handler) GoroutineWorker {
+	return &httpWorker{handler: handler}
+}
+
+func (worker *httpWorker) Job(data interface{}) interface{} {
+	job, ok := data.(*HTTPJob)
+	if !ok || job == nil {
+		return ErrUnsupportedPayload
+	}
+	if job.Done != nil {
+		defer close(job.Done)
+	}
+	worker.handler.ServeHTTP(job.W, job.R)
+	return nil
+}
+
+func (worker *httpWorker) Ready() bool {
+	return true
+}
+
+/*
+SendHTTPWork - Serve the request on a pool of HTTP workers, this is a synchronous call which
+returns once the handler has written its response.
+*/
+func SendHTTPWork(pool *WorkPool, w http.ResponseWriter, r *http.Request) error {
+	job := &HTTPJob{W: w, R: r, Done: make(chan struct{})}
+
+	result, err := pool.SendWork(job)
+	if err != nil {
+		return err
+	}
+	if err, ok := result.(error); ok {
+		return err
+	}
+	return nil
+}
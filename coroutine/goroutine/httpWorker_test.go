@@ -0,0 +1,65 @@
+package goroutine
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPWorker(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello "+r.URL.Query().Get("name"))
+	})
+
+	pool, err := CreateCustomPool([]GoroutineWorker{
+		NewHTTPWorker(handler),
+		NewHTTPWorker(handler),
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := SendHTTPWork(pool, w, r); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?name=pool")
+	if err != nil {
+		t.Errorf("Failed to send request: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "hello pool" {
+		t.Errorf("Wrong response body: %q != %q", "hello pool", body)
+	}
+}
+
+func TestHTTPWorkerDone(t *testing.T) {
+	worker := NewHTTPWorker(http.NotFoundHandler())
+
+	job := &HTTPJob{
+		W:    httptest.NewRecorder(),
+		R:    httptest.NewRequest("GET", "/", nil),
+		Done: make(chan struct{}),
+	}
+	if result := worker.Job(job); result != nil {
+		t.Errorf("Unexpected result from job: %v", result)
+	}
+	select {
+	case <-job.Done:
+	default:
+		t.Errorf("Expected Done to be closed after the job")
+	}
+
+	if result := worker.Job("not a request"); result != ErrUnsupportedPayload {
+		t.Errorf("Expected ErrUnsupportedPayload, got %v", result)
+	}
+}
@@ -0,0 +1,226 @@
+package goroutine
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+JobInfo - A job as seen by PendingJobs and RunningJobs. Seq is the sequence number of the job, as
+logged and traced, Enqueued when it was submitted and Age the time since, as of the snapshot.
+Priority, Labels and Description are only set for jobs sent with SendWorkAsync as a LabeledJob,
+Description being what its Describe function returned. Worker is the index of the worker running
+the job, -1 for a job run on the caller or still queued, and Elapsed the time it has been running.
+*/
+type JobInfo struct {
+	Seq         uint64
+	Enqueued    time.Time
+	Age         time.Duration
+	Priority    int
+	Labels      map[string]string
+	Description string
+	Worker      int
+	Elapsed     time.Duration
+}
+
+/*
+WithJobInspection - Keeps track of the jobs which are queued or running, for PendingJobs and
+RunningJobs. Tracking takes a short lock as each job is submitted, starts and ends, so it is off
+by default.
+*/
+func WithJobInspection() Option {
+	return func(pool *WorkPool) {
+		pool.inspector = &jobInspector{jobs: make(map[uint64]*trackedJob)}
+	}
+}
+
+/*
+PendingJobs - Up to limit of the jobs queued for a worker and not yet running, oldest first, or all
+of them if limit is zero or less. The jobs are copied under a short lock without holding up
+dispatch, so some may well have started by the time they are looked at. Returns nil unless the
+pool was created WithJobInspection.
+*/
+func (pool *WorkPool) PendingJobs(limit int) []JobInfo {
+	jobs := pool.inspector.snapshot(false, pool.clock.Now())
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].info.Seq < jobs[j].info.Seq
+	})
+	if limit > 0 && len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return describe(jobs)
+}
+
+/*
+RunningJobs - The jobs running, ordered by the index of their worker, see PendingJobs.
+*/
+func (pool *WorkPool) RunningJobs() []JobInfo {
+	jobs := pool.inspector.snapshot(true, pool.clock.Now())
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].info.Worker != jobs[j].info.Worker {
+			return jobs[i].info.Worker < jobs[j].info.Worker
+		}
+		return jobs[i].info.Seq < jobs[j].info.Seq
+	})
+	return describe(jobs)
+}
+
+/*
+jobInspector - The jobs which are queued or running, by sequence number.
+*/
+type jobInspector struct {
+	mutex sync.Mutex
+	jobs  map[uint64]*trackedJob
+}
+
+const (
+	jobQueued = iota
+	jobRunning
+	jobGone
+)
+
+/*
+trackedJob - A job tracked WithJobInspection, the fields other than state, worker and started are
+set when it is submitted and never change.
+*/
+type trackedJob struct {
+	inspector *jobInspector
+	seq       uint64
+	enqueued  time.Time
+	data      interface{}
+	labels    LabeledJob
+
+	state   int
+	worker  int
+	started time.Time
+}
+
+/*
+describedJob - The payload of a LabeledJob sent with SendWorkAsync to a pool WithJobInspection,
+newJob keeps the labels with the job it tracks.
+*/
+type describedJob struct {
+	data   interface{}
+	labels LabeledJob
+}
+
+func (inspector *jobInspector) add(seq uint64, enqueued time.Time, data interface{}, labels LabeledJob) *trackedJob {
+	if inspector == nil {
+		return nil
+	}
+	job := &trackedJob{inspector: inspector, seq: seq, enqueued: enqueued, data: data, labels: labels, worker: -1}
+
+	inspector.mutex.Lock()
+	defer inspector.mutex.Unlock()
+
+	inspector.jobs[seq] = job
+	return job
+}
+
+// start moves the job from the queued jobs to those running
+func (job *trackedJob) start(worker int, started time.Time) {
+	if job == nil {
+		return
+	}
+	job.inspector.mutex.Lock()
+	defer job.inspector.mutex.Unlock()
+
+	// A job given up on while queued may still reach a worker
+	job.inspector.jobs[job.seq] = job
+	job.state, job.worker, job.started = jobRunning, worker, started
+}
+
+// finish forgets the job once it has run
+func (job *trackedJob) finish() {
+	if job == nil {
+		return
+	}
+	job.inspector.mutex.Lock()
+	defer job.inspector.mutex.Unlock()
+
+	delete(job.inspector.jobs, job.seq)
+	job.state = jobGone
+}
+
+// abandon forgets the job if it is still queued, once the caller is no longer waiting for it
+func (job *trackedJob) abandon() {
+	if job == nil {
+		return
+	}
+	job.inspector.mutex.Lock()
+	defer job.inspector.mutex.Unlock()
+
+	if job.state == jobQueued {
+		delete(job.inspector.jobs, job.seq)
+		job.state = jobGone
+	}
+}
+
+/*
+jobSnapshot - A job copied by snapshot, along with what describe needs to describe it.
+*/
+type jobSnapshot struct {
+	info     JobInfo
+	data     interface{}
+	describe func(interface{}) string
+}
+
+/*
+snapshot - Copies the jobs which are running, or those queued, under the lock.
+*/
+func (inspector *jobInspector) snapshot(running bool, now time.Time) []jobSnapshot {
+	if inspector == nil {
+		return nil
+	}
+	inspector.mutex.Lock()
+	defer inspector.mutex.Unlock()
+
+	jobs := make([]jobSnapshot, 0, len(inspector.jobs))
+	for _, job := range inspector.jobs {
+		if (job.state == jobRunning) != running {
+			continue
+		}
+		snapshot := jobSnapshot{
+			info: JobInfo{
+				Seq:      job.seq,
+				Enqueued: job.enqueued,
+				Age:      now.Sub(job.enqueued),
+				Priority: job.labels.Priority,
+				Labels:   job.labels.Labels,
+				Worker:   job.worker,
+			},
+			data:     job.data,
+			describe: job.labels.Describe,
+		}
+		if running {
+			snapshot.info.Elapsed = now.Sub(job.started)
+		}
+		jobs = append(jobs, snapshot)
+	}
+	return jobs
+}
+
+/*
+describe - The JobInfo of jobs copied by snapshot, calling their Describe functions and copying
+their labels outside of the lock.
+*/
+func describe(jobs []jobSnapshot) []JobInfo {
+	if jobs == nil {
+		return nil
+	}
+	infos := make([]JobInfo, len(jobs))
+	for i, job := range jobs {
+		infos[i] = job.info
+		if job.info.Labels != nil {
+			infos[i].Labels = make(map[string]string, len(job.info.Labels))
+			for name, value := range job.info.Labels {
+				infos[i].Labels[name] = value
+			}
+		}
+		if job.describe != nil {
+			infos[i].Description = job.describe(job.data)
+		}
+	}
+	return infos
+}
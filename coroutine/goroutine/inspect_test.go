@@ -0,0 +1,105 @@
+package goroutine
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPendingJobs(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		if in == "block" {
+			close(started)
+			<-release
+		}
+		return in
+	}, WithJobInspection()).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	done := func(interface{}, error) { wg.Done() }
+	pool.SendWorkAsync("block", done)
+	<-started
+
+	waitPending := func(n int) {
+		for i := 0; i < 1000 && len(pool.PendingJobs(0)) != n; i++ {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	describe := func(in interface{}) string {
+		return fmt.Sprintf("job %v", in)
+	}
+	for i, name := range []string{"a", "b", "c"} {
+		pool.SendWorkAsync(LabeledJob{
+			Data:     name,
+			Labels:   map[string]string{"name": name},
+			Priority: i,
+			Describe: describe,
+		}, done)
+		waitPending(i + 1)
+	}
+
+	// A job given up on while queued leaves the queue
+	if _, err := pool.SendWorkTimed(10*time.Millisecond, "late"); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut, got %v", err)
+	}
+
+	pending := pool.PendingJobs(0)
+	if len(pending) != 3 {
+		t.Errorf("Expected 3 pending jobs, got %+v", pending)
+		return
+	}
+	for i, job := range pending {
+		name := []string{"a", "b", "c"}[i]
+		if job.Description != "job "+name || job.Labels["name"] != name || job.Priority != i {
+			t.Errorf("Unexpected pending job %d: %+v", i, job)
+		}
+		if job.Worker != -1 || job.Elapsed != 0 || job.Age <= 0 {
+			t.Errorf("Unexpected state of pending job %d: %+v", i, job)
+		}
+		if i > 0 && (job.Seq <= pending[i-1].Seq || job.Enqueued.Before(pending[i-1].Enqueued)) {
+			t.Errorf("Expected pending jobs oldest first, got %+v", pending)
+		}
+	}
+	if limited := pool.PendingJobs(2); len(limited) != 2 || limited[1].Seq != pending[1].Seq {
+		t.Errorf("Expected the two oldest jobs, got %+v", limited)
+	}
+
+	running := pool.RunningJobs()
+	if len(running) != 1 || running[0].Worker != 0 || running[0].Elapsed <= 0 || running[0].Seq >= pending[0].Seq {
+		t.Errorf("Expected the blocking job running on worker 0, got %+v", running)
+	}
+
+	close(release)
+	wg.Wait()
+	if pending := pool.PendingJobs(0); len(pending) != 0 {
+		t.Errorf("Expected no pending jobs, got %+v", pending)
+	}
+	if running := pool.RunningJobs(); len(running) != 0 {
+		t.Errorf("Expected no running jobs, got %+v", running)
+	}
+}
+
+func TestPendingJobsDisabled(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if pending, running := pool.PendingJobs(0), pool.RunningJobs(); pending != nil || running != nil {
+		t.Errorf("Expected nil without WithJobInspection, got %v, %v", pending, running)
+	}
+}
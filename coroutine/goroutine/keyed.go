@@ -0,0 +1,123 @@
+package goroutine
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync"
+)
+
+/*
+KeyedPool - A pool for jobs which must not run concurrently with other jobs of the same key, such
+as the events of one user. Each key is hashed to one of a number of inner pools, the buckets, and
+within a bucket a job waits for the previous job of its key to complete before taking a worker, so
+that jobs of different keys still share the bucket's workers.
+*/
+type KeyedPool struct {
+	buckets []*WorkPool
+
+	mutex sync.Mutex
+	keys  map[string]*keyLock
+}
+
+// keyLock serialises the jobs of one key, refs counts the jobs holding or waiting for it
+type keyLock struct {
+	mutex sync.Mutex
+	refs  int
+}
+
+/*
+NewKeyedPool - Creates a keyed pool of numBuckets inner pools each with workersPerBucket workers
+running fn, both are at least 1. The pool must be opened before sending work, as with CreatePool.
+*/
+func NewKeyedPool(numBuckets int, workersPerBucket int, fn func(interface{}) interface{}) *KeyedPool {
+	if numBuckets < 1 {
+		numBuckets = 1
+	}
+	if workersPerBucket < 1 {
+		workersPerBucket = 1
+	}
+
+	pool := &KeyedPool{
+		buckets: make([]*WorkPool, numBuckets),
+		keys:    make(map[string]*keyLock),
+	}
+	for i := range pool.buckets {
+		pool.buckets[i] = CreatePool(workersPerBucket, fn)
+	}
+	return pool
+}
+
+/*
+Open - Opens every bucket, if one fails those already opened are closed again.
+*/
+func (pool *KeyedPool) Open() (*KeyedPool, error) {
+	for i, bucket := range pool.buckets {
+		if _, err := bucket.Open(); err != nil {
+			for _, opened := range pool.buckets[:i] {
+				opened.Close()
+			}
+			return nil, err
+		}
+	}
+	return pool, nil
+}
+
+/*
+Close - Closes every bucket, returning the errors of any which failed joined together.
+*/
+func (pool *KeyedPool) Close() error {
+	var errs []error
+	for _, bucket := range pool.buckets {
+		if err := bucket.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+/*
+SendWork - Sends work to the bucket of key and returns the result once it has run, waiting first
+for any job of the same key sent earlier to complete. Errors are those of WorkPool.SendWork.
+*/
+func (pool *KeyedPool) SendWork(key string, work interface{}) (interface{}, error) {
+	lock := pool.lock(key)
+	defer pool.unlock(key, lock)
+
+	return pool.bucket(key).SendWork(work)
+}
+
+/*
+bucket - The inner pool which runs the jobs of key.
+*/
+func (pool *KeyedPool) bucket(key string) *WorkPool {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return pool.buckets[hash.Sum32()%uint32(len(pool.buckets))]
+}
+
+// lock waits until no other job of the key is running
+func (pool *KeyedPool) lock(key string) *keyLock {
+	pool.mutex.Lock()
+	lock, ok := pool.keys[key]
+	if !ok {
+		lock = &keyLock{}
+		pool.keys[key] = lock
+	}
+	lock.refs++
+	pool.mutex.Unlock()
+
+	lock.mutex.Lock()
+	return lock
+}
+
+// unlock lets the next job of the key run, the lock is dropped once no job holds it
+func (pool *KeyedPool) unlock(key string, lock *keyLock) {
+	lock.mutex.Unlock()
+
+	pool.mutex.Lock()
+	lock.refs--
+	if lock.refs == 0 {
+		delete(pool.keys, key)
+	}
+	pool.mutex.Unlock()
+}
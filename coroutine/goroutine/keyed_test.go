@@ -0,0 +1,68 @@
+package goroutine
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeyedPool(t *testing.T) {
+	numKeys := 8
+	running := make([]int32, numKeys)
+	var overlapped, maxRunning, total int32
+
+	pool, err := NewKeyedPool(2, 4, func(in interface{}) interface{} {
+		key := in.(int)
+		if atomic.AddInt32(&running[key], 1) > 1 {
+			atomic.AddInt32(&overlapped, 1)
+		}
+		now := atomic.AddInt32(&total, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if now <= max || atomic.CompareAndSwapInt32(&maxRunning, max, now) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&total, -1)
+		atomic.AddInt32(&running[key], -1)
+		return key
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				key := (i + j) % numKeys
+				if result, err := pool.SendWork(fmt.Sprint(key), key); err != nil || result != key {
+					t.Errorf("Expected %v, got %v, %v", key, result, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if overlapped != 0 {
+		t.Errorf("Expected no jobs of the same key to run concurrently, %v did", overlapped)
+	}
+	if maxRunning < 2 {
+		t.Errorf("Expected jobs of different keys to run concurrently, at most %v did", maxRunning)
+	}
+	if len(pool.keys) != 0 {
+		t.Errorf("Expected the key locks to be dropped, %v remain", len(pool.keys))
+	}
+
+	pool.Close()
+	if _, err := pool.SendWork("0", 0); err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning, got %v", err)
+	}
+}
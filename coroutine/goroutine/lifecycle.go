@@ -0,0 +1,190 @@
+package goroutine
+
+import (
+	"time"
+)
+
+/*
+LifecycleHooks - Functions called as a worker moves through its lifecycle, for tracing the order
+in which a worker is initialized, made ready, given jobs and terminated. Every hook receives the
+index of the worker in its pool, and any hook may be left nil.
+*/
+type LifecycleHooks struct {
+	// Called once the worker has been initialized, before it reports ready
+	OnInitialize func(worker int)
+
+	// Called when the worker reports ready, waited is how long it was polled before it was
+	OnReady func(worker int, waited time.Duration)
+
+	// Called around each job, err is ErrJobPanicked if the job panicked or the error returned
+	// by a closure submitted with Do
+	OnJobStart func(worker int)
+	OnJobEnd   func(worker int, took time.Duration, err error)
+
+	// Called once the worker has been terminated
+	OnTerminate func(worker int)
+
+	// Called when the job of the worker is interrupted
+	OnInterrupt func(worker int)
+}
+
+func (h *LifecycleHooks) initialize(worker int) {
+	if h != nil && h.OnInitialize != nil {
+		h.OnInitialize(worker)
+	}
+}
+
+func (h *LifecycleHooks) ready(worker int, waited time.Duration) {
+	if h != nil && h.OnReady != nil {
+		h.OnReady(worker, waited)
+	}
+}
+
+func (h *LifecycleHooks) jobStart(worker int) {
+	if h != nil && h.OnJobStart != nil {
+		h.OnJobStart(worker)
+	}
+}
+
+func (h *LifecycleHooks) jobEnd(worker int, took time.Duration, err error) {
+	if h != nil && h.OnJobEnd != nil {
+		h.OnJobEnd(worker, took, err)
+	}
+}
+
+func (h *LifecycleHooks) terminate(worker int) {
+	if h != nil && h.OnTerminate != nil {
+		h.OnTerminate(worker)
+	}
+}
+
+func (h *LifecycleHooks) interrupt(worker int) {
+	if h != nil && h.OnInterrupt != nil {
+		h.OnInterrupt(worker)
+	}
+}
+
+/*
+WithLifecycleHooks - Calls hooks as every worker of the pool moves through its lifecycle, see
+LifecycleHooks. The hooks are called on the worker's goroutine, apart from OnInterrupt which is
+called by whichever goroutine interrupts the job, and should return quickly.
+*/
+func WithLifecycleHooks(hooks LifecycleHooks) Option {
+	return func(pool *WorkPool) {
+		pool.hooks = &hooks
+	}
+}
+
+/*
+SetHooks - Sets the lifecycle hooks of the worker, this must be done before it is started.
+*/
+func (wrapper *workerWrapper) SetHooks(hooks LifecycleHooks) {
+	wrapper.hooks = &hooks
+}
+
+/*
+indexedWorker - Implemented by workers which need to know their index in the pool, the pool sets
+it when opened.
+*/
+type indexedWorker interface {
+	setIndex(index int)
+}
+
+/*
+tracingWorker - Wraps a worker and calls lifecycle hooks around each of its methods.
+*/
+type tracingWorker struct {
+	worker  GoroutineWorker
+	hooks   LifecycleHooks
+	index   int
+	waiting time.Time
+}
+
+/*
+NewTracingWorker - Wraps a worker so that hooks are called around its methods, for tracing custom
+workers without changing them. The wrapper forwards Initialize, Terminate, Interrupt, Flush and
+Reinitialize to the worker if it implements them, and the hooks are called whether it does or not.
+Workers are told their index when the pool they belong to is opened, until then hooks receive -1.
+Hooks may also be set for a whole pool with WithLifecycleHooks, in which case both sets are called.
+*/
+func NewTracingWorker(worker GoroutineWorker, hooks LifecycleHooks) GoroutineWorker {
+	return &tracingWorker{
+		worker: worker,
+		hooks:  hooks,
+		index:  -1,
+	}
+}
+
+func (w *tracingWorker) setIndex(index int) {
+	w.index = index
+}
+
+func (w *tracingWorker) Job(in interface{}) interface{} {
+	w.hooks.jobStart(w.index)
+	start := time.Now()
+
+	completed := false
+	defer func() {
+		var err error
+		if !completed {
+			err = ErrJobPanicked
+		}
+		w.hooks.jobEnd(w.index, time.Since(start), err)
+	}()
+
+	out := w.worker.Job(in)
+	completed = true
+	return out
+}
+
+func (w *tracingWorker) Ready() bool {
+	if !w.worker.Ready() {
+		if w.waiting.IsZero() {
+			w.waiting = time.Now()
+		}
+		return false
+	}
+
+	var waited time.Duration
+	if !w.waiting.IsZero() {
+		waited = time.Since(w.waiting)
+		w.waiting = time.Time{}
+	}
+	w.hooks.ready(w.index, waited)
+	return true
+}
+
+func (w *tracingWorker) Initialize() {
+	if extWorker, ok := w.worker.(GoroutineExtendedWorker); ok {
+		extWorker.Initialize()
+	}
+	w.hooks.initialize(w.index)
+}
+
+func (w *tracingWorker) Terminate() {
+	if extWorker, ok := w.worker.(GoroutineExtendedWorker); ok {
+		extWorker.Terminate()
+	}
+	w.hooks.terminate(w.index)
+}
+
+func (w *tracingWorker) Interrupt() {
+	w.hooks.interrupt(w.index)
+	if intWorker, ok := w.worker.(GoroutineInterruptable); ok {
+		intWorker.Interrupt()
+	}
+}
+
+func (w *tracingWorker) Reinitialize() error {
+	if reinitWorker, ok := w.worker.(GoroutineReinitializableWorker); ok {
+		return reinitWorker.Reinitialize()
+	}
+	return nil
+}
+
+func (w *tracingWorker) Flush() error {
+	if flushWorker, ok := w.worker.(GoroutineFlushableWorker); ok {
+		return flushWorker.Flush()
+	}
+	return nil
+}
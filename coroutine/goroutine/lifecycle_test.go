@@ -0,0 +1,103 @@
+package goroutine
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventLog records lifecycle events in the order they are reported
+type eventLog struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.events = append(l.events, fmt.Sprintf(format, args...))
+}
+
+func (l *eventLog) get() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string(nil), l.events...)
+}
+
+func (l *eventLog) hooks() LifecycleHooks {
+	return LifecycleHooks{
+		OnInitialize: func(worker int) { l.add("initialize %d", worker) },
+		OnReady:      func(worker int, waited time.Duration) { l.add("ready %d", worker) },
+		OnJobStart:   func(worker int) { l.add("start %d", worker) },
+		OnJobEnd: func(worker int, took time.Duration, err error) {
+			l.add("end %d %v", worker, err)
+		},
+		OnTerminate: func(worker int) { l.add("terminate %d", worker) },
+	}
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	log := &eventLog{}
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		if in == "panic" {
+			panic("job panicked")
+		}
+		return in
+	}, WithLifecycleHooks(log.hooks())).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	if _, err := pool.SendWork("hello"); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	if _, err := pool.SendWork("panic"); !errors.Is(err, ErrJobPanicked) {
+		t.Errorf("Expected ErrJobPanicked, got %v", err)
+	}
+	pool.Close()
+
+	expected := []string{
+		"initialize 0", "ready 0",
+		"start 0", "end 0 <nil>", "ready 0",
+		"start 0", "end 0 " + ErrJobPanicked.Error(), "ready 0",
+		"terminate 0",
+	}
+	if events := log.get(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("Unexpected events %q, expected %q", events, expected)
+	}
+}
+
+func TestTracingWorker(t *testing.T) {
+	log := &eventLog{}
+	inner := &toggleWorker{ready: 1}
+	workers := []GoroutineWorker{
+		&toggleWorker{},
+		NewTracingWorker(inner, log.hooks()),
+	}
+
+	pool, err := CreateCustomPool(workers).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	// The first worker is never ready, leaving the traced worker to take the job
+	if result, err := pool.SendWork("hello"); err != nil || result != "hello" {
+		t.Errorf("Unexpected result %v, %v", result, err)
+	}
+	pool.Close()
+
+	if inner.initialized != 1 || inner.terminated != 1 {
+		t.Errorf("Expected the wrapped worker to be initialized and terminated once, got %v and %v",
+			inner.initialized, inner.terminated)
+	}
+
+	expected := []string{"initialize 1", "ready 1", "start 1", "end 1 <nil>", "ready 1", "terminate 1"}
+	if events := log.get(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("Unexpected events %q, expected %q", events, expected)
+	}
+}
@@ -0,0 +1,267 @@
+package goroutine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+WithAdmissionFunc - Consults admit on the caller's goroutine for every job submitted to the pool,
+after the payload checks and before the job is queued, so that load can be shed on signals of the
+application's own such as memory or the health of a downstream service. admit is given the async
+jobs pending, as NumPendingAsyncJobs, the jobs running on workers and the job itself, and a
+non-nil error rejects the job with that error wrapped in ErrAdmissionRejected. Rejected jobs are
+counted in Stats as RejectedJobs and ShedJobs.
+*/
+func WithAdmissionFunc(admit func(pending int, running int, jobData interface{}) error) Option {
+	return func(pool *WorkPool) {
+		pool.admission = admit
+	}
+}
+
+/*
+WithPayloadLimit - Adds a check evaluated on the caller's goroutine for every job submitted to the
+pool, before the job is queued. A non-nil error rejects the job and is returned directly from the
+submitting call. The option may be given more than once, checks run in the order given.
+*/
+func WithPayloadLimit(check func(interface{}) error) Option {
+	return func(pool *WorkPool) {
+		if check != nil {
+			pool.payloadChecks = append(pool.payloadChecks, check)
+		}
+	}
+}
+
+/*
+PayloadSizeLimit - A payload check for WithPayloadLimit which rejects any job whose size, as
+reported by the size function, is over maxBytes with ErrPayloadTooLarge.
+*/
+func PayloadSizeLimit(size func(interface{}) int64, maxBytes int64) func(interface{}) error {
+	return func(jobData interface{}) error {
+		if size(jobData) > maxBytes {
+			return ErrPayloadTooLarge
+		}
+		return nil
+	}
+}
+
+/*
+WithQueueMemoryLimit - Limits the aggregate size of the payloads of all jobs which have been
+submitted and not yet completed. A job whose size, as reported by the size function, would take
+the total over maxBytes is rejected with ErrQueueMemoryExceeded. The size of a job is released
+once its result has been returned to the caller.
+*/
+func WithQueueMemoryLimit(size func(interface{}) int64, maxBytes int64) Option {
+	return func(pool *WorkPool) {
+		pool.queueMemory = &queueMemoryLimit{size: size, max: maxBytes}
+	}
+}
+
+/*
+queueMemoryLimit - Tracks the payload bytes of the jobs in flight.
+*/
+type queueMemoryLimit struct {
+	size func(interface{}) int64
+	max  int64
+	used int64
+}
+
+func (limit *queueMemoryLimit) reserve(jobData interface{}) error {
+	n := limit.size(jobData)
+	for {
+		used := atomic.LoadInt64(&limit.used)
+		if used+n > limit.max {
+			return ErrQueueMemoryExceeded
+		}
+		if atomic.CompareAndSwapInt64(&limit.used, used, used+n) {
+			return nil
+		}
+	}
+}
+
+func (limit *queueMemoryLimit) free(jobData interface{}) {
+	atomic.AddInt64(&limit.used, -limit.size(jobData))
+}
+
+/*
+WithMaxInFlight - Caps the number of jobs submitted and not yet completed, queued and running
+alike, across every way of submitting work so that a runaway producer cannot exhaust memory.
+When the cap is reached the synchronous calls, SendWork, SendWorkTimed, SendWorkContext, Do,
+SendWorkGroup, Map and Reduce, wait for a job to complete, giving up as they would on their
+timeout or context. The asynchronous calls, SendWorkAsync and the others returning before the job
+completes, fail with ErrTooManyInFlight instead, as does ForEach for the inputs over the cap, and
+SendWorkOrDrop drops the job. A job given up on after a timeout or cancellation counts until its
+worker has finished with it. Workers taken with BorrowWorker, Reserve or Broadcast are outside of the cap.
+*/
+func WithMaxInFlight(n int) Option {
+	return func(pool *WorkPool) {
+		if n > 0 {
+			pool.inFlight = &inFlightGate{max: n}
+		}
+	}
+}
+
+/*
+inFlightGate - Counts the jobs in flight against WithMaxInFlight, each job holds a slot from its
+admission until it is released. A job given up on once a worker took it keeps holding a slot
+until the worker has finished with it.
+*/
+type inFlightGate struct {
+	mutex sync.Mutex
+	max   int
+	count int
+
+	// freed is closed when a slot is freed while callers are waiting, and then replaced
+	freed chan struct{}
+}
+
+/*
+enter - Takes a slot, waiting until deadline, if not zero, or until ctx is done. Pass
+cancelledContext to take a slot only if one is free right now, ErrTooManyInFlight is returned if
+there is none.
+*/
+func (gate *inFlightGate) enter(ctx context.Context, deadline time.Time, clock Clock) error {
+	if gate == nil {
+		return nil
+	}
+
+	var timeout <-chan time.Time
+	for {
+		freed := gate.tryEnter()
+		if freed == nil {
+			return nil
+		}
+		if ctx == cancelledContext {
+			return ErrTooManyInFlight
+		}
+
+		if timeout == nil && !deadline.IsZero() {
+			timer := getTimer(clock, deadline.Sub(clock.Now()))
+			defer putTimer(timer)
+			timeout = timer.C()
+		}
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return cancelled(ctx)
+		case <-timeout:
+			return &TimeoutError{}
+		}
+	}
+}
+
+// tryEnter takes a slot if one is free, otherwise it returns a channel closed once one is freed
+func (gate *inFlightGate) tryEnter() chan struct{} {
+	gate.mutex.Lock()
+	defer gate.mutex.Unlock()
+
+	if gate.count < gate.max {
+		gate.count++
+		return nil
+	}
+	if gate.freed == nil {
+		gate.freed = make(chan struct{})
+	}
+	return gate.freed
+}
+
+// extend takes another slot for a job already holding one, regardless of the cap, so that the
+// job keeps a slot once its caller releases its own
+func (gate *inFlightGate) extend() {
+	if gate != nil {
+		gate.mutex.Lock()
+		gate.count++
+		gate.mutex.Unlock()
+	}
+}
+
+func (gate *inFlightGate) leave() {
+	if gate != nil {
+		gate.mutex.Lock()
+		gate.count--
+		if gate.freed != nil {
+			close(gate.freed)
+			gate.freed = nil
+		}
+		gate.mutex.Unlock()
+	}
+}
+
+/*
+admit - Runs the submission checks of the pool on a job for a call which does not wait for a slot
+under WithMaxInFlight, see admitUntil.
+*/
+func (pool *WorkPool) admit(jobData interface{}) (admission, error) {
+	return pool.admitUntil(cancelledContext, time.Time{}, jobData)
+}
+
+/*
+admission - What admit reserved for a job, to be handed back to release: the half open round of
+the circuit breaker for probe jobs and the epoch of the job for SubmitBarrier.
+*/
+type admission struct {
+	probe uint64
+	epoch *barrierEpoch
+}
+
+/*
+admitUntil - Runs the submission checks of the pool on a job, a rejected job is counted in Stats.
+When the pool was created WithMaxInFlight this waits for a slot until deadline, if not zero, or
+until ctx is done. Every job admitted must be released once it leaves the pool, and its outcome
+reported to the circuit breaker with the probe round of the admission returned here.
+*/
+func (pool *WorkPool) admitUntil(ctx context.Context, deadline time.Time, jobData interface{}) (admission, error) {
+	if len(pool.payloadChecks) > 0 || pool.admission != nil || pool.queueMemory != nil {
+		jobData = inspected(jobData)
+	}
+	for _, check := range pool.payloadChecks {
+		if err := check(jobData); err != nil {
+			atomic.AddUint64(&pool.rejectedJobs, 1)
+			return admission{}, err
+		}
+	}
+	if pool.admission != nil {
+		pending, running := int(pool.NumPendingAsyncJobs()), pool.numRunningJobs()
+		if err := pool.admission(pending, running, jobData); err != nil {
+			atomic.AddUint64(&pool.shedJobs, 1)
+			atomic.AddUint64(&pool.rejectedJobs, 1)
+			return admission{}, fmt.Errorf("%w: %w", ErrAdmissionRejected, err)
+		}
+	}
+	if err := pool.inFlight.enter(ctx, deadline, pool.clock); err != nil {
+		if err == ErrTooManyInFlight {
+			atomic.AddUint64(&pool.rejectedJobs, 1)
+		}
+		return admission{}, err
+	}
+	probe, err := pool.breaker.allow()
+	if err != nil {
+		pool.inFlight.leave()
+		atomic.AddUint64(&pool.rejectedJobs, 1)
+		return admission{}, err
+	}
+	if pool.queueMemory != nil {
+		if err := pool.queueMemory.reserve(jobData); err != nil {
+			pool.inFlight.leave()
+			pool.breaker.cancel(probe)
+			atomic.AddUint64(&pool.rejectedJobs, 1)
+			return admission{}, err
+		}
+	}
+	return admission{probe: probe, epoch: pool.barriers.enter()}, nil
+}
+
+/*
+release - Returns the resources reserved for a job by admit.
+*/
+func (pool *WorkPool) release(jobData interface{}, ticket admission) {
+	if pool.queueMemory != nil {
+		pool.queueMemory.free(inspected(jobData))
+	}
+	pool.inFlight.leave()
+	pool.barriers.leave(ticket.epoch)
+}
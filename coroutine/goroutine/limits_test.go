@@ -0,0 +1,215 @@
+package goroutine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func payloadSize(in interface{}) int64 {
+	if b, ok := in.([]byte); ok {
+		return int64(len(b))
+	}
+	return 0
+}
+
+func TestPayloadSizeLimit(t *testing.T) {
+	ran := 0
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		ran++
+		return nil
+	}, WithPayloadLimit(PayloadSizeLimit(payloadSize, 10))).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if _, err := pool.SendWork(make([]byte, 10)); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	if _, err := pool.SendWork(make([]byte, 11)); err != ErrPayloadTooLarge {
+		t.Errorf("Expected ErrPayloadTooLarge, got %v", err)
+	}
+	if err := pool.SendWorkAsync(make([]byte, 11), nil); err != ErrPayloadTooLarge {
+		t.Errorf("Expected ErrPayloadTooLarge from async submission, got %v", err)
+	}
+
+	if ran != 1 {
+		t.Errorf("Expected only the accepted job to run, ran %v", ran)
+	}
+	if rejected := pool.Stats().RejectedJobs; rejected != 2 {
+		t.Errorf("Expected 2 rejected jobs, got %v", rejected)
+	}
+}
+
+func TestQueueMemoryLimit(t *testing.T) {
+	release := make(chan struct{})
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		<-release
+		return nil
+	}, WithQueueMemoryLimit(payloadSize, 100)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	// Queue jobs behind a blocked worker until their sizes reach the cap
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		if err := pool.SendWorkAsync(make([]byte, 25), func(interface{}, error) { wg.Done() }); err != nil {
+			t.Errorf("Failed to send work: %v", err)
+			wg.Done()
+		}
+	}
+
+	if err := pool.SendWorkAsync(make([]byte, 1), nil); err != ErrQueueMemoryExceeded {
+		t.Errorf("Expected ErrQueueMemoryExceeded for the overflow job, got %v", err)
+	}
+	if rejected := pool.Stats().RejectedJobs; rejected != 1 {
+		t.Errorf("Expected 1 rejected job, got %v", rejected)
+	}
+
+	close(release)
+	wg.Wait()
+
+	// Completed jobs release their share of the limit
+	if _, err := pool.SendWork(make([]byte, 100)); err != nil {
+		t.Errorf("Failed to send work after the queue drained: %v", err)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	var running, highWater int32
+	pool, err := CreatePool(32, func(in interface{}) interface{} {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			high := atomic.LoadInt32(&highWater)
+			if now <= high || atomic.CompareAndSwapInt32(&highWater, high, now) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		if in == "panic" {
+			panic("job panicked")
+		}
+		return in
+	}, WithMaxInFlight(8)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	var tooMany int32
+	wg := sync.WaitGroup{}
+	for g := 0; g < 32; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				switch (g + i) % 7 {
+				case 0:
+					pool.SendWork(i)
+				case 1:
+					// Short timeouts give up both waiting for a slot and while running
+					pool.SendWorkTimed(time.Millisecond, i)
+				case 2:
+					if err := pool.SendWorkAsync(i, nil); err == ErrTooManyInFlight {
+						atomic.AddInt32(&tooMany, 1)
+					}
+				case 3:
+					pool.SendWork("panic")
+				case 4:
+					ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+					pool.SendWorkContext(ctx, i)
+					cancel()
+				case 5:
+					pool.SendWorkOrDrop(i)
+				case 6:
+					pool.Map([]interface{}{i, i + 1, i + 2})
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// Every slot is released once the async jobs and the jobs given up on have finished
+	count := -1
+	for deadline := time.Now().Add(time.Second); count != 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		pool.inFlight.mutex.Lock()
+		count = pool.inFlight.count
+		pool.inFlight.mutex.Unlock()
+	}
+	if count != 0 {
+		t.Errorf("Expected every slot to be released, %v are held", count)
+	}
+	if high := atomic.LoadInt32(&highWater); high > 8 || high == 0 {
+		t.Errorf("Expected at most 8 jobs in flight, got %v", high)
+	}
+	if atomic.LoadInt32(&tooMany) == 0 {
+		t.Error("Expected some async submissions to be refused")
+	}
+}
+
+func TestAdmissionFunc(t *testing.T) {
+	var mutex sync.Mutex
+	ran := map[int]bool{}
+	shed := errors.New("shedding load")
+	submitted := 0
+
+	pool, err := CreatePool(4, func(in interface{}) interface{} {
+		mutex.Lock()
+		ran[in.(int)] = true
+		mutex.Unlock()
+		return in
+	}, WithAdmissionFunc(func(pending, running int, jobData interface{}) error {
+		if pending < 0 || running < 0 || running > 4 {
+			t.Errorf("Expected sane counts, got %v pending and %v running", pending, running)
+		}
+		submitted++
+		if submitted%3 == 0 {
+			return shed
+		}
+		return nil
+	})).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	rejected := map[int]bool{}
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		err := pool.SendWorkAsync(i, func(interface{}, error) { wg.Done() })
+		if err != nil {
+			wg.Done()
+			if !errors.Is(err, ErrAdmissionRejected) || !errors.Is(err, shed) {
+				t.Errorf("Expected ErrAdmissionRejected wrapping the error, got %v", err)
+			}
+			rejected[i] = true
+		}
+	}
+	wg.Wait()
+
+	if len(rejected) != 10 {
+		t.Errorf("Expected a third of the jobs rejected, got %v", len(rejected))
+	}
+	for i := 0; i < 30; i++ {
+		if rejected[i] == ran[i] {
+			t.Errorf("Expected job %v to run only if admitted, rejected %v and ran %v", i, rejected[i], ran[i])
+		}
+	}
+	if stats := pool.Stats(); stats.ShedJobs != 10 || stats.RejectedJobs != 10 {
+		t.Errorf("Expected 10 shed jobs, got %v shed and %v rejected", stats.ShedJobs, stats.RejectedJobs)
+	}
+}
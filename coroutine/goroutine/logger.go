@@ -0,0 +1,57 @@
+package goroutine
+
+import (
+	"sync/atomic"
+)
+
+/*
+Logger - Receives the messages of a pool, *log.Logger satisfies it.
+*/
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+/*
+poolLogger - Holds the logger of a pool, which may be swapped while the pool is running. Workers
+share the logger of their pool. The name given WithPoolName prefixes every message.
+*/
+type poolLogger struct {
+	value atomic.Value
+	name  string
+}
+
+type loggerBox struct {
+	Logger
+}
+
+func (l *poolLogger) printf(format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	if box, _ := l.value.Load().(loggerBox); box.Logger != nil {
+		if l.name != "" {
+			box.Printf("[%s] "+format, append([]interface{}{l.name}, args...)...)
+			return
+		}
+		box.Printf(format, args...)
+	}
+}
+
+/*
+jobPanic - What a job panicked with and where, kept for the logger.
+*/
+type jobPanic struct {
+	value interface{}
+	stack []byte
+}
+
+/*
+SetLogger - Sets the logger the pool reports to, or removes it if nil, which is the default. The
+pool logs when it has opened, as each worker is initialized and terminated, when a job times out or
+panics, along with the job's sequence number and for a panic its stack, when the callback of an
+async job panics, and when closing starts and finishes. Nothing is logged for jobs that complete normally.
+The messages of a pool named WithPoolName are prefixed with its name in brackets.
+*/
+func (pool *WorkPool) SetLogger(logger Logger) {
+	pool.logger.value.Store(loggerBox{logger})
+}
@@ -0,0 +1,89 @@
+package goroutine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureLogger keeps every message logged
+type captureLogger struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (l *captureLogger) Printf(format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *captureLogger) all() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return strings.Join(l.messages, "\n")
+}
+
+func TestSetLogger(t *testing.T) {
+	logger := &captureLogger{}
+
+	pool := CreatePool(1, func(in interface{}) interface{} {
+		switch in {
+		case "panic":
+			panic("job exploded")
+		case "slow":
+			time.Sleep(20 * time.Millisecond)
+		}
+		return in
+	}, WithPoolName("uploads"))
+	pool.SetLogger(logger)
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	if _, err := pool.SendWorkTimed(5*time.Millisecond, "slow"); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut, got %v", err)
+	}
+	if _, err := pool.SendWork("panic"); !errors.Is(err, ErrJobPanicked) {
+		t.Errorf("Expected ErrJobPanicked, got %v", err)
+	}
+	if _, err := pool.SendWork("ok"); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	pool.Close()
+
+	// The timed out job is logged once its late result has been collected in the background
+	for deadline := time.Now().Add(time.Second); !strings.Contains(logger.all(), "timed out") && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+
+	messages := logger.all()
+	for _, want := range []string{
+		"pool opened with 1 workers",
+		"worker 0 initialized",
+		"job 1 timed out",
+		"job 2 panicked: job exploded",
+		"logger_test.go",
+		"pool closing",
+		"worker 0 terminated",
+		"pool closed",
+	} {
+		if !strings.Contains(messages, want) {
+			t.Errorf("Expected the log to contain %q, got:\n%s", want, messages)
+		}
+	}
+	logger.mutex.Lock()
+	for _, message := range logger.messages {
+		if !strings.HasPrefix(message, "[uploads] ") {
+			t.Errorf("Expected every message to be prefixed with the pool name, got %q", message)
+		}
+	}
+	logger.mutex.Unlock()
+	if strings.Contains(messages, "job 3") {
+		t.Errorf("Expected nothing to be logged for a job that succeeded, got:\n%s", messages)
+	}
+}
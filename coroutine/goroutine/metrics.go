@@ -0,0 +1,137 @@
+package goroutine
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+/*
+The metrics written by WritePrometheusText. These names are part of the package API and will not
+change, every series carries a pool label holding the name given by WithPoolName:
+
+	goroutine_pool_jobs_total{outcome}      counter   jobs completed, by JobOutcome string
+	goroutine_pool_job_duration_seconds     histogram time jobs spent running on a worker
+	goroutine_pool_queue_length             gauge     async jobs submitted and not yet finished
+	goroutine_pool_workers                  gauge     workers in the pool
+	goroutine_pool_timeouts_total           counter   jobs that did not complete before their timeout
+*/
+const (
+	metricJobsTotal   = "goroutine_pool_jobs_total"
+	metricJobDuration = "goroutine_pool_job_duration_seconds"
+	metricQueueLength = "goroutine_pool_queue_length"
+	metricWorkers     = "goroutine_pool_workers"
+	metricTimeouts    = "goroutine_pool_timeouts_total"
+)
+
+// durationBuckets - Upper bounds of the job duration histogram, in seconds.
+var durationBuckets = [...]float64{.0005, .001, .005, .01, .05, .1, .5, 1, 5}
+
+/*
+jobMetrics - Counters recorded for every finished job, all fields are accessed atomically.
+*/
+type jobMetrics struct {
+	outcomes [JobCancelled + 1]uint64
+
+	// buckets are not cumulative, the last one counts durations above every bound
+	buckets    [len(durationBuckets) + 1]uint64
+	durationNs uint64
+	durations  uint64
+}
+
+func (m *jobMetrics) record(result jobResult, outcome JobOutcome) {
+	if result.panicked && outcome == JobOK {
+		outcome = JobPanicked
+	}
+	atomic.AddUint64(&m.outcomes[outcome], 1)
+
+	if result.started.IsZero() || result.finished.IsZero() {
+		return
+	}
+	d := result.finished.Sub(result.started)
+	seconds := d.Seconds()
+
+	i := 0
+	for i < len(durationBuckets) && seconds > durationBuckets[i] {
+		i++
+	}
+	atomic.AddUint64(&m.buckets[i], 1)
+	atomic.AddUint64(&m.durationNs, uint64(d))
+	atomic.AddUint64(&m.durations, 1)
+}
+
+/*
+finishJob - Records the metrics of a completed job, logs it if it failed, hands its result to the
+discarded result handler if nobody will receive it and reports its trace if tracing is enabled.
+*/
+func (pool *WorkPool) finishJob(trace TraceFunc, job jobRequest, worker int, enqueued time.Time, result jobResult, outcome JobOutcome) {
+	pool.metrics.record(result, outcome)
+	if result.panic != nil {
+		pool.logger.printf("job %d panicked: %v\n%s", job.seq, result.panic.value, result.panic.stack)
+	} else if outcome == JobTimedOut {
+		pool.logger.printf("job %d timed out", job.seq)
+	}
+	if discardedJob(job, result, outcome) {
+		pool.discarded.add(job.seq, result.data)
+	}
+	traceJob(trace, job, worker, enqueued, result, outcome)
+}
+
+/*
+WritePrometheusText - Writes the pool's metrics to w in the Prometheus text exposition format,
+suitable for serving from a /metrics handler. The metric names are listed above.
+*/
+func (pool *WorkPool) WritePrometheusText(w io.Writer) error {
+	m := &pool.metrics
+	label := `pool="` + escapeLabel(pool.name) + `"`
+	out := bufio.NewWriter(w)
+
+	writeHeader(out, metricJobsTotal, "counter", "Jobs completed by the pool, by outcome.")
+	for outcome := JobOK; outcome <= JobCancelled; outcome++ {
+		writeSample(out, metricJobsTotal, label+`,outcome="`+outcome.String()+`"`,
+			strconv.FormatUint(atomic.LoadUint64(&m.outcomes[outcome]), 10))
+	}
+
+	writeHeader(out, metricJobDuration, "histogram", "Time jobs spent running on a worker.")
+	var cumulative uint64
+	for i, bound := range durationBuckets {
+		cumulative += atomic.LoadUint64(&m.buckets[i])
+		writeSample(out, metricJobDuration+"_bucket",
+			label+`,le="`+strconv.FormatFloat(bound, 'g', -1, 64)+`"`,
+			strconv.FormatUint(cumulative, 10))
+	}
+	cumulative += atomic.LoadUint64(&m.buckets[len(durationBuckets)])
+	writeSample(out, metricJobDuration+"_bucket", label+`,le="+Inf"`, strconv.FormatUint(cumulative, 10))
+	sum := time.Duration(atomic.LoadUint64(&m.durationNs)).Seconds()
+	writeSample(out, metricJobDuration+"_sum", label, strconv.FormatFloat(sum, 'g', -1, 64))
+	writeSample(out, metricJobDuration+"_count", label, strconv.FormatUint(atomic.LoadUint64(&m.durations), 10))
+
+	writeHeader(out, metricQueueLength, "gauge", "Async jobs submitted and not yet finished.")
+	writeSample(out, metricQueueLength, label, strconv.Itoa(int(pool.NumPendingAsyncJobs())))
+
+	writeHeader(out, metricWorkers, "gauge", "Workers in the pool.")
+	writeSample(out, metricWorkers, label, strconv.Itoa(pool.NumWorkers()))
+
+	writeHeader(out, metricTimeouts, "counter", "Jobs that did not complete before their timeout.")
+	writeSample(out, metricTimeouts, label, strconv.FormatUint(atomic.LoadUint64(&m.outcomes[JobTimedOut]), 10))
+
+	return out.Flush()
+}
+
+func writeHeader(out *bufio.Writer, name, kind, help string) {
+	out.WriteString("# HELP " + name + " " + help + "\n")
+	out.WriteString("# TYPE " + name + " " + kind + "\n")
+}
+
+func writeSample(out *bufio.Writer, name, labels, value string) {
+	out.WriteString(name + "{" + labels + "} " + value + "\n")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,79 @@
+package goroutine
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePrometheusText(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		switch in {
+		case "panic":
+			panic("job panicked")
+		case "slow":
+			time.Sleep(50 * time.Millisecond)
+		}
+		return in
+	}, WithPoolName(`web"api`)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := pool.SendWork("ok"); err != nil {
+			t.Errorf("Failed to send work: %v", err)
+		}
+	}
+	if _, err := pool.SendWork("panic"); !errors.Is(err, ErrJobPanicked) {
+		t.Errorf("Expected ErrJobPanicked, got %v", err)
+	}
+	if _, err := pool.SendWorkTimed(10*time.Millisecond, "slow"); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut, got %v", err)
+	}
+
+	if _, err := pool.SendWork("ok"); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+
+	// The timed out job is recorded once its late result has been collected in the background
+	var text string
+	for deadline := time.Now().Add(time.Second); ; {
+		buf := bytes.Buffer{}
+		if err := pool.WritePrometheusText(&buf); err != nil {
+			t.Errorf("Failed to write metrics: %v", err)
+		}
+		text = buf.String()
+		if strings.Contains(text, `goroutine_pool_timeouts_total{pool="web\"api"} 1`) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, line := range []string{
+		`# TYPE goroutine_pool_jobs_total counter`,
+		`goroutine_pool_jobs_total{pool="web\"api",outcome="ok"} 4`,
+		`goroutine_pool_jobs_total{pool="web\"api",outcome="panic"} 1`,
+		`goroutine_pool_jobs_total{pool="web\"api",outcome="timeout"} 1`,
+		`goroutine_pool_jobs_total{pool="web\"api",outcome="cancelled"} 0`,
+		`# TYPE goroutine_pool_job_duration_seconds histogram`,
+		`goroutine_pool_job_duration_seconds_bucket{pool="web\"api",le="+Inf"} 6`,
+		`goroutine_pool_job_duration_seconds_count{pool="web\"api"} 6`,
+		`goroutine_pool_queue_length{pool="web\"api"} 0`,
+		`goroutine_pool_workers{pool="web\"api"} 1`,
+		`goroutine_pool_timeouts_total{pool="web\"api"} 1`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, text)
+		}
+	}
+
+	// Only the slow job ran for longer than 10ms
+	if !strings.Contains(text, `goroutine_pool_job_duration_seconds_bucket{pool="web\"api",le="0.01"} 5`+"\n") {
+		t.Errorf("Expected five jobs within 10ms, got:\n%s", text)
+	}
+}
@@ -0,0 +1,209 @@
+package goroutine
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+var (
+	ErrInvalidPoolOptions = errors.New("invalid pool options")
+)
+
+/*
+NewPool - Creates a pool from options alone, the workers being described by exactly one of WithJob,
+WithGenericJobs or WithCustomWorkers alongside any of the other options, for example:
+
+	pool, err := NewPool(WithWorkers(8), WithJob(fn), WithQueueSize(4), WithDefaultTimeout(time.Second))
+
+Without WithWorkers a pool of WithJob or WithGenericJobs has one worker per GOMAXPROCS, and a pool
+of custom workers has one worker for each of them. Options which cannot be combined, a missing
+source of work or a worker count of zero or less are reported as ErrInvalidPoolOptions wrapped
+with a description of each problem, in which case no pool is returned. CreatePool,
+CreatePoolGeneric, CreateCustomPool and CreateCustomPoolBuffered are shorthands for NewPool which
+keep their lenient behaviour, a pool is always returned.
+*/
+func NewPool(opts ...Option) (*WorkPool, error) {
+	pool, err := newPool(opts)
+	if err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+/*
+poolConfig - The settings of NewPool which describe the workers, only present while the options
+are applied.
+*/
+type poolConfig struct {
+	numWorkers    int
+	numWorkersSet bool
+	job           func(interface{}) interface{}
+	jobSet        bool
+	generic       bool
+	custom        []GoroutineWorker
+	customSet     bool
+	errs          []error
+}
+
+func (config *poolConfig) fail(format string, args ...interface{}) {
+	config.errs = append(config.errs, fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidPoolOptions}, args...)...))
+}
+
+/*
+WithWorkers - Sets the number of workers of a pool created by NewPool, which must be at least one.
+With WithCustomWorkers it must match the number of custom workers.
+*/
+func WithWorkers(n int) Option {
+	return func(pool *WorkPool) {
+		pool.config.numWorkers = n
+		pool.config.numWorkersSet = true
+	}
+}
+
+/*
+WithJob - Sets the closure each worker of a pool created by NewPool runs for every job, as passed
+to CreatePool.
+*/
+func WithJob(job func(interface{}) interface{}) Option {
+	return func(pool *WorkPool) {
+		pool.config.job = job
+		pool.config.jobSet = true
+	}
+}
+
+/*
+WithGenericJobs - Creates the pool with generic workers, which run the job sent to them as
+described by CreatePoolGeneric.
+*/
+func WithGenericJobs() Option {
+	return func(pool *WorkPool) {
+		pool.config.generic = true
+	}
+}
+
+/*
+WithCustomWorkers - Creates the pool with a worker for each of workers, as passed to
+CreateCustomPool.
+*/
+func WithCustomWorkers(workers []GoroutineWorker) Option {
+	return func(pool *WorkPool) {
+		pool.config.custom = workers
+		pool.config.customSet = true
+	}
+}
+
+/*
+WithQueueSize - Lets up to depth jobs be queued on each worker, as described by
+CreateCustomPoolBuffered. A depth of 0, the default, creates an ordinary pool.
+*/
+func WithQueueSize(depth int) Option {
+	return func(pool *WorkPool) {
+		if depth < 0 {
+			pool.config.fail("queue size %d is negative", depth)
+			return
+		}
+		pool.bufDepth = depth
+	}
+}
+
+/*
+newPool - Applies the options and creates the workers they describe. The pool is returned even when
+the options are invalid, built as best it can be, so that the older constructors keep working for
+arguments NewPool rejects.
+*/
+func newPool(opts []Option) (*WorkPool, error) {
+	pool := &WorkPool{
+		running:     0,
+		config:      &poolConfig{},
+		clock:       realClock{},
+		panics:      newPanicHistory(DefaultPanicHistorySize),
+		readyTicker: newReadyTicker(),
+	}
+	for _, opt := range opts {
+		opt(pool)
+	}
+	if pool.adaptive != nil {
+		pool.adaptive.clock = pool.clock
+	}
+	pool.readyTicker.clock = pool.clock
+	if pool.breaker != nil {
+		pool.breaker.clock = pool.clock
+	}
+	if pool.watchdog != nil {
+		pool.watchdog.clock = pool.clock
+	}
+	config := pool.config
+	pool.config = nil
+
+	switch {
+	case config.customSet && config.jobSet:
+		config.fail("WithJob cannot be combined with WithCustomWorkers, custom workers run their own job")
+	case config.generic && (config.jobSet || config.customSet):
+		config.fail("WithGenericJobs cannot be combined with WithJob or WithCustomWorkers")
+	case !config.customSet && !config.jobSet && !config.generic:
+		config.fail("one of WithJob, WithGenericJobs or WithCustomWorkers is required")
+	case config.jobSet && config.job == nil:
+		config.fail("WithJob was given a nil job")
+	}
+	if config.customSet {
+		if len(config.custom) == 0 {
+			config.fail("WithCustomWorkers was given no workers")
+		}
+		if config.numWorkersSet && config.numWorkers != len(config.custom) {
+			config.fail("WithWorkers(%d) does not match the %d custom workers", config.numWorkers, len(config.custom))
+		}
+		if pool.callerRuns {
+			config.errs = append(config.errs, fmt.Errorf("%w: %w", ErrInvalidPoolOptions, ErrCallerRunsCustomWorkers))
+		}
+	} else if config.numWorkersSet && config.numWorkers <= 0 {
+		config.fail("WithWorkers(%d) must be at least 1", config.numWorkers)
+	}
+	if pool.external != nil {
+		switch {
+		case config.customSet:
+			config.fail("WithExternalWorkers cannot be combined with WithCustomWorkers, RunWorker runs the pool's job")
+		case config.numWorkersSet:
+			config.fail("WithExternalWorkers cannot be combined with WithWorkers, the workers are the RunWorker calls")
+		}
+		if pool.bufDepth > 0 || pool.lazyStart || pool.burst != nil {
+			config.fail("WithExternalWorkers cannot be combined with WithQueueSize, WithLazyStart or WithBurstWorkers")
+		}
+	}
+
+	switch {
+	case config.customSet:
+		workers := make([]*workerWrapper, len(config.custom))
+		for i := range workers {
+			workers[i] = &workerWrapper{
+				worker: config.custom[i],
+			}
+		}
+		pool.setWorkers(workers)
+	default:
+		numWorkers := config.numWorkers
+		if !config.numWorkersSet {
+			numWorkers = runtime.GOMAXPROCS(0)
+		}
+		if pool.external != nil {
+			// The one slot served by every RunWorker call
+			numWorkers = 1
+		}
+		if numWorkers < 0 {
+			numWorkers = 0
+		}
+		job := config.job
+		if config.generic {
+			job = genericJob
+		}
+		pool.job = &job
+		workers := make([]*workerWrapper, numWorkers)
+		for i := range workers {
+			workers[i] = &workerWrapper{
+				worker: &(defaultWorker{pool.job}),
+			}
+		}
+		pool.setWorkers(workers)
+	}
+	return pool, errors.Join(config.errs...)
+}
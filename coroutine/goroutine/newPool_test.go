@@ -0,0 +1,136 @@
+package goroutine
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewPoolInvalid(t *testing.T) {
+	double := func(in interface{}) interface{} { return in.(int) * 2 }
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"no work", []Option{WithWorkers(2)}, "is required"},
+		{"job and custom", []Option{WithJob(double), WithCustomWorkers(spinWorkers(2, 0))}, "WithJob cannot be combined"},
+		{"generic and job", []Option{WithGenericJobs(), WithJob(double)}, "WithGenericJobs cannot be combined"},
+		{"generic and custom", []Option{WithGenericJobs(), WithCustomWorkers(spinWorkers(2, 0))}, "WithGenericJobs cannot be combined"},
+		{"nil job", []Option{WithJob(nil)}, "nil job"},
+		{"zero workers", []Option{WithWorkers(0), WithJob(double)}, "at least 1"},
+		{"negative workers", []Option{WithWorkers(-1), WithGenericJobs()}, "at least 1"},
+		{"no custom workers", []Option{WithCustomWorkers(nil)}, "no workers"},
+		{"workers mismatch", []Option{WithWorkers(3), WithCustomWorkers(spinWorkers(2, 0))}, "does not match"},
+		{"negative queue", []Option{WithJob(double), WithQueueSize(-1)}, "negative"},
+		{"caller runs custom", []Option{WithCustomWorkers(spinWorkers(2, 0)), WithCallerRunsPolicy()}, ErrCallerRunsCustomWorkers.Error()},
+		{"no burst workers", []Option{WithJob(double), WithBurstWorkers(0, time.Second)}, "burst workers 0"},
+		{"no burst duration", []Option{WithJob(double), WithBurstWorkers(1, 0)}, "burst duration"},
+		{"external and custom", []Option{WithExternalWorkers(), WithCustomWorkers(spinWorkers(2, 0))}, "combined with WithCustomWorkers"},
+		{"external and workers", []Option{WithJob(double), WithExternalWorkers(), WithWorkers(2)}, "combined with WithWorkers"},
+		{"external and queue", []Option{WithJob(double), WithExternalWorkers(), WithQueueSize(1)}, "combined with WithQueueSize"},
+		{"external and lazy", []Option{WithJob(double), WithExternalWorkers(), WithLazyStart()}, "combined with WithQueueSize"},
+		{"external and burst", []Option{WithJob(double), WithExternalWorkers(), WithBurstWorkers(1, time.Second)}, "combined with WithQueueSize"},
+		{"negative panic history", []Option{WithJob(double), WithPanicHistory(-1)}, "panic history size"},
+		{"nil pending marshal", []Option{WithJob(double), WithPendingCodec(nil, func([]byte) (interface{}, error) { return nil, nil })}, "pending codec"},
+		{"nil pending unmarshal", []Option{WithJob(double), WithPendingCodec(func(interface{}) ([]byte, error) { return nil, nil }, nil)}, "pending codec"},
+		{"zero poll interval", []Option{WithJob(double), WithReadyPollInterval(0, 0)}, "ready poll interval"},
+		{"negative poll jitter", []Option{WithJob(double), WithReadyPollInterval(time.Millisecond, -0.1)}, "ready poll jitter"},
+		{"poll jitter above 1", []Option{WithJob(double), WithReadyPollInterval(time.Millisecond, 2)}, "ready poll jitter"},
+		{"zero progress rate", []Option{WithJob(double), WithProgressRate(0)}, "progress rate"},
+	}
+	for _, test := range tests {
+		pool, err := func() (pool *WorkPool, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("panicked: %v", r)
+				}
+			}()
+			return NewPool(test.opts...)
+		}()
+		if pool != nil || !errors.Is(err, ErrInvalidPoolOptions) {
+			t.Errorf("%s: Expected ErrInvalidPoolOptions, got %v", test.name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: Expected the error to mention %q, got %v", test.name, test.want, err)
+		}
+	}
+
+	// Every problem is reported
+	_, err := NewPool(WithWorkers(1), WithCustomWorkers(spinWorkers(2, 0)), WithQueueSize(-1))
+	if err == nil || !strings.Contains(err.Error(), "does not match") || !strings.Contains(err.Error(), "negative") {
+		t.Errorf("Expected both problems to be reported, got %v", err)
+	}
+	if _, err := NewPool(WithCustomWorkers(spinWorkers(2, 0)), WithCallerRunsPolicy()); !errors.Is(err, ErrCallerRunsCustomWorkers) {
+		t.Errorf("Expected ErrCallerRunsCustomWorkers, got %v", err)
+	}
+}
+
+func TestNewPool(t *testing.T) {
+	double := func(in interface{}) interface{} { return in.(int) * 2 }
+
+	// A job pool with a queue, a timeout and a name
+	pool, err := NewPool(WithWorkers(3), WithJob(double), WithQueueSize(2), WithDefaultTimeout(time.Second), WithPoolName("doubler"))
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	if pool.NumWorkers() != 3 || pool.Timeout() != time.Second || pool.Name() != "doubler" || pool.bufDepth != 2 {
+		t.Errorf("Expected the options to be applied, got %v workers, %v, %q", pool.NumWorkers(), pool.Timeout(), pool.Name())
+	}
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to open pool: %v", err)
+		return
+	}
+	if result, err := pool.SendWork(21); err != nil || result != 42 {
+		t.Errorf("Expected 42, got %v, %v", result, err)
+	}
+	pool.Close()
+
+	// A generic pool sized by GOMAXPROCS which runs jobs on the caller when busy
+	pool, err = NewPool(WithGenericJobs(), WithCallerRunsPolicy())
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	if pool.NumWorkers() != runtime.GOMAXPROCS(0) {
+		t.Errorf("Expected %v workers, got %v", runtime.GOMAXPROCS(0), pool.NumWorkers())
+	}
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to open pool: %v", err)
+		return
+	}
+	if result, err := pool.SendWork(func() interface{} { return "generic" }); err != nil || result != "generic" {
+		t.Errorf("Expected generic, got %v, %v", result, err)
+	}
+	pool.Close()
+
+	// A custom pool with a matching worker count and lazily started workers
+	pool, err = NewPool(WithCustomWorkers(spinWorkers(2, 0)), WithWorkers(2), WithLazyStart(), WithMaxInFlight(4))
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to open pool: %v", err)
+		return
+	}
+	if result, err := pool.SendWork("custom"); err != nil || result != "custom" {
+		t.Errorf("Expected custom, got %v, %v", result, err)
+	}
+	pool.Close()
+
+	if pool.config != nil {
+		t.Errorf("Expected the construction settings to be dropped")
+	}
+
+	// The older constructors keep accepting what NewPool rejects
+	if pool := CreatePool(0, double); pool == nil || pool.NumWorkers() != 0 {
+		t.Errorf("Expected CreatePool to create a pool without workers")
+	}
+}
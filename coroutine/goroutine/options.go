@@ -0,0 +1,29 @@
+package goroutine
+
+/*
+Option - An optional setting applied to a pool when it is created, options are passed to NewPool
+or as the trailing arguments of CreatePool, CreatePoolGeneric and CreateCustomPool.
+*/
+type Option func(*WorkPool)
+
+/*
+WithLazyStart - Defers launching worker goroutines until work is submitted. Open marks the pool
+as running without starting any workers, and workers are then started one at a time whenever a
+job finds none of the started workers idle, up to NumWorkers.
+*/
+func WithLazyStart() Option {
+	return func(pool *WorkPool) {
+		pool.lazyStart = true
+	}
+}
+
+/*
+WithPoolName - Names the pool so that applications running several pools can tell which one
+reported a metric, event or log message, the name is available from Name().
+*/
+func WithPoolName(name string) Option {
+	return func(pool *WorkPool) {
+		pool.name = name
+		pool.logger.name = name
+	}
+}
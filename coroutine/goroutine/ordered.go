@@ -0,0 +1,108 @@
+package goroutine
+
+import (
+	"sync"
+)
+
+/*
+WithOrderedCallbacks - Calls the callbacks of SendWorkAsync and SendWorkTimedAsync strictly in
+the order the jobs were submitted, even though the jobs complete out of order on parallel workers.
+Completed results are held by the pool until every earlier job has had its callback called, at
+most bufferCap jobs may be submitted and awaiting their callback at once, beyond that submissions
+block until the oldest callback is released. Every async submission on such a pool is ordered, so
+ordered and plain async jobs are never mixed. A bufferCap below 1 is treated as 1.
+*/
+func WithOrderedCallbacks(bufferCap int) Option {
+	return func(pool *WorkPool) {
+		if bufferCap < 1 {
+			bufferCap = 1
+		}
+		ordered := &callbackOrderer{
+			bufferCap: uint64(bufferCap),
+			completed: make(map[uint64]orderedResult),
+		}
+		ordered.space = sync.NewCond(&ordered.mutex)
+		pool.ordered = ordered
+	}
+}
+
+type orderedResult struct {
+	after  func(interface{}, error)
+	result interface{}
+	err    error
+}
+
+/*
+callbackOrderer - The reorder buffer of a pool created WithOrderedCallbacks. All methods are safe
+to call on a nil orderer, which calls callbacks as soon as their job completes.
+*/
+type callbackOrderer struct {
+	mutex       sync.Mutex
+	space       *sync.Cond
+	bufferCap   uint64
+	nextSubmit  uint64
+	nextRelease uint64
+	releasing   bool
+	completed   map[uint64]orderedResult
+}
+
+/*
+reserve - Assigns the next submission sequence, blocking while the reorder buffer is full.
+*/
+func (ordered *callbackOrderer) reserve() uint64 {
+	if ordered == nil {
+		return 0
+	}
+
+	ordered.mutex.Lock()
+	defer ordered.mutex.Unlock()
+
+	for ordered.nextSubmit-ordered.nextRelease >= ordered.bufferCap {
+		ordered.space.Wait()
+	}
+	seq := ordered.nextSubmit
+	ordered.nextSubmit++
+	return seq
+}
+
+/*
+complete - Records the result of the job with sequence seq and calls every callback which is now
+in order. Callbacks are called one at a time by whichever goroutine completed the job they were
+waiting for.
+*/
+func (ordered *callbackOrderer) complete(seq uint64, after func(interface{}, error), result interface{}, err error) {
+	if ordered == nil {
+		if after != nil {
+			after(result, err)
+		}
+		return
+	}
+
+	ordered.mutex.Lock()
+	ordered.completed[seq] = orderedResult{after: after, result: result, err: err}
+	if ordered.releasing {
+		ordered.mutex.Unlock()
+		return
+	}
+	ordered.releasing = true
+
+	for {
+		next, ok := ordered.completed[ordered.nextRelease]
+		if !ok {
+			break
+		}
+		delete(ordered.completed, ordered.nextRelease)
+		ordered.mutex.Unlock()
+
+		if next.after != nil {
+			next.after(next.result, next.err)
+		}
+
+		ordered.mutex.Lock()
+		ordered.nextRelease++
+		ordered.space.Broadcast()
+	}
+
+	ordered.releasing = false
+	ordered.mutex.Unlock()
+}
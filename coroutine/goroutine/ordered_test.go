@@ -0,0 +1,86 @@
+package goroutine
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOrderedCallbacks(t *testing.T) {
+	pool, err := CreatePool(8, func(in interface{}) interface{} {
+		time.Sleep(time.Duration(rand.Intn(2000)) * time.Microsecond)
+		return in
+	}, WithOrderedCallbacks(16)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	numJobs := 500
+	wg := sync.WaitGroup{}
+	wg.Add(numJobs)
+
+	last := -1
+	for i := 0; i < numJobs; i++ {
+		err := pool.SendWorkAsync(i, func(result interface{}, err error) {
+			defer wg.Done()
+			if err != nil {
+				t.Errorf("Job failed: %v", err)
+				return
+			}
+			if seq := result.(int); seq != last+1 {
+				t.Errorf("Callback for job %v came after job %v", seq, last)
+			} else {
+				last = seq
+			}
+		})
+		if err != nil {
+			t.Errorf("Failed to send work: %v", err)
+		}
+	}
+	wg.Wait()
+
+	if last != numJobs-1 {
+		t.Errorf("Expected the last callback to be job %v, got %v", numJobs-1, last)
+	}
+}
+
+func TestOrderedCallbacksBlockWhenFull(t *testing.T) {
+	release := make(chan struct{})
+
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		if in == 0 {
+			<-release
+		}
+		return in
+	}, WithOrderedCallbacks(2)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	pool.SendWorkAsync(0, nil)
+	pool.SendWorkAsync(1, nil)
+
+	submitted := make(chan struct{})
+	go func() {
+		pool.SendWorkAsync(2, nil)
+		close(submitted)
+	}()
+
+	select {
+	case <-submitted:
+		t.Error("Expected the submission to block while the reorder buffer is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-submitted:
+	case <-time.After(time.Second):
+		t.Error("Submission did not resume once the buffer drained")
+	}
+}
@@ -0,0 +1,110 @@
+package goroutine
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultPanicHistorySize is the number of panics PanicHistory keeps unless set WithPanicHistory
+const DefaultPanicHistorySize = 100
+
+/*
+PanicRecord - A job which panicked, as kept by the pool for PanicHistory. WorkerIndex is -1 for a
+job run on the submitting goroutine, by a synchronous pool or WithCallerRunsPolicy.
+*/
+type PanicRecord struct {
+	WorkerIndex int
+	PanicValue  interface{}
+	StackTrace  string
+	OccurredAt  time.Time
+	Input       interface{}
+}
+
+/*
+WithPanicHistory - Sets how many of the most recent panics the pool keeps for PanicHistory, the
+oldest being dropped first, DefaultPanicHistorySize by default. A size of 0 keeps none. The
+inputs of the jobs are kept along with them, so a pool whose payloads are large may want less.
+*/
+func WithPanicHistory(size int) Option {
+	return func(pool *WorkPool) {
+		if size < 0 {
+			pool.config.fail("panic history size %d is negative", size)
+			return
+		}
+		pool.panics = newPanicHistory(size)
+	}
+}
+
+/*
+PanicHistory - Returns the panics recovered from jobs of the pool, oldest first, up to the size set
+WithPanicHistory. The records are a copy, taken without holding up the workers.
+*/
+func (pool *WorkPool) PanicHistory() []PanicRecord {
+	return pool.panics.snapshot()
+}
+
+// record describes the panic of a job for PanicHistory
+func (p *jobPanic) record(worker int, input interface{}, at time.Time) PanicRecord {
+	return PanicRecord{
+		WorkerIndex: worker,
+		PanicValue:  p.value,
+		StackTrace:  string(p.stack),
+		OccurredAt:  at,
+		Input:       input,
+	}
+}
+
+/*
+panicHistory - A ring buffer of the most recent panics, a nil history keeps nothing.
+*/
+type panicHistory struct {
+	mutex   sync.Mutex
+	records []PanicRecord
+	next    int
+	full    bool
+}
+
+func newPanicHistory(size int) *panicHistory {
+	if size == 0 {
+		return nil
+	}
+	return &panicHistory{records: make([]PanicRecord, size)}
+}
+
+/*
+add - Records a panic, overwriting the oldest once the history is full.
+*/
+func (history *panicHistory) add(record PanicRecord) {
+	if history == nil {
+		return
+	}
+
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+
+	history.records[history.next] = record
+	history.next++
+	if history.next == len(history.records) {
+		history.next = 0
+		history.full = true
+	}
+}
+
+/*
+snapshot - Copies the panics recorded, oldest first.
+*/
+func (history *panicHistory) snapshot() []PanicRecord {
+	if history == nil {
+		return nil
+	}
+
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+
+	if !history.full {
+		return append([]PanicRecord(nil), history.records[:history.next]...)
+	}
+	records := make([]PanicRecord, 0, len(history.records))
+	records = append(records, history.records[history.next:]...)
+	return append(records, history.records[:history.next]...)
+}
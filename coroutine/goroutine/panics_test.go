@@ -0,0 +1,61 @@
+package goroutine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPanicHistory(t *testing.T) {
+	pool, err := NewPool(WithWorkers(2), WithPanicHistory(3), WithJob(func(in interface{}) interface{} {
+		if n := in.(int); n%2 == 1 {
+			panic(n)
+		}
+		return in
+	}))
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to open pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	for i := 0; i < 10; i++ {
+		pool.SendWork(i)
+	}
+
+	history := pool.PanicHistory()
+	if len(history) != 3 {
+		t.Fatalf("Expected the last 3 panics, got %v", len(history))
+	}
+	for i, record := range history {
+		expected := 5 + 2*i
+		if record.Input != expected || record.PanicValue != expected {
+			t.Errorf("Expected a panic of job %v, got %v with %v", expected, record.Input, record.PanicValue)
+		}
+		if record.WorkerIndex < 0 || record.WorkerIndex > 1 {
+			t.Errorf("Expected the index of a worker, got %v", record.WorkerIndex)
+		}
+		if !strings.Contains(record.StackTrace, "panics_test.go") || record.OccurredAt.IsZero() {
+			t.Errorf("Expected the stack and time of the panic, got %+v", record)
+		}
+	}
+
+	// the copy is not changed by later panics
+	pool.SendWork(11)
+	if history[2].Input != 9 || pool.PanicHistory()[2].Input != 11 {
+		t.Errorf("Expected a snapshot of the history")
+	}
+
+	disabled, _ := NewPool(WithWorkers(1), WithPanicHistory(0), WithJob(func(interface{}) interface{} {
+		panic("job panicked")
+	}))
+	disabled.Open()
+	defer disabled.Close()
+	disabled.SendWork(nil)
+	if len(disabled.PanicHistory()) != 0 {
+		t.Errorf("Expected no history to be kept")
+	}
+}
@@ -0,0 +1,275 @@
+package goroutine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrJobDrained     = errors.New("job was drained from the pending queue")
+	ErrNoPendingCodec = errors.New("the pool has no pending codec")
+
+	// errJobParked is returned by a worker given a job after Quiesce, the job stays pending
+	errJobParked = errors.New("job parked by quiesce")
+)
+
+/*
+PendingJob - A job of SendWorkAsync which had not started when it was drained with DrainPending,
+ready to be persisted by the caller and handed to RestorePending of another pool. Payload is the
+job's payload as encoded by the marshal function set WithPendingCodec, and Labels and Priority are
+those it was sent with as a LabeledJob. The pool does not order jobs by Priority, it is kept for
+the caller, for example to restore the most important jobs first.
+*/
+type PendingJob struct {
+	Payload  []byte
+	Labels   map[string]string
+	Priority int
+}
+
+/*
+LabeledJob - A payload for SendWorkAsync carrying labels and a priority alongside the job, which are
+kept with the job if it is drained with DrainPending and reported by PendingJobs and RunningJobs,
+along with what Describe returns for Data if it is set. The worker is given Data, and the labels
+are simply dropped by a pool without a pending codec or job inspection.
+*/
+type LabeledJob struct {
+	Data     interface{}
+	Labels   map[string]string
+	Priority int
+	Describe func(jobData interface{}) string
+}
+
+/*
+WithPendingCodec - Keeps track of the jobs sent with SendWorkAsync which have not yet started, so
+that they can be drained with DrainPending once the pool is quiesced, for example to carry them
+over a restart. marshal encodes the payload of a drained job and unmarshal decodes it again for
+RestorePending. The pool does no I/O itself, persisting the encoded jobs is up to the caller. A
+payload which marshal cannot encode is not drained, its job fails with the error of marshal.
+*/
+func WithPendingCodec(marshal func(interface{}) ([]byte, error), unmarshal func([]byte) (interface{}, error)) Option {
+	return func(pool *WorkPool) {
+		if marshal == nil || unmarshal == nil {
+			pool.config.fail("pending codec needs both a marshal and an unmarshal function")
+			return
+		}
+		pool.pending = &pendingQueue{
+			marshal:   marshal,
+			unmarshal: unmarshal,
+			entries:   map[uint64]*pendingEntry{},
+		}
+	}
+}
+
+/*
+Quiesce - Stops the pool starting any more of the jobs sent with SendWorkAsync, for a pool created
+WithPendingCodec, ahead of draining them with DrainPending. Jobs already running carry on, and jobs
+sent afterwards are held as well. The pool cannot be resumed, the jobs still pending when it is
+closed without being drained fail with ErrPoolNotRunning.
+*/
+func (pool *WorkPool) Quiesce() error {
+	if pool.pending == nil {
+		return ErrNoPendingCodec
+	}
+	if !pool.isRunning() {
+		return ErrPoolNotRunning
+	}
+	pool.pending.quiesce()
+	return nil
+}
+
+/*
+DrainPending - Removes every job sent with SendWorkAsync which has not started from a quiesced
+pool and returns them in the order they were sent, to be persisted by the caller. A job is either
+run or drained, never both: the callbacks of drained jobs are called with ErrJobDrained. Returns nil
+if the pool was not quiesced.
+*/
+func (pool *WorkPool) DrainPending() []PendingJob {
+	if pool.pending == nil {
+		return nil
+	}
+	return pool.pending.drain()
+}
+
+/*
+RestorePending - Sends jobs drained from a pool with DrainPending to this pool with SendWorkAsync,
+in the order given and without callbacks, call it after Open and before the pool is given any new
+work so that the restored jobs keep their place. The jobs are decoded with the unmarshal function
+set WithPendingCodec, and if any of them cannot be decoded none are sent.
+*/
+func (pool *WorkPool) RestorePending(jobs []PendingJob) error {
+	if pool.pending == nil {
+		return ErrNoPendingCodec
+	}
+	if !pool.isRunning() {
+		return ErrPoolNotRunning
+	}
+
+	payloads := make([]interface{}, len(jobs))
+	for i, job := range jobs {
+		data, err := pool.pending.unmarshal(job.Payload)
+		if err != nil {
+			return fmt.Errorf("decoding pending job %d: %w", i, err)
+		}
+		payloads[i] = data
+		if job.Labels != nil || job.Priority != 0 {
+			payloads[i] = LabeledJob{Data: data, Labels: job.Labels, Priority: job.Priority}
+		}
+	}
+	for i, data := range payloads {
+		if err := pool.SendWorkAsync(data, nil); err != nil {
+			return fmt.Errorf("restoring pending job %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+/*
+pendingJob - A job of SendWorkAsync on a pool with a pending codec, the entry is unwrapped by
+newJob.
+*/
+type pendingJob struct {
+	data  interface{}
+	entry *pendingEntry
+}
+
+const (
+	pendingWaiting = iota
+	pendingStarted
+	pendingDrained
+)
+
+/*
+pendingEntry - A job of SendWorkAsync tracked until it has either started or been drained.
+*/
+type pendingEntry struct {
+	queue    *pendingQueue
+	id       uint64
+	job      LabeledJob
+	state    int
+	drained  chan struct{}
+	drainErr error
+}
+
+/*
+pendingQueue - The jobs of SendWorkAsync which have not started, the mutex makes starting and
+draining a job mutually exclusive.
+*/
+type pendingQueue struct {
+	marshal   func(interface{}) ([]byte, error)
+	unmarshal func([]byte) (interface{}, error)
+
+	mutex    sync.Mutex
+	quiesced bool
+	nextID   uint64
+	entries  map[uint64]*pendingEntry
+}
+
+func (queue *pendingQueue) add(job LabeledJob) *pendingEntry {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.nextID++
+	entry := &pendingEntry{queue: queue, id: queue.nextID, job: job, drained: make(chan struct{})}
+	queue.entries[entry.id] = entry
+	return entry
+}
+
+func (queue *pendingQueue) remove(entry *pendingEntry) {
+	queue.mutex.Lock()
+	delete(queue.entries, entry.id)
+	queue.mutex.Unlock()
+}
+
+func (queue *pendingQueue) quiesce() {
+	queue.mutex.Lock()
+	queue.quiesced = true
+	queue.mutex.Unlock()
+}
+
+func (queue *pendingQueue) drain() []PendingJob {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if !queue.quiesced {
+		return nil
+	}
+
+	ids := make([]uint64, 0, len(queue.entries))
+	for id, entry := range queue.entries {
+		if entry.state == pendingWaiting {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	jobs := make([]PendingJob, 0, len(ids))
+	for _, id := range ids {
+		entry := queue.entries[id]
+		delete(queue.entries, id)
+		entry.state = pendingDrained
+		entry.drainErr = ErrJobDrained
+
+		payload, err := queue.marshal(entry.job.Data)
+		if err != nil {
+			entry.drainErr = fmt.Errorf("encoding pending job: %w", err)
+		} else {
+			jobs = append(jobs, PendingJob{Payload: payload, Labels: entry.job.Labels, Priority: entry.job.Priority})
+		}
+		close(entry.drained)
+	}
+	return jobs
+}
+
+/*
+start - Called by the worker given the job, returns nil if the job may run. A drained job returns
+the error it was drained with, and a job reaching a worker after Quiesce returns errJobParked and
+stays pending. A job without an entry always runs.
+*/
+func (entry *pendingEntry) start() error {
+	if entry == nil {
+		return nil
+	}
+
+	entry.queue.mutex.Lock()
+	defer entry.queue.mutex.Unlock()
+
+	switch {
+	case entry.state == pendingDrained:
+		return entry.drainErr
+	case entry.queue.quiesced:
+		return errJobParked
+	}
+	entry.state = pendingStarted
+	return nil
+}
+
+/*
+send - Wraps the send of a tracked job, a job parked by Quiesce waits until it is drained or the
+pool closes.
+*/
+func (entry *pendingEntry) send(pool *WorkPool, send func() (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		result, err := send()
+		if err != errJobParked {
+			entry.queue.remove(entry)
+			return result, err
+		}
+
+		select {
+		case <-entry.drained:
+			return nil, entry.drainErr
+		case <-pool.closingChan():
+		}
+
+		// The pool may close just as the job is drained, in which case it has been handed over
+		entry.queue.mutex.Lock()
+		defer entry.queue.mutex.Unlock()
+		if entry.state == pendingDrained {
+			return nil, entry.drainErr
+		}
+		delete(entry.queue.entries, entry.id)
+		return nil, ErrPoolNotRunning
+	}
+}
@@ -0,0 +1,165 @@
+package goroutine
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// intCodec encodes int payloads only
+func intCodec() Option {
+	return WithPendingCodec(func(in interface{}) ([]byte, error) {
+		n, ok := in.(int)
+		if !ok {
+			return nil, errors.New("not an int")
+		}
+		return []byte(strconv.Itoa(n)), nil
+	}, func(payload []byte) (interface{}, error) {
+		return strconv.Atoi(string(payload))
+	})
+}
+
+// waitForPending waits until the pool has no async jobs pending
+func waitForPending(t *testing.T, pool *WorkPool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.NumPendingAsyncJobs() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %v async jobs", pool.NumPendingAsyncJobs())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDrainPending(t *testing.T) {
+	var mutex sync.Mutex
+	executed := map[int]int{}
+	job := func(in interface{}) interface{} {
+		time.Sleep(time.Millisecond)
+		mutex.Lock()
+		executed[in.(int)]++
+		mutex.Unlock()
+		return in
+	}
+
+	pool, err := CreatePool(2, job, intCodec()).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	const total = 100
+	var drainedMutex sync.Mutex
+	drained := 0
+	send := func(data interface{}) {
+		err := pool.SendWorkAsync(data, func(result interface{}, err error) {
+			if errors.Is(err, ErrJobDrained) {
+				drainedMutex.Lock()
+				drained++
+				drainedMutex.Unlock()
+			} else if err != nil {
+				t.Errorf("Job failed: %v", err)
+			}
+		})
+		if err != nil {
+			t.Errorf("Failed to send job: %v", err)
+		}
+	}
+	for i := 0; i < total-1; i++ {
+		send(i)
+	}
+
+	// Quiesce mid-stream, a job sent afterwards is held
+	time.Sleep(10 * time.Millisecond)
+	if err := pool.Quiesce(); err != nil {
+		t.Errorf("Failed to quiesce: %v", err)
+	}
+	send(LabeledJob{Data: total - 1, Labels: map[string]string{"tenant": "a"}, Priority: 7})
+	time.Sleep(time.Millisecond)
+
+	pending := pool.DrainPending()
+	if len(pending) == 0 || len(pending) == total {
+		t.Fatalf("Expected some jobs to be drained mid-stream, got %v", len(pending))
+	}
+	last := pending[len(pending)-1]
+	if last.Labels["tenant"] != "a" || last.Priority != 7 {
+		t.Errorf("Labels of the drained job were lost: %+v", last)
+	}
+
+	waitForPending(t, pool)
+	pool.Close()
+
+	drainedMutex.Lock()
+	if drained != len(pending) {
+		t.Errorf("Expected %v callbacks with ErrJobDrained, got %v", len(pending), drained)
+	}
+	drainedMutex.Unlock()
+
+	restored, err := CreatePool(2, job, intCodec()).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	if err := restored.RestorePending(pending); err != nil {
+		t.Errorf("Failed to restore: %v", err)
+	}
+	waitForPending(t, restored)
+	restored.Close()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	for i := 0; i < total; i++ {
+		if executed[i] != 1 {
+			t.Errorf("Job %v executed %v times", i, executed[i])
+		}
+	}
+}
+
+func TestDrainPendingUnencodable(t *testing.T) {
+	release := make(chan struct{})
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		<-release
+		return in
+	}, intCodec()).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if pool.DrainPending() != nil {
+		t.Errorf("Expected nothing drained from a pool which is not quiesced")
+	}
+
+	// The first job holds the worker, the second cannot be encoded
+	pool.SendWorkAsync(1, nil)
+	time.Sleep(5 * time.Millisecond)
+	errs := make(chan error, 1)
+	pool.SendWorkAsync("two", func(result interface{}, err error) {
+		errs <- err
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	pool.Quiesce()
+	if pending := pool.DrainPending(); len(pending) != 0 {
+		t.Errorf("Expected no encodable jobs, got %v", len(pending))
+	}
+	close(release)
+
+	select {
+	case err := <-errs:
+		if err == nil || errors.Is(err, ErrJobDrained) {
+			t.Errorf("Expected the error of marshal, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for the unencodable job")
+	}
+
+	if err := pool.RestorePending([]PendingJob{{Payload: []byte("x")}}); err == nil {
+		t.Errorf("Expected an error restoring a payload which cannot be decoded")
+	}
+	if err := CreatePool(1, func(in interface{}) interface{} { return in }).Quiesce(); !errors.Is(err, ErrNoPendingCodec) {
+		t.Errorf("Expected ErrNoPendingCodec, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package goroutine
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+/*
+CreatePoolPerCPU - Creates a pool with one worker per GOMAXPROCS for CPU bound jobs. The number of
+workers is evaluated each time the pool is opened, so after changing GOMAXPROCS close and reopen
+the pool to adjust it, NumWorkers reports the size from the last Open. Combine with
+WithLockOSThread for jobs which depend on the OS thread, such as cgo calls.
+*/
+func CreatePoolPerCPU(job func(interface{}) interface{}, opts ...Option) *WorkPool {
+	pool := CreatePool(runtime.GOMAXPROCS(0), job, opts...)
+	pool.perCPUJob = job
+	return pool
+}
+
+/*
+WithLockOSThread - Locks the goroutine of each worker to its own OS thread for as long as the
+worker runs, so that every job of a worker runs on the same thread.
+*/
+func WithLockOSThread() Option {
+	return func(pool *WorkPool) {
+		pool.lockOSThread = true
+	}
+}
+
+/*
+resizePerCPU - Adds or removes workers of a per CPU pool to match GOMAXPROCS, called by Open while
+the pool is closed.
+*/
+func (pool *WorkPool) resizePerCPU() {
+	if pool.perCPUJob == nil {
+		return
+	}
+
+	// Workers stopped with StopWorker are dropped, the pool is sized from those left. The new
+	// slice is built aside as the current one may be being read without the lock
+	numWorkers := runtime.GOMAXPROCS(0)
+	live := make([]*workerWrapper, 0, numWorkers)
+	for _, workerWrapper := range pool.workers {
+		if atomic.LoadUint32(&workerWrapper.stopped) == 0 && len(live) < numWorkers {
+			live = append(live, workerWrapper)
+		}
+	}
+	for len(live) < numWorkers {
+		live = append(live, &workerWrapper{
+			worker: &(defaultWorker{&pool.perCPUJob}),
+		})
+	}
+	pool.setWorkers(live)
+	atomic.StoreInt32(&pool.stoppedWorkers, 0)
+	atomic.StoreInt32(&pool.stoppingWorkers, 0)
+}
@@ -0,0 +1,66 @@
+package goroutine
+
+import (
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type threadWorker struct {
+	mutex   sync.Mutex
+	threads map[int]bool
+}
+
+func (worker *threadWorker) Job(in interface{}) interface{} {
+	for i := 0; i < 5; i++ {
+		worker.mutex.Lock()
+		worker.threads[syscall.Gettid()] = true
+		worker.mutex.Unlock()
+
+		// A blocking syscall hands the P to another thread, the goroutine may then resume on
+		// a different thread unless it is locked to its own
+		pause := syscall.NsecToTimespec(int64(100 * time.Microsecond))
+		syscall.Nanosleep(&pause, nil)
+		runtime.Gosched()
+	}
+	return nil
+}
+
+func (worker *threadWorker) Ready() bool {
+	return true
+}
+
+func TestLockOSThread(t *testing.T) {
+	workers := make([]GoroutineWorker, 4)
+	for i := range workers {
+		workers[i] = &threadWorker{threads: map[int]bool{}}
+	}
+
+	pool, err := CreateCustomPool(workers, WithLockOSThread()).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				pool.SendWork(nil)
+			}
+		}()
+	}
+	wg.Wait()
+	pool.Close()
+
+	for i, worker := range workers {
+		threads := worker.(*threadWorker).threads
+		if len(threads) > 1 {
+			t.Errorf("Worker %v ran on %v OS threads", i, len(threads))
+		}
+	}
+}
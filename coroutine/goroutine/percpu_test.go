@@ -0,0 +1,38 @@
+package goroutine
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestCreatePoolPerCPU(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
+
+	pool := CreatePoolPerCPU(func(in interface{}) interface{} {
+		return in
+	})
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	if pool.NumWorkers() != 2 {
+		t.Errorf("Expected 2 workers, got %v", pool.NumWorkers())
+	}
+	pool.Close()
+
+	// The size is re-evaluated when the pool is reopened
+	for _, procs := range []int{3, 1} {
+		runtime.GOMAXPROCS(procs)
+		if _, err := pool.Open(); err != nil {
+			t.Errorf("Failed to reopen pool: %v", err)
+			return
+		}
+		if pool.NumWorkers() != procs {
+			t.Errorf("Expected %v workers, got %v", procs, pool.NumWorkers())
+		}
+		if result, err := pool.SendWork(procs); err != nil || result != procs {
+			t.Errorf("Unexpected result: %v, %v", result, err)
+		}
+		pool.Close()
+	}
+}
@@ -0,0 +1,120 @@
+package goroutine
+
+import (
+	"fmt"
+	"sync"
+)
+
+/*
+StageError - Returned by a Pipeline when a stage fails, Stage is the index of the failing stage
+within the pipeline and Err is either the error returned by the pool or the error the stage's
+job returned as its result.
+*/
+type StageError struct {
+	Stage int
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("pipeline stage %d: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+/*
+Pipeline - Chains pools together so that the result of each stage is the job of the next, each
+stage runs with the concurrency of its own pool.
+*/
+type Pipeline struct {
+	stages []*WorkPool
+}
+
+/*
+ChainPools - Creates a pipeline which runs each job through the given pools in order. The pools
+must be opened before sending work through the pipeline.
+*/
+func ChainPools(stages ...*WorkPool) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+/*
+Send - Run a job through every stage of the pipeline and return the result of the last stage,
+this is a synchronous call. If a stage fails, either because the pool returned an error or the
+job returned an error as its result, the remaining stages are skipped and a *StageError is
+returned.
+*/
+func (p *Pipeline) Send(jobData interface{}) (interface{}, error) {
+	result := jobData
+	for i, stage := range p.stages {
+		var err error
+		if result, err = stage.SendWork(result); err != nil {
+			return nil, &StageError{Stage: i, Err: err}
+		}
+		if err, ok := result.(error); ok {
+			return nil, &StageError{Stage: i, Err: err}
+		}
+	}
+	return result, nil
+}
+
+/*
+SendBatch - Run every job through the pipeline concurrently and return the results in the order
+of the jobs. Every job runs to completion and, if any failed, a *BatchError is returned alongside
+the results recording the *StageError of each failed job, whose result is nil.
+*/
+func (p *Pipeline) SendBatch(jobs []interface{}) ([]interface{}, error) {
+	results := make([]interface{}, len(jobs))
+	batchErr := newBatchError(len(jobs))
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for i, jobData := range jobs {
+		wg.Add(1)
+		go func(i int, jobData interface{}) {
+			defer wg.Done()
+			result, err := p.Send(jobData)
+			if err != nil {
+				mutex.Lock()
+				batchErr.failed[i] = err
+				mutex.Unlock()
+				return
+			}
+			results[i] = result
+		}(i, jobData)
+	}
+	wg.Wait()
+
+	return results, batchErr.errOrNil()
+}
+
+/*
+SendAsync - Run a job through the pipeline without blocking, and optionally send the result to
+a receiving closure. You may set the closure to nil if no further actions are required.
+*/
+func (p *Pipeline) SendAsync(jobData interface{}, after func(interface{}, error)) {
+	go func() {
+		result, err := p.Send(jobData)
+		if after != nil {
+			after(result, err)
+		}
+	}()
+}
+
+/*
+Close - Close every stage of the pipeline, starting from the last stage so that a stage is only
+closed once every stage after it is, and no stage is left running with nothing downstream to take
+its results. Jobs in flight are not drained: a job which finishes an earlier stage after a later
+one was closed fails with a *StageError for the closed stage, so wait for Send calls to return
+before closing to avoid that. The first error encountered is returned after all stages are closed.
+*/
+func (p *Pipeline) Close() error {
+	var firstErr error
+	for i := len(p.stages) - 1; i >= 0; i-- {
+		if err := p.stages[i].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
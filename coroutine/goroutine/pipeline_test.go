@@ -0,0 +1,141 @@
+package goroutine
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	errOdd := errors.New("odd input")
+	var stage3Calls int32
+
+	decode, _ := CreatePool(1, func(in interface{}) interface{} {
+		return in.(int) + 1
+	}).Open()
+	enrich, _ := CreatePool(3, func(in interface{}) interface{} {
+		if in.(int)%2 != 0 {
+			return errOdd
+		}
+		return in.(int) * 10
+	}).Open()
+	store, _ := CreatePool(5, func(in interface{}) interface{} {
+		atomic.AddInt32(&stage3Calls, 1)
+		return in.(int) - 1
+	}).Open()
+
+	pipeline := ChainPools(decode, enrich, store)
+	defer pipeline.Close()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := pipeline.Send(i)
+			if i%2 == 0 {
+				var stageErr *StageError
+				if !errors.As(err, &stageErr) || stageErr.Stage != 1 || !errors.Is(err, errOdd) {
+					t.Errorf("Expected stage 1 to fail for %v, got %v", i, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Failed to send work: %v", err)
+			} else if expected := (i+1)*10 - 1; result != expected {
+				t.Errorf("Wrong return value: %v != %v", expected, result)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if stage3Calls != 10 {
+		t.Errorf("Expected stage 3 to run only for successful jobs, ran %v times", stage3Calls)
+	}
+
+	done := make(chan struct{})
+	pipeline.SendAsync(1, func(result interface{}, err error) {
+		if err != nil || result != 19 {
+			t.Errorf("Unexpected async result: %v, %v", result, err)
+		}
+		close(done)
+	})
+	<-done
+}
+
+func TestPipelineSendBatch(t *testing.T) {
+	errOdd := errors.New("odd input")
+	double, _ := CreatePool(2, func(in interface{}) interface{} {
+		return in.(int) * 2
+	}).Open()
+	check, _ := CreatePool(2, func(in interface{}) interface{} {
+		if in.(int)%4 != 0 {
+			return errOdd
+		}
+		return in
+	}).Open()
+
+	pipeline := ChainPools(double, check)
+	defer pipeline.Close()
+
+	results, err := pipeline.SendBatch([]interface{}{2, 3, 4, 5})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || !errors.Is(err, errOdd) {
+		t.Fatalf("Expected a *BatchError matching errOdd, got %v", err)
+	}
+	for _, i := range []int{1, 3} {
+		var stageErr *StageError
+		if !errors.As(batchErr.Failed()[i], &stageErr) || stageErr.Stage != 1 {
+			t.Errorf("Expected item %v to fail in stage 1, got %v", i, batchErr.Failed()[i])
+		}
+	}
+	if results[0] != 4 || results[1] != nil || results[2] != 8 || results[3] != nil {
+		t.Errorf("Expected [4 <nil> 8 <nil>], got %v", results)
+	}
+}
+
+// Extended worker which records the stage it belongs to once it is terminated
+type stageExtWorker struct {
+	countingExtWorker
+
+	stage      int
+	mutex      *sync.Mutex
+	terminated *[]int
+}
+
+func (w *stageExtWorker) Terminate() {
+	w.mutex.Lock()
+	*w.terminated = append(*w.terminated, w.stage)
+	w.mutex.Unlock()
+}
+
+func TestPipelineCloseOrder(t *testing.T) {
+	var mutex sync.Mutex
+	var terminated []int
+
+	stages := make([]*WorkPool, 3)
+	for i := range stages {
+		stages[i], _ = CreateCustomPool([]GoroutineWorker{
+			&stageExtWorker{stage: i, mutex: &mutex, terminated: &terminated},
+		}).Open()
+	}
+	first := stages[0]
+
+	if err := ChainPools(stages...).Close(); err != nil {
+		t.Errorf("Failed to close pipeline: %v", err)
+	}
+	mutex.Lock()
+	if len(terminated) != 3 || terminated[0] != 2 || terminated[1] != 1 || terminated[2] != 0 {
+		t.Errorf("Expected the stages to close from the last, got %v", terminated)
+	}
+	mutex.Unlock()
+	if _, err := first.SendWork(func() {}); err != ErrPoolNotRunning {
+		t.Errorf("Expected first stage to be closed, got %v", err)
+	}
+
+	var stageErr *StageError
+	if _, err := ChainPools(first).Send(nil); !errors.As(err, &stageErr) || stageErr.Err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning from closed stage, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package goroutine
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultReadyPollInterval is how often a worker which is not ready is asked again by default
+const DefaultReadyPollInterval = 5 * time.Millisecond
+
+/*
+WithReadyPollInterval - Sets how often the workers which are not ready are asked again, every
+DefaultReadyPollInterval by default. Each interval is stretched by a random factor of up to
+jitter, between 0 and 1, so that pools polling the same resource drift apart. The workers of a
+pool waiting to be ready share a single timer, so a pool of many workers reconnecting at once
+wakes the scheduler once per interval rather than once per worker, and the timer is stopped while
+no worker is waiting. Closing the pool breaks the workers out straight away.
+*/
+func WithReadyPollInterval(interval time.Duration, jitter float64) Option {
+	return func(pool *WorkPool) {
+		if interval <= 0 {
+			pool.config.fail("ready poll interval %v must be positive", interval)
+			return
+		}
+		if jitter < 0 || jitter > 1 {
+			pool.config.fail("ready poll jitter %v must be between 0 and 1", jitter)
+			return
+		}
+		pool.readyTicker = &readyTicker{interval: interval, jitter: jitter}
+	}
+}
+
+/*
+readyTicker - A timer shared by the workers of a pool while they wait to be ready. A goroutine
+ticks while at least one worker is waiting, closing the channel the workers wait on at each tick.
+*/
+type readyTicker struct {
+	interval time.Duration
+	jitter   float64
+	clock    Clock
+
+	mutex   sync.Mutex
+	waiters int
+	tick    chan struct{}
+	stop    chan struct{}
+
+	// wakeups counts the ticks, for tests
+	wakeups uint64
+}
+
+func newReadyTicker() *readyTicker {
+	return &readyTicker{interval: DefaultReadyPollInterval}
+}
+
+/*
+wait - Registers a waiting worker and returns the channel closed on the next tick, the worker
+must call done once it stops waiting.
+*/
+func (ticker *readyTicker) wait() <-chan struct{} {
+	ticker.mutex.Lock()
+	defer ticker.mutex.Unlock()
+
+	ticker.waiters++
+	if ticker.tick == nil {
+		ticker.tick = make(chan struct{})
+		ticker.stop = make(chan struct{})
+		go ticker.run(ticker.stop)
+	}
+	return ticker.tick
+}
+
+/*
+done - Unregisters a waiting worker, stopping the ticks once no worker is waiting.
+*/
+func (ticker *readyTicker) done() {
+	ticker.mutex.Lock()
+	defer ticker.mutex.Unlock()
+
+	ticker.waiters--
+	if ticker.waiters == 0 {
+		close(ticker.stop)
+		ticker.tick, ticker.stop = nil, nil
+	}
+}
+
+// run ticks until stop is closed
+func (ticker *readyTicker) run(stop chan struct{}) {
+	for {
+		timer := ticker.clock.NewTimer(ticker.next())
+		select {
+		case <-timer.C():
+		case <-stop:
+			timer.Stop()
+			return
+		}
+
+		ticker.mutex.Lock()
+		if ticker.stop != stop {
+			ticker.mutex.Unlock()
+			return
+		}
+		atomic.AddUint64(&ticker.wakeups, 1)
+		close(ticker.tick)
+		ticker.tick = make(chan struct{})
+		ticker.mutex.Unlock()
+	}
+}
+
+// next returns the interval until the next tick with its jitter
+func (ticker *readyTicker) next() time.Duration {
+	if ticker.jitter <= 0 {
+		return ticker.interval
+	}
+	return time.Duration(float64(ticker.interval) * (1 + rand.Float64()*ticker.jitter))
+}
@@ -0,0 +1,64 @@
+package goroutine
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// neverReadyWorker is never ready, counting how often it is asked
+type neverReadyWorker struct {
+	polls *int64
+}
+
+func (w neverReadyWorker) Job(in interface{}) interface{} { return in }
+
+func (w neverReadyWorker) Ready() bool {
+	atomic.AddInt64(w.polls, 1)
+	return false
+}
+
+func TestReadyPollInterval(t *testing.T) {
+	var polls int64
+	workers := make([]GoroutineWorker, 200)
+	for i := range workers {
+		workers[i] = neverReadyWorker{&polls}
+	}
+
+	pool, err := NewPool(WithCustomWorkers(workers), WithReadyPollInterval(5*time.Millisecond, 0.2))
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to open pool: %v", err)
+		return
+	}
+
+	elapsed := 100 * time.Millisecond
+	time.Sleep(elapsed)
+
+	// one wakeup per interval of at least 5ms, however many workers wait
+	if wakeups := atomic.LoadUint64(&pool.readyTicker.wakeups); wakeups == 0 || wakeups > uint64(elapsed/(5*time.Millisecond))+1 {
+		t.Errorf("Expected at most %v wakeups, got %v", elapsed/(5*time.Millisecond)+1, wakeups)
+	}
+	if atomic.LoadInt64(&polls) < int64(len(workers))*2 {
+		t.Errorf("Expected every worker to be polled again on the shared ticks, got %v polls", polls)
+	}
+
+	start := time.Now()
+	pool.Close()
+	if waited := time.Since(start); waited > 100*time.Millisecond {
+		t.Errorf("Expected close to break the workers out promptly, took %v", waited)
+	}
+
+	pool.readyTicker.mutex.Lock()
+	defer pool.readyTicker.mutex.Unlock()
+	if pool.readyTicker.waiters != 0 || pool.readyTicker.tick != nil {
+		t.Errorf("Expected the ticker to stop once no worker waits")
+	}
+
+	if _, err := NewPool(WithCustomWorkers(workers), WithReadyPollInterval(0, 0)); err == nil {
+		t.Errorf("Expected an error for a zero interval")
+	}
+}
@@ -0,0 +1,130 @@
+package goroutine
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultProgressRate is how many progress reports a second are passed on unless set WithProgressRate
+const DefaultProgressRate = 10
+
+/*
+GoroutineProgressWorker - An optional interface that can be implemented by workers running long
+jobs which can tell how far along they are. JobProgress is then called for each job in place of
+Job, and the job calls report as it goes with the units of work done out of total. Reports reach
+the submitter of a job sent with SendWorkAsyncProgress, for other jobs report does nothing.
+*/
+type GoroutineProgressWorker interface {
+
+	// Called for each job, expects the result to be returned synchronously
+	JobProgress(in interface{}, report func(done, total int)) interface{}
+}
+
+/*
+WithProgressRate - Sets how many progress reports a second are passed on to the onProgress
+callback of each job sent with SendWorkAsyncProgress, DefaultProgressRate by default. Reports
+arriving sooner after the last one passed on are dropped.
+*/
+func WithProgressRate(perSecond int) Option {
+	return func(pool *WorkPool) {
+		if perSecond <= 0 {
+			pool.config.fail("progress rate %d must be at least 1", perSecond)
+			return
+		}
+		pool.progressInterval = time.Second / time.Duration(perSecond)
+	}
+}
+
+/*
+SendWorkAsyncProgress - Send a job to a worker without blocking as SendWorkAsync does, passing
+the progress reported by a worker implementing GoroutineProgressWorker on to onProgress, at most
+at the rate set WithProgressRate. onProgress is called on the worker's goroutine and never once
+the result has been delivered, so it is not called at all for a job which does not report and its
+last call comes before that of after. An error is returned if the job is rejected at submission.
+*/
+func (pool *WorkPool) SendWorkAsyncProgress(
+	jobData interface{},
+	onProgress func(done, total int),
+	after func(interface{}, error),
+) error {
+	if onProgress == nil {
+		return errors.New("progress callback is nil")
+	}
+
+	jobData = pool.clonePayload(jobData)
+	ticket, err := pool.admit(jobData)
+	if err != nil {
+		return err
+	}
+
+	interval := pool.progressInterval
+	if interval <= 0 {
+		interval = time.Second / DefaultProgressRate
+	}
+	seq := pool.ordered.reserve()
+	reporter := &progressReporter{
+		onProgress: onProgress,
+		interval:   interval,
+		clock:      pool.clock,
+	}
+
+	var submitted interface{} = progressJob{jobData, reporter}
+	if after == nil {
+		submitted = uncollectedJob{submitted}
+	}
+	pool.runAsync(jobData, ticket, seq, after, func() (interface{}, error) {
+		result, err := pool.sendWork("", false, submitted)
+		reporter.stop()
+		return result, err
+	})
+	return nil
+}
+
+/*
+progressJob - A job sent with SendWorkAsyncProgress, the reporter is unwrapped by newJob.
+*/
+type progressJob struct {
+	data     interface{}
+	reporter *progressReporter
+}
+
+/*
+progressReporter - Passes the progress of one job on to its submitter, rate limited and only until
+the job has completed.
+*/
+type progressReporter struct {
+	onProgress func(done, total int)
+	interval   time.Duration
+	clock      Clock
+
+	// mutex is held while onProgress is called, so that no call is under way once stop returns
+	mutex   sync.Mutex
+	last    time.Time
+	stopped bool
+}
+
+func (reporter *progressReporter) report(done, total int) {
+	if reporter == nil {
+		return
+	}
+
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	if reporter.stopped {
+		return
+	}
+	now := reporter.clock.Now()
+	if !reporter.last.IsZero() && now.Sub(reporter.last) < reporter.interval {
+		return
+	}
+	reporter.last = now
+	reporter.onProgress(done, total)
+}
+
+func (reporter *progressReporter) stop() {
+	reporter.mutex.Lock()
+	reporter.stopped = true
+	reporter.mutex.Unlock()
+}
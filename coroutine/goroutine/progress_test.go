@@ -0,0 +1,110 @@
+package goroutine
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// progressWorker reports a step each 10ms of the clock until it reaches steps
+type progressWorker struct {
+	clock *FakeClock
+	steps int
+}
+
+func (w *progressWorker) JobProgress(in interface{}, report func(done, total int)) interface{} {
+	if in == "silent" {
+		return in
+	}
+	for i := 1; i <= w.steps; i++ {
+		w.clock.Advance(10 * time.Millisecond)
+		report(i, w.steps)
+	}
+	return w.steps
+}
+
+func (w *progressWorker) Job(in interface{}) interface{} {
+	return w.JobProgress(in, func(int, int) {})
+}
+
+func (w *progressWorker) Ready() bool { return true }
+
+func (w *progressWorker) Initialize() {}
+
+func (w *progressWorker) Terminate() {}
+
+func TestSendWorkAsyncProgress(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	worker := &progressWorker{clock: clock, steps: 100}
+
+	pool, err := CreateCustomPool([]GoroutineWorker{worker}, WithClock(clock), WithProgressRate(10)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	var calls, last int32
+	var finished int32
+	results := make(chan interface{}, 1)
+	err = pool.SendWorkAsyncProgress(nil, func(done, total int) {
+		if atomic.LoadInt32(&finished) == 1 {
+			t.Errorf("Progress reported after the result")
+		}
+		if total != 100 {
+			t.Errorf("Wrong total: %v != %v", total, 100)
+		}
+		atomic.AddInt32(&calls, 1)
+		atomic.StoreInt32(&last, int32(done))
+	}, func(result interface{}, err error) {
+		atomic.StoreInt32(&finished, 1)
+		if err != nil {
+			t.Errorf("Job failed: %v", err)
+		}
+		results <- result
+	})
+	if err != nil {
+		t.Errorf("Failed to send job: %v", err)
+		return
+	}
+
+	select {
+	case result := <-results:
+		if result != 100 {
+			t.Errorf("Wrong result: %v != %v", result, 100)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for the result")
+		return
+	}
+
+	// 100 steps of 10ms take one second of the clock, reported at most 10 times a second
+	if n := atomic.LoadInt32(&calls); n < 10 || n > 11 {
+		t.Errorf("Wrong number of progress reports: %v", n)
+	}
+	if done := atomic.LoadInt32(&last); done < 90 {
+		t.Errorf("Last progress report too early: %v", done)
+	}
+
+	silent := make(chan struct{})
+	err = pool.SendWorkAsyncProgress("silent", func(done, total int) {
+		t.Errorf("Progress reported for a job which does not report")
+	}, func(interface{}, error) {
+		close(silent)
+	})
+	if err != nil {
+		t.Errorf("Failed to send job: %v", err)
+		return
+	}
+	<-silent
+
+	if _, err := pool.SendWork(nil); err != nil {
+		t.Errorf("Job without a progress callback failed: %v", err)
+	}
+}
+
+func TestProgressRateInvalid(t *testing.T) {
+	if _, err := NewPool(WithWorkers(1), WithJob(func(in interface{}) interface{} { return in }), WithProgressRate(0)); err == nil {
+		t.Errorf("Expected an error for a progress rate of 0")
+	}
+}
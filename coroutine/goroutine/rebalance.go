@@ -0,0 +1,97 @@
+package goroutine
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+/*
+Rebalance - Migrates a running pool to target workers in the background rather than through a
+Close and Open, returning at once. Growing starts the new workers while the others carry on, and
+shrinking retires the surplus, idle ones first, each finishing its job before it exits, so the
+workers serving jobs never number fewer than the smaller of the current size and target. The
+channel of RebalanceDone is closed once the pool has target workers.
+
+Calls made while a rebalance is under way take over from it, the pool ending up with the target
+of the last call. Returns ErrLastWorker for a target below one, ErrResizeCustom if a pool of custom
+workers would have to grow and otherwise the errors of Resize, a failure in the background is
+logged.
+*/
+func (pool *WorkPool) Rebalance(target int) error {
+	switch {
+	case pool.bufDepth > 0:
+		return ErrResizeBuffered
+	case pool.external != nil:
+		return ErrExternalWorkers
+	case target < 1:
+		return ErrLastWorker
+	case !pool.isRunning():
+		return ErrPoolNotRunning
+	case pool.job == nil && target > pool.numLiveWorkers():
+		return ErrResizeCustom
+	}
+
+	done := make(chan struct{})
+	atomic.StoreInt32(&pool.rebalance.target, int32(target))
+	pool.rebalance.done.Store(done)
+	go func() {
+		defer close(done)
+		pool.rebalance.mutex.Lock()
+		defer pool.rebalance.mutex.Unlock()
+
+		target := int(atomic.LoadInt32(&pool.rebalance.target))
+		if err := pool.rebalanceTo(target); err != nil {
+			pool.logger.printf("pool rebalance to %d workers failed: %v", target, err)
+		}
+	}()
+	return nil
+}
+
+/*
+RebalanceDone - A channel closed once the pool has reached the target of the last Rebalance, it is
+closed already if Rebalance has not been called.
+*/
+func (pool *WorkPool) RebalanceDone() <-chan struct{} {
+	if done, ok := pool.rebalance.done.Load().(chan struct{}); ok {
+		return done
+	}
+	return rebalanced
+}
+
+/*
+rebalancer - The state of Rebalance, the calls run one at a time each heading for the target of
+the latest, so that whichever runs last leaves the pool at that target.
+*/
+type rebalancer struct {
+	mutex  sync.Mutex
+	target int32
+
+	// done holds the chan struct{} of the latest call
+	done atomic.Value
+}
+
+// rebalanced is returned by RebalanceDone before any Rebalance
+var rebalanced = func() chan struct{} {
+	done := make(chan struct{})
+	close(done)
+	return done
+}()
+
+/*
+rebalanceTo - Grows or shrinks the pool to target workers, waiting for the workers retired to
+exit.
+*/
+func (pool *WorkPool) rebalanceTo(target int) error {
+	delta := target - pool.numLiveWorkers()
+	if delta > 0 {
+		return pool.grow(delta)
+	}
+	if delta < 0 {
+		retired := make(chan struct{})
+		if err := pool.shrink(-delta, func() { close(retired) }); err != nil {
+			return err
+		}
+		<-retired
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package goroutine
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRebalance(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 5)
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		if in == "block" {
+			started <- struct{}{}
+			<-release
+		}
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	select {
+	case <-pool.RebalanceDone():
+	default:
+		t.Errorf("Expected RebalanceDone to be closed before any Rebalance")
+	}
+
+	if err := pool.Rebalance(5); err != nil {
+		t.Errorf("Failed to rebalance: %v", err)
+	}
+	<-pool.RebalanceDone()
+	if n := pool.NumWorkers(); n != 5 {
+		t.Errorf("Expected 5 workers after growing, got %v", n)
+	}
+
+	// Shrink while every worker is busy, the call returns at once and the pool keeps at least
+	// two workers throughout
+	var jobs sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			pool.SendWork("block")
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		<-started
+	}
+
+	before := time.Now()
+	if err := pool.Rebalance(2); err != nil {
+		t.Errorf("Failed to rebalance: %v", err)
+	}
+	if elapsed := time.Since(before); elapsed > 50*time.Millisecond {
+		t.Errorf("Expected Rebalance to return at once, took %v", elapsed)
+	}
+
+	var lowest int32 = 5
+	stopSampling := make(chan struct{})
+	var sampling sync.WaitGroup
+	sampling.Add(1)
+	go func() {
+		defer sampling.Done()
+		for {
+			if n := int32(pool.NumWorkers()); n < atomic.LoadInt32(&lowest) {
+				atomic.StoreInt32(&lowest, n)
+			}
+			select {
+			case <-stopSampling:
+				return
+			default:
+				time.Sleep(100 * time.Microsecond)
+			}
+		}
+	}()
+
+	select {
+	case <-pool.RebalanceDone():
+		t.Errorf("Expected the rebalance to wait for the busy workers")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	jobs.Wait()
+	<-pool.RebalanceDone()
+	close(stopSampling)
+	sampling.Wait()
+
+	if n := pool.NumWorkers(); n != 2 {
+		t.Errorf("Expected 2 workers after shrinking, got %v", n)
+	}
+	if n := atomic.LoadInt32(&lowest); n < 2 {
+		t.Errorf("Expected never fewer than 2 workers, got %v", n)
+	}
+	if result, err := pool.SendWork(1); err != nil || result != 1 {
+		t.Errorf("Expected 1, got %v, %v", result, err)
+	}
+
+	if err := pool.Rebalance(0); err != ErrLastWorker {
+		t.Errorf("Expected ErrLastWorker, got %v", err)
+	}
+}
+
+func TestRebalanceLatestTarget(t *testing.T) {
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	for _, target := range []int{6, 3, 4} {
+		if err := pool.Rebalance(target); err != nil {
+			t.Errorf("Failed to rebalance: %v", err)
+		}
+	}
+	<-pool.RebalanceDone()
+
+	// The earlier calls may still be running, each heads for the latest target
+	deadline := time.Now().Add(time.Second)
+	for pool.NumWorkers() != 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := pool.NumWorkers(); n != 4 {
+		t.Errorf("Expected 4 workers, got %v", n)
+	}
+
+	pool.Close()
+	if err := pool.Rebalance(2); err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning, got %v", err)
+	}
+}
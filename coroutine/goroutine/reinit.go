@@ -0,0 +1,57 @@
+package goroutine
+
+/*
+WithWorkerFactory - Sets the function creating a fresh worker to take the place of one whose
+Reinitialize failed after a job panicked, see GoroutineReinitializableWorker. The failed worker
+is terminated and the new one initialized in its slot before it is given another job. Without a
+factory the failed worker is terminated and initialized again in place.
+*/
+func WithWorkerFactory(factory func() GoroutineWorker) Option {
+	return func(pool *WorkPool) {
+		pool.workerFactory = factory
+	}
+}
+
+/*
+reinitialize - Called by the loop after a job has panicked and its result has been delivered,
+before the worker reports ready again. A worker which fails to reinitialize is replaced by one
+from the factory, or restarted if there is none.
+*/
+func (wrapper *workerWrapper) reinitialize() {
+	wrapper.workerMutex.Lock()
+	defer wrapper.workerMutex.Unlock()
+
+	reinitWorker, ok := wrapper.worker.(GoroutineReinitializableWorker)
+	if !ok {
+		return
+	}
+	err := reinitWorker.Reinitialize()
+	if err == nil {
+		return
+	}
+
+	if extWorker, ok := wrapper.worker.(GoroutineExtendedWorker); ok {
+		wrapper.guard("Terminate", extWorker.Terminate)
+	}
+
+	var replacement GoroutineWorker
+	if wrapper.factory != nil {
+		replacement = wrapper.factory()
+	}
+	if replacement == nil {
+		wrapper.logger.printf("worker %d failed to reinitialize, restarting it: %v", wrapper.index, err)
+		replacement = wrapper.worker
+	} else {
+		wrapper.logger.printf("worker %d failed to reinitialize, replacing it: %v", wrapper.index, err)
+		if indexed, ok := replacement.(indexedWorker); ok {
+			indexed.setIndex(wrapper.index)
+		}
+		wrapper.swapMutex.Lock()
+		wrapper.worker = replacement
+		wrapper.swapMutex.Unlock()
+	}
+
+	if extWorker, ok := replacement.(GoroutineExtendedWorker); ok {
+		wrapper.guard("Initialize", extWorker.Initialize)
+	}
+}
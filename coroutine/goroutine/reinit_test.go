@@ -0,0 +1,99 @@
+package goroutine
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+// reinitWorker panics on jobs of "panic" and fails to reinitialize once broken
+type reinitWorker struct {
+	generation    int
+	broken        bool
+	initialized   int32
+	terminated    int32
+	reinitialized int32
+}
+
+func (w *reinitWorker) Job(in interface{}) interface{} {
+	switch in {
+	case "panic":
+		panic("job panicked")
+	case "break":
+		w.broken = true
+		panic("job broke the worker")
+	}
+	return w.generation
+}
+
+func (w *reinitWorker) Ready() bool { return true }
+
+func (w *reinitWorker) Initialize() { atomic.AddInt32(&w.initialized, 1) }
+
+func (w *reinitWorker) Terminate() { atomic.AddInt32(&w.terminated, 1) }
+
+func (w *reinitWorker) Reinitialize() error {
+	atomic.AddInt32(&w.reinitialized, 1)
+	if w.broken {
+		return errors.New("worker is broken")
+	}
+	return nil
+}
+
+func TestReinitialize(t *testing.T) {
+	first := &reinitWorker{generation: 1}
+	var created []*reinitWorker
+
+	pool, err := CreateCustomPool([]GoroutineWorker{first}, WithWorkerFactory(func() GoroutineWorker {
+		worker := &reinitWorker{generation: len(created) + 2}
+		created = append(created, worker)
+		return worker
+	})).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	if _, err := pool.SendWork("panic"); !errors.Is(err, ErrJobPanicked) {
+		t.Errorf("Expected ErrJobPanicked, got %v", err)
+	}
+	if result, _ := pool.SendWork(nil); result != 1 {
+		t.Errorf("Expected the reinitialized worker to carry on, got generation %v", result)
+	}
+	if first.reinitialized != 1 || first.terminated != 0 || len(created) != 0 {
+		t.Errorf("Expected the worker reinitialized in place, got %+v", first)
+	}
+
+	pool.SendWork("break")
+	if result, _ := pool.SendWork(nil); result != 2 {
+		t.Errorf("Expected the replacement worker, got generation %v", result)
+	}
+	if first.terminated != 1 || len(created) != 1 || created[0].initialized != 1 {
+		t.Errorf("Expected the broken worker terminated and its replacement initialized")
+	}
+
+	pool.Close()
+	if created[0].terminated != 1 || first.terminated != 1 {
+		t.Errorf("Expected only the replacement to be terminated on close")
+	}
+}
+
+func TestReinitializeWithoutFactory(t *testing.T) {
+	worker := &reinitWorker{generation: 1}
+
+	pool, err := CreateCustomPool([]GoroutineWorker{worker}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	pool.SendWork("break")
+	if result, _ := pool.SendWork(nil); result != 1 {
+		t.Errorf("Expected the worker to be kept, got generation %v", result)
+	}
+	if worker.terminated != 1 || worker.initialized != 2 {
+		t.Errorf("Expected the worker to be restarted, terminated %v and initialized %v times",
+			worker.terminated, worker.initialized)
+	}
+}
@@ -0,0 +1,81 @@
+package goroutine
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	ErrReplaceBuffered = errors.New("workers of a buffered pool cannot be replaced")
+)
+
+/*
+ReplaceWorker - Swaps the worker at index for newWorker while the pool keeps running, for example
+to roll out a worker loaded with new model weights without closing the pool. The slot is taken
+out of rotation as soon as its current job, if any, is done, the old worker is terminated and the
+new one initialized if they are extended workers, and the slot then serves jobs again. Other
+workers carry on serving jobs throughout, so replacing each worker in turn upgrades the whole
+pool without a failed submission.
+
+Returns ErrPoolNotRunning on a closed pool, ErrWorkerIndex for an index outside the pool,
+ErrWorkerStopped for a worker stopped with StopWorker and ErrReplaceBuffered for buffered pools.
+*/
+func (pool *WorkPool) ReplaceWorker(index int, newWorker GoroutineWorker) error {
+	if newWorker == nil {
+		return ErrWorkerNil
+	}
+	if pool.bufDepth > 0 {
+		return ErrReplaceBuffered
+	}
+	if pool.external != nil {
+		return ErrExternalWorkers
+	}
+
+	pool.statusMutex.RLock()
+	defer pool.statusMutex.RUnlock()
+
+	if !pool.isRunning() {
+		return ErrPoolNotRunning
+	}
+	if index < 0 || index >= len(pool.workers) {
+		return ErrWorkerIndex
+	}
+	wrapper := pool.workers[index]
+	if atomic.LoadUint32(&wrapper.stopped) == 1 {
+		return ErrWorkerStopped
+	}
+
+	if indexed, ok := newWorker.(indexedWorker); ok {
+		indexed.setIndex(index)
+	}
+
+	// A worker of a lazy pool which has not been started is initialized when it starts
+	if atomic.LoadUint32(&wrapper.started) == 0 {
+		wrapper.workerMutex.Lock()
+		started := atomic.LoadUint32(&wrapper.started) == 1
+		if !started {
+			wrapper.swapMutex.Lock()
+			wrapper.worker = newWorker
+			wrapper.swapMutex.Unlock()
+		}
+		wrapper.workerMutex.Unlock()
+		if !started {
+			return nil
+		}
+	}
+
+	// Holding the worker's ready signal keeps jobs from being routed to it, and it is only
+	// given once the worker has finished its current job
+	select {
+	case _, ok := <-wrapper.readyChan:
+		if !ok {
+			return ErrWorkerClosed
+		}
+	case <-wrapper.closing:
+		return ErrWorkerStopped
+	}
+
+	err := wrapper.SetWorker(newWorker)
+	wrapper.jobChan <- jobRequest{returned: true}
+	return err
+}
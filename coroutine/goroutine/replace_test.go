@@ -0,0 +1,111 @@
+package goroutine
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReplaceWorker(t *testing.T) {
+	numWorkers := 4
+	oldWorkers := make([]*taggedExtWorker, numWorkers)
+	workers := make([]GoroutineWorker, numWorkers)
+	for i := range workers {
+		oldWorkers[i] = &taggedExtWorker{tag: "v1"}
+		workers[i] = oldWorkers[i]
+	}
+
+	pool, err := CreateCustomPool(workers).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	if err := pool.ReplaceWorker(numWorkers, &taggedExtWorker{tag: "v2"}); err != ErrWorkerIndex {
+		t.Errorf("Expected ErrWorkerIndex, got %v", err)
+	}
+	if err := pool.ReplaceWorker(0, nil); err != ErrWorkerNil {
+		t.Errorf("Expected ErrWorkerNil, got %v", err)
+	}
+
+	// Continuous load while every worker is replaced in turn
+	var failed, sent int64
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, err := pool.SendWork(nil); err != nil {
+					atomic.AddInt64(&failed, 1)
+				}
+				atomic.AddInt64(&sent, 1)
+			}
+		}()
+	}
+
+	newWorkers := make([]*taggedExtWorker, numWorkers)
+	for i := range newWorkers {
+		newWorkers[i] = &taggedExtWorker{tag: "v2"}
+		if err := pool.ReplaceWorker(i, newWorkers[i]); err != nil {
+			t.Errorf("Failed to replace worker %d: %v", i, err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if failed != 0 || sent == 0 {
+		t.Errorf("Expected no failed submissions, %v of %v failed", failed, sent)
+	}
+	for i := 0; i < 20; i++ {
+		if result, err := pool.SendWork(nil); err != nil || result != "v2" {
+			t.Errorf("Expected results from the new workers, got %v, %v", result, err)
+		}
+	}
+	for i := range oldWorkers {
+		if n := atomic.LoadInt32(&oldWorkers[i].terminated); n != 1 {
+			t.Errorf("Expected old worker %d to be terminated once, got %v", i, n)
+		}
+		if n := atomic.LoadInt32(&newWorkers[i].initialized); n != 1 {
+			t.Errorf("Expected new worker %d to be initialized once, got %v", i, n)
+		}
+	}
+
+	pool.Close()
+	if err := pool.ReplaceWorker(0, &taggedExtWorker{tag: "v3"}); err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning, got %v", err)
+	}
+	for i := range newWorkers {
+		if n := atomic.LoadInt32(&newWorkers[i].terminated); n != 1 {
+			t.Errorf("Expected new worker %d to be terminated on Close, got %v", i, n)
+		}
+	}
+}
+
+func TestReplaceWorkerLazy(t *testing.T) {
+	oldWorker := &taggedExtWorker{tag: "v1"}
+	pool, err := CreateCustomPool([]GoroutineWorker{oldWorker}, WithLazyStart()).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	// A worker which has not started is swapped without being initialized or terminated
+	newWorker := &taggedExtWorker{tag: "v2"}
+	if err := pool.ReplaceWorker(0, newWorker); err != nil {
+		t.Errorf("Failed to replace worker: %v", err)
+	}
+	if result, err := pool.SendWork(nil); err != nil || result != "v2" {
+		t.Errorf("Expected a result from the new worker, got %v, %v", result, err)
+	}
+	if oldWorker.initialized != 0 || oldWorker.terminated != 0 || atomic.LoadInt32(&newWorker.initialized) != 1 {
+		t.Errorf("Expected only the new worker to be initialized")
+	}
+}
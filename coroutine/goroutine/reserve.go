@@ -0,0 +1,183 @@
+package goroutine
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrReserveTimeout     = errors.New("no worker became idle before the reserve timed out")
+	ErrReservationExpired = errors.New("the reservation expired before it was run")
+	ErrReservationUsed    = errors.New("the reservation has already been run or released")
+)
+
+/*
+DefaultReservationHold - How long a reserved worker is held for its caller before it is returned
+to the pool unused, unless set with WithReservationHold.
+*/
+const DefaultReservationHold = 10 * time.Second
+
+/*
+WithReservationHold - Sets how long a worker reserved with Reserve is held before the reservation
+expires and the worker is returned to the pool, so that a caller which neither runs nor releases
+its reservation cannot strand the worker.
+*/
+func WithReservationHold(hold time.Duration) Option {
+	return func(pool *WorkPool) {
+		if hold > 0 {
+			pool.reservationHold = hold
+		}
+	}
+}
+
+const (
+	reservationHeld = iota
+	reservationRunning
+	reservationExpired
+	reservationDone
+)
+
+/*
+Reservation - An idle worker held for a single job, obtained from Reserve. The job must be run
+with Run, or the worker handed back with Release, before the reservation expires.
+*/
+type Reservation struct {
+	pool    *WorkPool
+	chosen  int
+	expires Timer
+
+	mutex sync.Mutex
+	state int
+}
+
+/*
+Reserve - Blocks until a worker is idle and holds it for the caller, for jobs which need some
+last moment preparation once it is certain that a worker is about to run them. No other job is
+given the worker until the reservation is run, released or expires after the hold time set with
+WithReservationHold. A timeout of 0 or less waits for as long as it takes. Returns
+ErrReserveTimeout if no worker became idle in time, and as with BorrowWorker the workers of a
+buffered pool cannot be reserved. Like a borrowed worker, a held reservation delays Close until
+it is run, released or expires.
+*/
+func (pool *WorkPool) Reserve(timeout time.Duration) (*Reservation, error) {
+	if pool.bufDepth > 0 {
+		return nil, ErrBorrowBuffered
+	}
+
+	pool.statusMutex.RLock()
+
+	if !pool.isRunning() {
+		pool.statusMutex.RUnlock()
+		return nil, ErrPoolNotRunning
+	}
+
+	chosen, ok := pool.demandWorker()
+	if chosen < 0 {
+		selectCases := pool.selects
+		if timeout > 0 {
+			timer := getTimer(pool.clock, timeout)
+			defer putTimer(timer)
+			selectCases = append(pool.selects[:len(pool.selects):len(pool.selects)],
+				reflect.SelectCase{
+					Dir:  reflect.SelectRecv,
+					Chan: reflect.ValueOf(timer.C()),
+				},
+			)
+		}
+		chosen, _, ok = reflect.Select(selectCases)
+	}
+	if chosen >= len(pool.selects) {
+		pool.statusMutex.RUnlock()
+		return nil, ErrReserveTimeout
+	}
+	if !ok || chosen < 0 {
+		pool.statusMutex.RUnlock()
+		return nil, ErrWorkerClosed
+	}
+
+	atomic.AddInt32(&pool.borrowedWorkers, 1)
+
+	reservation := &Reservation{
+		pool:   pool,
+		chosen: chosen,
+	}
+	hold := pool.reservationHold
+	if hold <= 0 {
+		hold = DefaultReservationHold
+	}
+	reservation.expires = pool.clock.AfterFunc(hold, reservation.expire)
+	return reservation, nil
+}
+
+/*
+Run - Runs a job on the reserved worker and returns its result, after which the reservation is
+used up. Returns ErrReservationExpired if the hold time passed before Run was called, and
+ErrReservationUsed if the reservation was already run or released.
+*/
+func (r *Reservation) Run(jobData interface{}) (interface{}, error) {
+	if err := r.take(reservationRunning); err != nil {
+		return nil, err
+	}
+
+	pool := r.pool
+	defer func() {
+		r.giveBack(false)
+
+		r.mutex.Lock()
+		r.state = reservationDone
+		r.mutex.Unlock()
+	}()
+
+	job, trace, enqueued := pool.newJob(pool.clonePayload(jobData))
+	defer job.tracked.abandon()
+	return pool.runJob(r.chosen, job, trace, enqueued)
+}
+
+/*
+Release - Returns the reserved worker to the pool without running a job, it is safe to call after
+the reservation was run or expired.
+*/
+func (r *Reservation) Release() {
+	if r.take(reservationDone) == nil {
+		r.giveBack(true)
+	}
+}
+
+// expire returns the worker once the hold time has passed without the reservation being used
+func (r *Reservation) expire() {
+	if r.take(reservationExpired) == nil {
+		r.giveBack(true)
+	}
+}
+
+// take moves a held reservation to state, or returns why it can no longer be used
+func (r *Reservation) take(state int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch r.state {
+	case reservationHeld:
+		r.state = state
+		if state != reservationExpired {
+			r.expires.Stop()
+		}
+		return nil
+	case reservationExpired:
+		return ErrReservationExpired
+	}
+	return ErrReservationUsed
+}
+
+// giveBack returns the worker to the pool, unused tells the worker that no job is coming
+func (r *Reservation) giveBack(unused bool) {
+	pool := r.pool
+	if unused {
+		pool.workers[r.chosen].jobChan <- jobRequest{returned: true}
+	}
+
+	atomic.AddInt32(&pool.borrowedWorkers, -1)
+	pool.statusMutex.RUnlock()
+}
@@ -0,0 +1,101 @@
+package goroutine
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReserveRun(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		return in.(int) * 2
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	reservation, err := pool.Reserve(time.Second)
+	if err != nil {
+		t.Errorf("Failed to reserve worker: %v", err)
+		return
+	}
+
+	// The only worker is reserved, so other submissions cannot take it
+	if _, err := pool.SendWorkTimed(20*time.Millisecond, 1); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut while the worker is reserved, got %v", err)
+	}
+	if _, err := pool.Reserve(20 * time.Millisecond); err != ErrReserveTimeout {
+		t.Errorf("Expected ErrReserveTimeout, got %v", err)
+	}
+
+	if result, err := reservation.Run(21); err != nil || result != 42 {
+		t.Errorf("Unexpected result %v, %v", result, err)
+	}
+	if _, err := reservation.Run(21); err != ErrReservationUsed {
+		t.Errorf("Expected ErrReservationUsed, got %v", err)
+	}
+
+	// Once run the worker is back in the pool
+	if result, err := pool.SendWorkTimed(1000*time.Millisecond, 2); err != nil || result != 4 {
+		t.Errorf("Unexpected result %v, %v", result, err)
+	}
+}
+
+func TestReserveRelease(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	reservation, err := pool.Reserve(0)
+	if err != nil {
+		t.Errorf("Failed to reserve worker: %v", err)
+		return
+	}
+	reservation.Release()
+	reservation.Release()
+
+	if _, err := reservation.Run("late"); err != ErrReservationUsed {
+		t.Errorf("Expected ErrReservationUsed, got %v", err)
+	}
+	if result, err := pool.SendWork("hello"); err != nil || result != "hello" {
+		t.Errorf("Unexpected result %v, %v", result, err)
+	}
+}
+
+func TestReserveExpire(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		return in
+	}, WithReservationHold(20*time.Millisecond)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	reservation, err := pool.Reserve(0)
+	if err != nil {
+		t.Errorf("Failed to reserve worker: %v", err)
+		return
+	}
+
+	// The expired reservation hands the worker to the waiting job
+	if result, err := pool.SendWork("hello"); err != nil || result != "hello" {
+		t.Errorf("Unexpected result %v, %v", result, err)
+	}
+	if _, err := reservation.Run("late"); err != ErrReservationExpired {
+		t.Errorf("Expected ErrReservationExpired, got %v", err)
+	}
+	reservation.Release()
+
+	// A forgotten reservation does not keep the pool from closing
+	if _, err := pool.Reserve(0); err != nil {
+		t.Errorf("Failed to reserve worker: %v", err)
+	}
+	pool.Close()
+}
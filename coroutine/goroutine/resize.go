@@ -0,0 +1,140 @@
+package goroutine
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrResizeCustom   = errors.New("a pool of custom workers cannot grow, it has no job to give new workers")
+	ErrResizeBuffered = errors.New("a buffered pool cannot be resized")
+)
+
+/*
+Resize - Adds or removes delta workers of a running pool without stopping the others, so that work
+in flight carries on throughout.
+
+Growing creates delta workers running the pool's job and adds them to those the pool dispatches
+to, they are started at once unless the pool was created WithLazyStart. The workers are added
+under the pool's lock, so the call first waits for synchronous jobs in flight to return. A pool of
+custom workers has no job to give new workers and returns ErrResizeCustom.
+
+Shrinking retires -delta workers, idle ones first. A retired worker takes no more jobs, finishes
+the job it is running, if any, and exits, and NumWorkers counts down as each one does. The call
+returns without waiting, and as with StopWorker the workers keep their indices and are not started
+again when the pool is reopened. ErrLastWorker is returned rather than leave the pool without
+workers.
+
+Isolation groups are sized from the number of workers on Open and a per CPU pool sizes itself
+afresh on Open, so neither follows a Resize until the pool is reopened.
+*/
+func (pool *WorkPool) Resize(delta int) error {
+	if pool.bufDepth > 0 {
+		return ErrResizeBuffered
+	}
+	if pool.external != nil {
+		return ErrExternalWorkers
+	}
+	if delta > 0 {
+		return pool.grow(delta)
+	}
+	if delta < 0 {
+		return pool.shrink(-delta, nil)
+	}
+	return nil
+}
+
+/*
+grow - Adds and opens n workers running the pool's job.
+*/
+func (pool *WorkPool) grow(n int) error {
+	if pool.job == nil {
+		return ErrResizeCustom
+	}
+
+	pool.statusMutex.Lock()
+	defer pool.statusMutex.Unlock()
+
+	if !pool.isRunning() {
+		return ErrPoolNotRunning
+	}
+
+	// The workers are added to copies of the slices, readers which do not lock the pool may
+	// still be ranging over the current ones
+	workers := append(pool.workers[:len(pool.workers):len(pool.workers)], make([]*workerWrapper, n)...)
+	selects := append(pool.selects[:len(pool.selects):len(pool.selects)], make([]reflect.SelectCase, n)...)
+	for i := len(pool.workers); i < len(workers); i++ {
+		workers[i] = &workerWrapper{
+			worker: &(defaultWorker{pool.job}),
+		}
+		selects[i] = pool.openWorker(i, workers[i])
+	}
+	pool.selects = selects
+	pool.setWorkers(workers)
+	if !pool.lazyStart {
+		for pool.startWorker() {
+		}
+	}
+	pool.logger.printf("pool grown to %d workers", pool.numLiveWorkers())
+	return nil
+}
+
+/*
+shrink - Retires n workers in the background. The pool stays read locked until they have all
+exited, so that Close waits for them as it does for the jobs they finish, and retired is then
+called if not nil.
+*/
+func (pool *WorkPool) shrink(n int, retired func()) error {
+	pool.statusMutex.RLock()
+
+	if !pool.isRunning() {
+		pool.statusMutex.RUnlock()
+		return ErrPoolNotRunning
+	}
+	if !pool.reserveStops(n) {
+		pool.statusMutex.RUnlock()
+		return ErrLastWorker
+	}
+
+	retiring := make([]*workerWrapper, 0, n)
+	take := func(idleOnly bool) {
+		for i := len(pool.workers) - 1; i >= 0 && len(retiring) < n; i-- {
+			workerWrapper := pool.workers[i]
+			busy := atomic.LoadUint32(&workerWrapper.started) == 1 && atomic.LoadUint32(&workerWrapper.idle) == 0
+			if idleOnly && busy {
+				continue
+			}
+			if atomic.CompareAndSwapUint32(&workerWrapper.stopped, 0, 1) {
+				retiring = append(retiring, workerWrapper)
+			}
+		}
+	}
+	take(true)
+	take(false)
+
+	// Every reservation is backed by a worker not yet stopped, this only guards the count
+	atomic.AddInt32(&pool.stoppingWorkers, int32(len(retiring)-n))
+
+	var wg sync.WaitGroup
+	for _, wrapper := range retiring {
+		wg.Add(1)
+		go func(wrapper *workerWrapper) {
+			defer wg.Done()
+			if wrapper.stop() {
+				atomic.AddInt32(&pool.startedWorkers, -1)
+			}
+			atomic.AddInt32(&pool.stoppedWorkers, 1)
+		}(wrapper)
+	}
+	go func() {
+		wg.Wait()
+		pool.logger.printf("pool shrunk to %d workers", pool.numLiveWorkers())
+		pool.statusMutex.RUnlock()
+		if retired != nil {
+			retired()
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,147 @@
+package goroutine
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResize(t *testing.T) {
+	release := make(chan struct{})
+	running := make(chan struct{}, 4)
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		if in == "block" {
+			running <- struct{}{}
+			<-release
+		}
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if err := pool.Resize(2); err != nil {
+		t.Errorf("Failed to grow pool: %v", err)
+	}
+	if n := pool.NumWorkers(); n != 4 {
+		t.Errorf("Expected 4 workers, got %v", n)
+	}
+
+	// Every worker, old and new, takes a job
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if result, err := pool.SendWork("block"); err != nil || result != "block" {
+				t.Errorf("Expected the job to complete, got %v, %v", result, err)
+			}
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		<-running
+	}
+
+	// Busy workers finish their jobs before they exit
+	if err := pool.Resize(-3); err != nil {
+		t.Errorf("Failed to shrink pool: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := pool.NumWorkers(); n != 4 {
+		t.Errorf("Expected the busy workers to be counted until they exit, got %v", n)
+	}
+	close(release)
+	wg.Wait()
+	for deadline := time.Now().Add(time.Second); pool.NumWorkers() != 1 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if n := pool.NumWorkers(); n != 1 {
+		t.Errorf("Expected 1 worker, got %v", n)
+	}
+	for i := 0; i < 10; i++ {
+		if result, err := pool.SendWork(i); err != nil || result != i {
+			t.Errorf("Expected %v, got %v, %v", i, result, err)
+		}
+	}
+
+	if err := pool.Resize(-1); err != ErrLastWorker {
+		t.Errorf("Expected ErrLastWorker, got %v", err)
+	}
+
+	// Retired workers stay retired across a reopen
+	pool.Close()
+	if err := pool.Resize(1); err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning, got %v", err)
+	}
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to reopen pool: %v", err)
+		return
+	}
+	if n := pool.NumWorkers(); n != 1 {
+		t.Errorf("Expected 1 worker after reopening, got %v", n)
+	}
+}
+
+// Run with -race, the pool is resized while it is read without locking
+func TestResizeConcurrentReaders(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				pool.NumWorkers()
+				pool.NumIdleWorkers()
+				pool.Snapshot()
+				pool.Stats()
+				pool.SendWork(nil)
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		if err := pool.Resize(1); err != nil {
+			t.Errorf("Failed to grow pool: %v", err)
+		}
+	}
+	if err := pool.Rebalance(5); err != nil {
+		t.Errorf("Failed to rebalance pool: %v", err)
+	}
+	<-pool.RebalanceDone()
+	close(stop)
+	wg.Wait()
+
+	if n := pool.NumWorkers(); n != 5 {
+		t.Errorf("Expected 5 workers, got %v", n)
+	}
+}
+
+func TestResizeCustom(t *testing.T) {
+	pool, err := CreateCustomPool([]GoroutineWorker{&countingExtWorker{}}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if err := pool.Resize(1); err != ErrResizeCustom {
+		t.Errorf("Expected ErrResizeCustom, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package goroutine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/*
+WithScaleDownDelay - Lets a lazily started pool shrink again once load drops. A started worker is
+only retired after it has been idle for the whole delay, and the countdown is abandoned as soon as
+a job lands on it, so a lull shorter than the delay costs nothing. A retired worker is flushed and
+terminated like a worker of a closed pool and is started, and initialized, again on demand.
+
+The pool shrinks down to a single started worker, which is kept so that jobs already waiting for
+a worker are always served. The option has no effect unless the pool was created WithLazyStart,
+as nothing else would start a retired worker again, and a delay of 0 or less keeps started workers
+for as long as the pool is open.
+*/
+func WithScaleDownDelay(d time.Duration) Option {
+	return func(pool *WorkPool) {
+		pool.scaleDownDelay = d
+	}
+}
+
+/*
+canRetire - Asks the pool whether the worker, idle for the whole delay, may retire.
+*/
+func (wrapper *workerWrapper) canRetire() bool {
+	return wrapper.onRetire != nil && wrapper.onRetire()
+}
+
+/*
+retire - Called by Loop as it exits, marks a worker which retired as not started once it has been
+terminated, so that it may be started again.
+*/
+func (wrapper *workerWrapper) retire() {
+	if atomic.LoadUint32(&wrapper.retired) == 1 {
+		wrapper.logger.printf("worker %d retired after being idle for %v", wrapper.index, wrapper.scaleDownDelay)
+		// Once marked as not started the wrapper is no longer the goroutine's to touch
+		atomic.StoreUint32(&wrapper.started, 0)
+	}
+}
+
+/*
+retireWorker - Removes a worker which is about to retire from the count of started workers, unless
+it is the last one. Returns false if the worker must stay.
+*/
+func (pool *WorkPool) retireWorker() bool {
+	for {
+		started := atomic.LoadInt32(&pool.startedWorkers)
+		if started <= 1 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&pool.startedWorkers, started, started-1) {
+			return true
+		}
+	}
+}
+
+/*
+numRetiringWorkers - Number of idle workers which will be retired unless a job lands on them.
+*/
+func (pool *WorkPool) numRetiringWorkers() int {
+	retiring := 0
+	for _, workerWrapper := range pool.lockFreeWorkers() {
+		if atomic.LoadUint32(&workerWrapper.retiring) == 1 {
+			retiring++
+		}
+	}
+	return retiring
+}
@@ -0,0 +1,91 @@
+package goroutine
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startBothWorkers starts both workers of a lazy pool by keeping the first busy
+func startBothWorkers(t *testing.T, pool *WorkPool) {
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	pool.SendWorkAsync(func() {
+		<-release
+	}, func(interface{}, error) {
+		close(finished)
+	})
+	for stats := pool.Stats(); stats.IdleWorkers == stats.StartedWorkers; stats = pool.Stats() {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := pool.SendWork(nil); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	close(release)
+	<-finished
+	if n := pool.NumStartedWorkers(); n != 2 {
+		t.Errorf("Expected 2 started workers, got %v", n)
+	}
+}
+
+func TestScaleDownDelay(t *testing.T) {
+	workers := []*countingExtWorker{{}, {}}
+	pool, err := CreateCustomPool([]GoroutineWorker{workers[0], workers[1]}, WithLazyStart(), WithScaleDownDelay(time.Second)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	startBothWorkers(t, pool)
+
+	// Load returning within the delay cancels the retirement
+	time.Sleep(500 * time.Millisecond)
+	if n := pool.Stats().RetiringWorkers; n != 2 {
+		t.Errorf("Expected 2 workers pending retirement, got %v", n)
+	}
+	startBothWorkers(t, pool)
+	time.Sleep(600 * time.Millisecond)
+	for i, worker := range workers {
+		if n := atomic.LoadInt32(&worker.terminated); n != 0 {
+			t.Errorf("Expected no Terminate of worker %d before it was idle for the delay, got %v", i, n)
+		}
+	}
+}
+
+func TestScaleDownDelayRetires(t *testing.T) {
+	workers := []*countingExtWorker{{}, {}}
+	pool, err := CreateCustomPool([]GoroutineWorker{workers[0], workers[1]}, WithLazyStart(), WithScaleDownDelay(20*time.Millisecond)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	terminated := func() int32 {
+		return atomic.LoadInt32(&workers[0].terminated) + atomic.LoadInt32(&workers[1].terminated)
+	}
+
+	// The pool shrinks down to a single worker
+	startBothWorkers(t, pool)
+	for deadline := time.Now().Add(time.Second); terminated() == 0 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := terminated(); n != 1 {
+		t.Errorf("Expected one idle worker to be terminated, got %v", n)
+	}
+	if stats := pool.Stats(); stats.StartedWorkers != 1 {
+		t.Errorf("Expected 1 started worker, got %+v", stats)
+	}
+
+	// The retired worker is started again on demand
+	startBothWorkers(t, pool)
+	if n := atomic.LoadInt32(&workers[0].initialized) + atomic.LoadInt32(&workers[1].initialized); n != 3 {
+		t.Errorf("Expected the retired worker to be initialized again, got %v initializations", n)
+	}
+
+	pool.Close()
+	if n := terminated(); n != 3 {
+		t.Errorf("Expected both workers to be terminated on Close, got %v terminations", n)
+	}
+}
@@ -0,0 +1,298 @@
+package goroutine
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidCronExpr = errors.New("invalid cron expression")
+)
+
+/*
+ScheduledJob - A handle on a recurring job started with Schedule.
+*/
+type ScheduledJob interface {
+
+	// Stops the job from being run again, a run already submitted to the pool still completes
+	Cancel()
+
+	// The time the job is next due, zero once it has been cancelled
+	NextRun() time.Time
+}
+
+/*
+Schedule - Runs work on a worker of the pool at every time matching cronExpr, in local time, for
+periodic jobs such as refreshing a cache which should share the pool's workers with other work.
+The expression has the five standard fields, minute hour day-of-month month day-of-week, each
+being * or a comma separated list of values and ranges such as 1-5, each optionally stepped as in
+0-30/10, where a stepped * steps through the whole range of the field. Months and days of the
+week are numbers, 0 or 7 being Sunday, and when both day fields are restricted a day matching
+either is due. The descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight and
+@hourly are accepted, as is @every followed by a duration such as @every 30s for intervals finer
+than a minute.
+
+Each run is submitted as with SendWorkAsync, so it waits for a worker like any other job, and
+errors returned by work as its result are logged. The job recurs until it is cancelled, runs
+falling due while the pool is closed are skipped. Returns ErrInvalidCronExpr, wrapped with the
+reason, if the expression cannot be parsed.
+*/
+func (pool *WorkPool) Schedule(cronExpr string, work func() interface{}) (ScheduledJob, error) {
+	if work == nil {
+		return nil, errors.New("scheduled work is nil")
+	}
+	schedule, err := parseCron(cronExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &scheduledJob{
+		pool:     pool,
+		expr:     cronExpr,
+		schedule: schedule,
+		work:     work,
+	}
+	job.mutex.Lock()
+	job.arm(job.pool.clock.Now())
+	job.mutex.Unlock()
+	return job, nil
+}
+
+/*
+scheduledJob - A recurring job, its timer is armed for the next run each time it fires.
+*/
+type scheduledJob struct {
+	pool     *WorkPool
+	expr     string
+	schedule cronSchedule
+	work     func() interface{}
+
+	mutex     sync.Mutex
+	timer     Timer
+	next      time.Time
+	cancelled bool
+}
+
+// arm sets the timer for the first run after now, the mutex must be held
+func (job *scheduledJob) arm(now time.Time) {
+	job.next = job.schedule.next(now)
+	if job.next.IsZero() {
+		return
+	}
+	job.timer = job.pool.clock.AfterFunc(job.next.Sub(now), job.fire)
+}
+
+func (job *scheduledJob) fire() {
+	job.mutex.Lock()
+	if job.cancelled {
+		job.mutex.Unlock()
+		return
+	}
+	job.arm(job.pool.clock.Now())
+	job.mutex.Unlock()
+
+	if !job.pool.isRunning() {
+		return
+	}
+	job.pool.SendWorkAsync(jobCall(func() (interface{}, error) {
+		return job.work(), nil
+	}), func(result interface{}, err error) {
+		if err == nil {
+			err, _ = result.(error)
+		}
+		if err != nil {
+			job.pool.logger.printf("scheduled job %q failed: %v", job.expr, err)
+		}
+	})
+}
+
+func (job *scheduledJob) Cancel() {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	job.cancelled = true
+	job.next = time.Time{}
+	if job.timer != nil {
+		job.timer.Stop()
+	}
+}
+
+func (job *scheduledJob) NextRun() time.Time {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	return job.next
+}
+
+/*
+cronSchedule - Computes the next time a schedule is due after a given time.
+*/
+type cronSchedule interface {
+	next(after time.Time) time.Time
+}
+
+/*
+everySchedule - An @every schedule, due at a fixed interval.
+*/
+type everySchedule time.Duration
+
+func (every everySchedule) next(after time.Time) time.Time {
+	return after.Add(time.Duration(every))
+}
+
+/*
+cronFields - A five field cron expression, each field a bit set of the values it matches.
+*/
+type cronFields struct {
+	minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar record a day field given as *, which matches any day for the other
+	domStar, dowStar bool
+}
+
+// cronBounds are the lowest and highest values of each field in order
+var cronBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+/*
+parseCron - Parses a cron expression or descriptor into a schedule.
+*/
+func parseCron(expr string) (cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(expr[len("@every "):]))
+		if err != nil || every <= 0 {
+			return nil, fmt.Errorf("%w: %q has no positive duration", ErrInvalidCronExpr, expr)
+		}
+		return everySchedule(every), nil
+	}
+	if descriptor, ok := cronDescriptors[expr]; ok {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q does not have 5 fields", ErrInvalidCronExpr, expr)
+	}
+
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, cronBounds[i][0], cronBounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %q: %v", ErrInvalidCronExpr, field, err)
+		}
+		sets[i] = set
+	}
+
+	// Sunday may be written as 0 or 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return &cronFields{
+		minute:  sets[0],
+		hour:    sets[1],
+		dom:     sets[2],
+		month:   sets[3],
+		dow:     sets[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps into a bit set
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if slash := strings.IndexByte(part, '/'); slash >= 0 {
+			n, err := strconv.Atoi(part[slash+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			step = n
+			part = part[:slash]
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			var err error
+			if dash := strings.IndexByte(part, '-'); dash >= 0 {
+				lo, err = strconv.Atoi(part[:dash])
+				if err == nil {
+					hi, err = strconv.Atoi(part[dash+1:])
+				}
+			} else {
+				lo, err = strconv.Atoi(part)
+				hi = lo
+				if step > 1 {
+					// a stepped single value, as in 5/15, runs to the end of the range
+					hi = max
+				}
+			}
+			if err != nil {
+				return 0, fmt.Errorf("bad value in %q", part)
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// cronSearchLimit bounds the search for the next run, an expression such as 0 0 30 2 * is never due
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+func (c *cronFields) next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the cron rule that a day matching either restricted day field is due
+func (c *cronFields) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
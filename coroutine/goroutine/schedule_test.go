@@ -0,0 +1,97 @@
+package goroutine
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 10, 17, 30, 0, time.UTC) // a Wednesday
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 31, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		{"5 * * * *", time.Date(2024, time.January, 31, 11, 5, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, time.January, 31, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{"30 8 29 2 *", time.Date(2024, time.February, 29, 8, 30, 0, 0, time.UTC)},
+		{"0 0 1,15 * 5", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	}
+	for _, test := range tests {
+		schedule, err := parseCron(test.expr)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", test.expr, err)
+			continue
+		}
+		if next := schedule.next(from); !next.Equal(test.next) {
+			t.Errorf("Expected %q to be next due at %v, got %v", test.expr, test.next, next)
+		}
+	}
+
+	// Never due
+	if schedule, err := parseCron("0 0 30 2 *"); err != nil || !schedule.next(from).IsZero() {
+		t.Errorf("Expected a schedule which is never due, got %v", err)
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@every -1s", "@sometimes"} {
+		if _, err := parseCron(expr); !errors.Is(err, ErrInvalidCronExpr) {
+			t.Errorf("Expected ErrInvalidCronExpr for %q, got %v", expr, err)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if _, err := pool.Schedule("* * *", func() interface{} { return nil }); !errors.Is(err, ErrInvalidCronExpr) {
+		t.Errorf("Expected ErrInvalidCronExpr, got %v", err)
+	}
+
+	var runs int32
+	job, err := pool.Schedule("@every 10ms", func() interface{} {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Failed to schedule job: %v", err)
+		return
+	}
+	if next := job.NextRun(); next.IsZero() || time.Until(next) > 10*time.Millisecond {
+		t.Errorf("Expected the job to be due within 10ms, got %v", next)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&runs) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	job.Cancel()
+	if n := atomic.LoadInt32(&runs); n < 3 {
+		t.Errorf("Expected the job to run at least 3 times, ran %v", n)
+	}
+	if !job.NextRun().IsZero() {
+		t.Errorf("Expected no next run once cancelled, got %v", job.NextRun())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	cancelled := atomic.LoadInt32(&runs)
+	time.Sleep(30 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != cancelled {
+		t.Errorf("Expected no runs after Cancel, ran %v more", n-cancelled)
+	}
+}
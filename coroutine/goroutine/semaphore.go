@@ -0,0 +1,105 @@
+package goroutine
+
+import (
+	"context"
+	"time"
+)
+
+/*
+cancelledContext - Used to take a unit of a semaphore only if one is free right now.
+*/
+var cancelledContext = func() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}()
+
+/*
+Semaphore - Limits the number of jobs running at once across every pool sharing it, see
+WithSemaphore. Acquire blocks until a unit is available or ctx is done, returning the error of
+ctx in that case, and each successful Acquire is matched by one Release.
+*/
+type Semaphore interface {
+	Acquire(ctx context.Context) error
+	Release()
+}
+
+/*
+WithSemaphore - Gates every job of the pool on an external semaphore, which may be shared with
+other pools to cap their combined concurrency, for example on a license limited resource. A unit
+is acquired before the job is handed to a worker and released once its result is collected, so
+workers are not held idle while waiting. A timed or context bound submission which gives up while
+waiting for the semaphore fails as if it had timed out waiting for a worker.
+*/
+func WithSemaphore(semaphore Semaphore) Option {
+	return func(pool *WorkPool) {
+		pool.semaphore = semaphore
+	}
+}
+
+/*
+NewWeightedSemaphore - Creates a semaphore with n units for WithSemaphore. Acquire always takes
+a free unit when there is one, even if ctx is already done.
+*/
+func NewWeightedSemaphore(n int) Semaphore {
+	return make(weightedSemaphore, n)
+}
+
+type weightedSemaphore chan struct{}
+
+func (semaphore weightedSemaphore) Acquire(ctx context.Context) error {
+	select {
+	case semaphore <- struct{}{}:
+		return nil
+	default:
+	}
+
+	select {
+	case semaphore <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (semaphore weightedSemaphore) Release() {
+	<-semaphore
+}
+
+/*
+acquireSemaphore - Takes a unit of the semaphore of the pool, if any.
+*/
+func (pool *WorkPool) acquireSemaphore(ctx context.Context) error {
+	if pool.semaphore == nil {
+		return nil
+	}
+	return pool.semaphore.Acquire(ctx)
+}
+
+/*
+acquireSemaphoreUntil - Takes a unit of the semaphore of the pool, if any, giving up when ctx is
+done or the deadline, if not zero, passes.
+*/
+func (pool *WorkPool) acquireSemaphoreUntil(ctx context.Context, deadline time.Time) error {
+	if pool.semaphore == nil {
+		return nil
+	}
+	if !deadline.IsZero() {
+		// The deadline is kept by the pool's clock rather than by the context
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		timer := pool.clock.AfterFunc(deadline.Sub(pool.clock.Now()), cancel)
+		defer timer.Stop()
+	}
+	return pool.semaphore.Acquire(ctx)
+}
+
+/*
+releaseSemaphore - Returns a unit taken by acquireSemaphore.
+*/
+func (pool *WorkPool) releaseSemaphore() {
+	if pool.semaphore != nil {
+		pool.semaphore.Release()
+	}
+}
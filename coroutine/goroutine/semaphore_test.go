@@ -0,0 +1,97 @@
+package goroutine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSharedSemaphore(t *testing.T) {
+	semaphore := NewWeightedSemaphore(2)
+	var running, highWater int32
+
+	job := func(in interface{}) interface{} {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			high := atomic.LoadInt32(&highWater)
+			if now <= high || atomic.CompareAndSwapInt32(&highWater, high, now) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		return in
+	}
+
+	first, err := CreatePool(3, job, WithSemaphore(semaphore)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer first.Close()
+	second, err := CreatePool(3, job, WithSemaphore(semaphore)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer second.Close()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 6; i++ {
+		pool := first
+		if i%2 == 1 {
+			pool = second
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				var err error
+				if j%2 == 0 {
+					_, err = pool.SendWork(j)
+				} else {
+					_, err = pool.SendWorkTimed(1000*time.Millisecond, j)
+				}
+				if err != nil {
+					t.Errorf("Job failed: %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if highWater != 2 {
+		t.Errorf("Expected at most 2 jobs at once across both pools, got %v", highWater)
+	}
+}
+
+func TestSemaphoreTimeout(t *testing.T) {
+	semaphore := NewWeightedSemaphore(1)
+	semaphore.Acquire(context.Background())
+	defer semaphore.Release()
+
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		return in
+	}, WithSemaphore(semaphore)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if _, err := pool.SendWorkTimed(10*time.Millisecond, nil); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pool.SendWorkContext(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context to be cancelled, got %v", err)
+	}
+	if _, ok := pool.SendWorkOrDrop(nil); ok {
+		t.Error("Expected the job to be dropped while the semaphore is taken")
+	}
+}
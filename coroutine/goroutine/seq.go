@@ -0,0 +1,66 @@
+package goroutine
+
+import (
+	"context"
+)
+
+/*
+sequencedJob - Wraps a payload whose sequence number is wanted by its caller, seq receives the
+number once the job is assigned one.
+*/
+type sequencedJob struct {
+	data interface{}
+	seq  *uint64
+}
+
+/*
+SendWorkSeq - Sends a job as SendWork does and also returns the sequence number of the job, which
+is the number passed to the trace function, the stuck worker watchdog, the discarded result
+handler and the logger for the same job, for correlating logs or as an idempotency key. Sequence
+numbers are unique while the pool is open, the first job after each Open is numbered 1. The
+sequence is 0 if the job was rejected before it was assigned one, for example because the pool
+is not running.
+*/
+func (pool *WorkPool) SendWorkSeq(jobData interface{}) (uint64, interface{}, error) {
+	deadline := pool.defaultDeadline()
+
+	jobData = pool.clonePayload(jobData)
+	ticket, err := pool.admitUntil(context.Background(), deadline, jobData)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer pool.release(jobData, ticket)
+
+	var seq uint64
+	result, err := pool.sendWorkDefault(deadline, sequencedJob{jobData, &seq})
+	pool.breaker.done(ticket.probe, result, err)
+	return seq, result, err
+}
+
+/*
+SendWorkAsyncSeq - Sends a job as SendWorkAsync does, passing the sequence number of the job to
+after along with its result, see SendWorkSeq. A nil after is the same as SendWorkAsync with no
+callback.
+*/
+func (pool *WorkPool) SendWorkAsyncSeq(jobData interface{}, after func(seq uint64, result interface{}, err error)) error {
+	if after == nil {
+		return pool.SendWorkAsync(jobData, nil)
+	}
+
+	jobData = pool.clonePayload(jobData)
+	ticket, err := pool.admit(jobData)
+	if err != nil {
+		return err
+	}
+
+	order := pool.ordered.reserve()
+
+	var seq uint64
+	submitted := sequencedJob{jobData, &seq}
+	pool.runAsync(jobData, ticket, order, func(result interface{}, err error) {
+		after(seq, result, err)
+	}, func() (interface{}, error) {
+		return pool.sendWork("", false, submitted)
+	})
+	return nil
+}
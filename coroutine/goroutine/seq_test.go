@@ -0,0 +1,69 @@
+package goroutine
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSendWorkSeq(t *testing.T) {
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	var mutex sync.Mutex
+	traced := map[uint64]bool{}
+	pool.SetTraceFunc(func(trace JobTrace) {
+		mutex.Lock()
+		traced[trace.Seq] = true
+		mutex.Unlock()
+	})
+
+	for i := 1; i <= 3; i++ {
+		seq, result, err := pool.SendWorkSeq(i)
+		if err != nil || result != i {
+			t.Errorf("Expected %v, got %v, %v", i, result, err)
+		}
+		if seq != uint64(i) {
+			t.Errorf("Expected sequence %v, got %v", i, seq)
+		}
+		mutex.Lock()
+		if !traced[seq] {
+			t.Errorf("Expected the trace function to see sequence %v", seq)
+		}
+		mutex.Unlock()
+	}
+
+	done := make(chan uint64)
+	if err := pool.SendWorkAsyncSeq("async", func(seq uint64, result interface{}, err error) {
+		if err != nil || result != "async" {
+			t.Errorf("Expected async, got %v, %v", result, err)
+		}
+		done <- seq
+	}); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	seq := <-done
+	mutex.Lock()
+	if seq != 4 || !traced[seq] {
+		t.Errorf("Expected sequence 4 seen by the trace function, got %v", seq)
+	}
+	mutex.Unlock()
+
+	// Sequences start again from 1 once the pool is reopened
+	pool.Close()
+	if seq, _, err := pool.SendWorkSeq("closed"); err != ErrPoolNotRunning || seq != 0 {
+		t.Errorf("Expected ErrPoolNotRunning and no sequence, got %v, %v", seq, err)
+	}
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to reopen pool: %v", err)
+		return
+	}
+	if seq, _, err := pool.SendWorkSeq("reopened"); err != nil || seq != 1 {
+		t.Errorf("Expected sequence 1 after reopening, got %v, %v", seq, err)
+	}
+}
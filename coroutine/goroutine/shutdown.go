@@ -0,0 +1,133 @@
+package goroutine
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+var (
+	ErrWorkerPanicked = errors.New("worker panicked")
+)
+
+/*
+WorkerPanicError - A panic recovered from the Initialize, Terminate, Flush or Interrupt method of a
+worker, it matches ErrWorkerPanicked. The pool carries on as if the method had returned, so a worker
+misbehaving on its way out cannot crash the process during an otherwise clean shutdown.
+*/
+type WorkerPanicError struct {
+	Index  int
+	Method string
+	Value  interface{}
+	Stack  []byte
+}
+
+func (e *WorkerPanicError) Error() string {
+	return fmt.Sprintf("%v in %s of worker %d: %v", ErrWorkerPanicked, e.Method, e.Index, e.Value)
+}
+
+func (e *WorkerPanicError) Unwrap() error {
+	return ErrWorkerPanicked
+}
+
+/*
+ShutdownReport - How the workers of a pool terminated when it was closed, see CloseReport. Err is
+ErrPoolNotRunning if the pool was not running, in which case there are no workers to report.
+*/
+type ShutdownReport struct {
+	Err     error
+	Workers []WorkerShutdown
+}
+
+/*
+WorkerShutdown - How a worker terminated. Running is false for a worker which was not running when
+the pool closed, a worker not yet started WithLazyStart or stopped with StopWorker, and the other
+fields are only set for running workers. Duration is the time from the pool closing to the worker
+terminating, FlushErr the error of a GoroutineFlushableWorker and Panics every panic recovered
+from the worker's methods since it was started, that of Terminate last if it panicked.
+*/
+type WorkerShutdown struct {
+	Index    int
+	Running  bool
+	Duration time.Duration
+	FlushErr error
+	Panics   []*WorkerPanicError
+}
+
+/*
+Clean - Whether the worker flushed and terminated without an error or a panic.
+*/
+func (worker WorkerShutdown) Clean() bool {
+	return worker.FlushErr == nil && len(worker.Panics) == 0
+}
+
+/*
+Failed - The workers which did not terminate cleanly.
+*/
+func (report ShutdownReport) Failed() []WorkerShutdown {
+	var failed []WorkerShutdown
+	for _, worker := range report.Workers {
+		if !worker.Clean() {
+			failed = append(failed, worker)
+		}
+	}
+	return failed
+}
+
+/*
+CloseReport - Close the pool as Close does and report how each worker terminated. Close completes
+even when workers panic in Terminate or fail to flush, and the report pinpoints which ones did.
+*/
+func (pool *WorkPool) CloseReport() ShutdownReport {
+	workers, err := pool.close()
+	return ShutdownReport{Err: err, Workers: workers}
+}
+
+/*
+guard - Calls a method of the worker, recovering a panic as a *WorkerPanicError which is logged and
+kept for the shutdown report.
+*/
+func (wrapper *workerWrapper) guard(method string, call func()) (err *WorkerPanicError) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &WorkerPanicError{Index: wrapper.index, Method: method, Value: r, Stack: debug.Stack()}
+			wrapper.logger.printf("worker %d panicked in %s: %v", wrapper.index, method, r)
+
+			wrapper.panicMutex.Lock()
+			wrapper.methodPanics = append(wrapper.methodPanics, err)
+			wrapper.panicMutex.Unlock()
+		}
+	}()
+	call()
+	return nil
+}
+
+/*
+shutdown - Reports how a worker which was running when the pool closed terminated, once it has
+been joined.
+*/
+func (wrapper *workerWrapper) shutdown(closed time.Time) WorkerShutdown {
+	wrapper.panicMutex.Lock()
+	defer wrapper.panicMutex.Unlock()
+
+	return WorkerShutdown{
+		Index:    wrapper.index,
+		Running:  true,
+		Duration: wrapper.terminatedAt.Sub(closed),
+		FlushErr: wrapper.flushErr,
+		Panics:   append([]*WorkerPanicError(nil), wrapper.methodPanics...),
+	}
+}
+
+// errs returns the flush error and the panics of the worker for CloseErr
+func (worker WorkerShutdown) errs() []error {
+	var errs []error
+	if worker.FlushErr != nil {
+		errs = append(errs, fmt.Errorf("worker %d: %w", worker.Index, worker.FlushErr))
+	}
+	for _, panicked := range worker.Panics {
+		errs = append(errs, panicked)
+	}
+	return errs
+}
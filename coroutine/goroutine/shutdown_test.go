@@ -0,0 +1,111 @@
+package goroutine
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+type panickingTerminateWorker struct {
+	panics     bool
+	terminated int32
+}
+
+func (w *panickingTerminateWorker) Job(in interface{}) interface{} {
+	return in
+}
+
+func (w *panickingTerminateWorker) Ready() bool {
+	return true
+}
+
+func (w *panickingTerminateWorker) Initialize() {}
+
+func (w *panickingTerminateWorker) Terminate() {
+	atomic.AddInt32(&w.terminated, 1)
+	if w.panics {
+		panic("terminate failed")
+	}
+}
+
+func (w *panickingTerminateWorker) Interrupt() {
+	if w.panics {
+		panic("interrupt failed")
+	}
+}
+
+func TestCloseReport(t *testing.T) {
+	workers := []*panickingTerminateWorker{{}, {panics: true}, {}}
+	pool, err := CreateCustomPool([]GoroutineWorker{workers[0], workers[1], workers[2]}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	for i := 0; i < 10; i++ {
+		if result, err := pool.SendWork(i); err != nil || result != i {
+			t.Errorf("Expected %v, got %v, %v", i, result, err)
+		}
+	}
+
+	report := pool.CloseReport()
+	if report.Err != nil {
+		t.Errorf("Unexpected error closing the pool: %v", report.Err)
+	}
+	if pool.isRunning() {
+		t.Errorf("Expected the pool to be closed")
+	}
+	for i, worker := range workers {
+		if n := atomic.LoadInt32(&worker.terminated); n != 1 {
+			t.Errorf("Expected worker %d to be terminated once, got %v", i, n)
+		}
+	}
+	if len(report.Workers) != 3 {
+		t.Errorf("Expected 3 workers in the report, got %v", len(report.Workers))
+		return
+	}
+	for i, worker := range report.Workers {
+		if !worker.Running || worker.Index != i || worker.Duration < 0 {
+			t.Errorf("Unexpected report for worker %d: %+v", i, worker)
+		}
+	}
+
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].Index != 1 {
+		t.Errorf("Expected worker 1 to be the only one failing, got %+v", failed)
+		return
+	}
+	if len(failed[0].Panics) != 1 {
+		t.Errorf("Expected one panic, got %v", failed[0].Panics)
+		return
+	}
+	panicked := failed[0].Panics[0]
+	if !errors.Is(panicked, ErrWorkerPanicked) || panicked.Method != "Terminate" ||
+		panicked.Value != "terminate failed" || len(panicked.Stack) == 0 {
+		t.Errorf("Unexpected panic: %+v", panicked)
+	}
+
+	if report := pool.CloseReport(); report.Err != ErrPoolNotRunning {
+		t.Errorf("Expected ErrPoolNotRunning from second CloseReport, got %v", report.Err)
+	}
+}
+
+func TestCloseErrWorkerPanics(t *testing.T) {
+	worker := &panickingTerminateWorker{panics: true}
+	pool, err := CreateCustomPool([]GoroutineWorker{worker}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	// A panic in Interrupt is recovered on the caller
+	pool.workers[0].Interrupt()
+
+	err = pool.CloseErr()
+	var panicked *WorkerPanicError
+	if !errors.As(err, &panicked) || panicked.Index != 0 {
+		t.Errorf("Expected a WorkerPanicError from CloseErr, got %v", err)
+	}
+	if !errors.Is(err, ErrWorkerPanicked) {
+		t.Errorf("Expected the error to match ErrWorkerPanicked, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package goroutine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/*
+The states of a worker reported by Snapshot.
+*/
+const (
+	// The worker is waiting for a job
+	WorkerReady = "ready"
+
+	// The worker is running a job, or handing its result back
+	WorkerRunning = "running"
+
+	// The worker is being polled until its Ready method returns true
+	WorkerPolling = "polling"
+
+	// The worker has not been started, either the pool is closed or it was created WithLazyStart
+	WorkerStopped = "stopped"
+)
+
+/*
+WorkerStatus - The state of a single worker at the time of a Snapshot.
+*/
+type WorkerStatus struct {
+	Index int
+	State string
+
+	// How long the current job has been running, zero unless the worker is running a job
+	CurrentJobDuration time.Duration
+
+	TotalJobsCompleted int64
+
+	// When the worker last finished a job, zero if it has not run one
+	LastJobAt time.Time
+}
+
+/*
+Snapshot - Reports the state of every worker in the pool, for debugging a hung pool or feeding a
+dashboard. The state is read without locking the pool or waiting for busy workers, so a snapshot
+of a busy pool may be slightly out of date by the time it is returned.
+*/
+func (pool *WorkPool) Snapshot() []WorkerStatus {
+	now := pool.clock.Now()
+
+	workers := pool.lockFreeWorkers()
+	statuses := make([]WorkerStatus, len(workers))
+	for i, wrapper := range workers {
+		statuses[i] = wrapper.status(i, now)
+	}
+	return statuses
+}
+
+// status reads the state of the worker from its atomic fields
+func (wrapper *workerWrapper) status(index int, now time.Time) WorkerStatus {
+	status := WorkerStatus{
+		Index:              index,
+		State:              WorkerRunning,
+		TotalJobsCompleted: atomic.LoadInt64(&wrapper.jobsCompleted),
+	}
+	if last := atomic.LoadInt64(&wrapper.lastJobAt); last != 0 {
+		status.LastJobAt = time.Unix(0, last)
+	}
+
+	switch {
+	case atomic.LoadUint32(&wrapper.started) == 0:
+		status.State = WorkerStopped
+	case atomic.LoadUint32(&wrapper.busy) == 1:
+		if started := atomic.LoadInt64(&wrapper.jobStartedAt); started != 0 {
+			status.CurrentJobDuration = now.Sub(time.Unix(0, started))
+		}
+	case atomic.LoadUint32(&wrapper.polling) == 1:
+		status.State = WorkerPolling
+	case atomic.LoadUint32(&wrapper.idle) == 1:
+		status.State = WorkerReady
+	}
+	return status
+}
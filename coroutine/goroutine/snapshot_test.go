@@ -0,0 +1,59 @@
+package goroutine
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	gate := &gateWorker{started: make(chan struct{}, 1), release: make(chan struct{})}
+	polled := &toggleWorker{}
+
+	pool := CreateCustomPool([]GoroutineWorker{gate, polled})
+	for _, status := range pool.Snapshot() {
+		if status.State != WorkerStopped {
+			t.Errorf("Expected worker %d to be stopped before Open, got %v", status.Index, status.State)
+		}
+	}
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+
+	// The gate worker is the only one ready, so it takes the job
+	deadline := time.Now().Add(time.Second)
+	for pool.Snapshot()[0].State != WorkerReady && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	before := time.Now()
+	pool.SendWorkAsync("job", nil)
+	<-gate.started
+	time.Sleep(10 * time.Millisecond)
+
+	statuses := pool.Snapshot()
+	if len(statuses) != 2 {
+		t.Errorf("Expected 2 statuses, got %v", len(statuses))
+		return
+	}
+	if s := statuses[0]; s.Index != 0 || s.State != WorkerRunning || s.CurrentJobDuration < 10*time.Millisecond {
+		t.Errorf("Expected worker 0 to be running the job, got %+v", s)
+	}
+	if s := statuses[1]; s.State != WorkerPolling || s.CurrentJobDuration != 0 || s.TotalJobsCompleted != 0 {
+		t.Errorf("Expected worker 1 to be polling, got %+v", s)
+	}
+
+	close(gate.release)
+	deadline = time.Now().Add(time.Second)
+	for pool.Snapshot()[0].TotalJobsCompleted != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	s := pool.Snapshot()[0]
+	if s.TotalJobsCompleted != 1 || s.LastJobAt.Before(before) || s.CurrentJobDuration != 0 {
+		t.Errorf("Expected worker 0 to have completed its job, got %+v", s)
+	}
+
+	atomic.StoreInt32(&polled.ready, 1)
+	pool.Close()
+}
@@ -0,0 +1,63 @@
+package goroutine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/*
+Stats - A snapshot of the state and counters of a pool.
+*/
+type Stats struct {
+	Name             string
+	Workers          int
+	StartedWorkers   int
+	IdleWorkers      int
+	PendingAsyncJobs int32
+
+	// Idle workers of a pool created WithScaleDownDelay which are counting down to retirement
+	RetiringWorkers int
+
+	// Jobs refused at submission, for example by a payload limit
+	RejectedJobs uint64
+
+	// The jobs of RejectedJobs refused by the function set WithAdmissionFunc
+	ShedJobs uint64
+
+	// Jobs run on the caller's goroutine by WithCallerRunsPolicy
+	CallerRanJobs uint64
+
+	// Submissions to SendWorkDedup that joined a job already in flight
+	CoalescedJobs uint64
+
+	// Results computed which no caller received, see SetDiscardedResultHandler
+	DiscardedResults uint64
+
+	// The number of jobs allowed to run at once and the p95 job latency it was derived from,
+	// zero unless the pool was created WithAdaptiveLimit
+	EffectiveLimit int
+	LatencyP95     time.Duration
+}
+
+/*
+Stats - Get a snapshot of the pool's state and counters.
+*/
+func (pool *WorkPool) Stats() Stats {
+	limit, p95 := pool.adaptive.state()
+
+	return Stats{
+		EffectiveLimit:   limit,
+		LatencyP95:       p95,
+		Name:             pool.name,
+		Workers:          pool.NumWorkers(),
+		StartedWorkers:   pool.NumStartedWorkers(),
+		IdleWorkers:      pool.NumIdleWorkers(),
+		RetiringWorkers:  pool.numRetiringWorkers(),
+		PendingAsyncJobs: pool.NumPendingAsyncJobs(),
+		RejectedJobs:     atomic.LoadUint64(&pool.rejectedJobs),
+		ShedJobs:         atomic.LoadUint64(&pool.shedJobs),
+		CallerRanJobs:    atomic.LoadUint64(&pool.callerRanJobs),
+		CoalescedJobs:    atomic.LoadUint64(&pool.inflight.coalesced),
+		DiscardedResults: atomic.LoadUint64(&pool.discarded.count),
+	}
+}
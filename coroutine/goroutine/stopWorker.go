@@ -0,0 +1,105 @@
+package goroutine
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	ErrWorkerIndex   = errors.New("worker index out of range")
+	ErrWorkerStopped = errors.New("worker has already been stopped")
+	ErrLastWorker    = errors.New("the last worker of a pool cannot be stopped")
+	ErrStopBuffered  = errors.New("workers of a buffered pool cannot be stopped")
+)
+
+/*
+StopWorker - Removes the worker at index from the pool for good, for shedding load or when the
+resource behind a worker is being reclaimed. The worker stops taking jobs at once and the call
+waits for the job it is running, if any, to complete before the worker is flushed and terminated
+like a worker of a closed pool. Other workers carry on serving jobs throughout, and NumWorkers
+no longer counts the stopped worker.
+
+The worker keeps its index, so the indices of the others are unchanged, and Snapshot reports it
+as stopped. It is not started again when the pool is reopened, except that a per CPU pool sizes
+itself afresh on Open. Returns ErrWorkerIndex for an index outside the pool, ErrWorkerStopped if
+the worker was stopped already and ErrLastWorker rather than leave the pool without workers.
+*/
+func (pool *WorkPool) StopWorker(index int) error {
+	if pool.bufDepth > 0 {
+		return ErrStopBuffered
+	}
+	if pool.external != nil {
+		return ErrExternalWorkers
+	}
+
+	pool.statusMutex.RLock()
+	defer pool.statusMutex.RUnlock()
+
+	if !pool.isRunning() {
+		return ErrPoolNotRunning
+	}
+	if index < 0 || index >= len(pool.workers) {
+		return ErrWorkerIndex
+	}
+	wrapper := pool.workers[index]
+
+	if !pool.reserveStops(1) {
+		if atomic.LoadUint32(&wrapper.stopped) == 1 {
+			return ErrWorkerStopped
+		}
+		return ErrLastWorker
+	}
+	if !atomic.CompareAndSwapUint32(&wrapper.stopped, 0, 1) {
+		atomic.AddInt32(&pool.stoppingWorkers, -1)
+		return ErrWorkerStopped
+	}
+	atomic.AddInt32(&pool.stoppedWorkers, 1)
+
+	if wrapper.stop() {
+		atomic.AddInt32(&pool.startedWorkers, -1)
+	}
+	return nil
+}
+
+/*
+reserveStops - Reserves n workers to be stopped, returns false rather than leave the pool without
+workers. stoppingWorkers counts the workers stopped or being stopped, whereas stoppedWorkers only
+counts them from when they are no longer available.
+*/
+func (pool *WorkPool) reserveStops(n int) bool {
+	for {
+		stopping := atomic.LoadInt32(&pool.stoppingWorkers)
+		if int(stopping)+n >= len(pool.workers) {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&pool.stoppingWorkers, stopping, stopping+int32(n)) {
+			return true
+		}
+	}
+}
+
+/*
+stop - Stops a worker of a running pool and waits for it to terminate, returning whether it had
+been started. Unlike Close the job channel stays open, as a caller may have taken the worker's
+ready signal just before it was stopped and will hand it a job.
+*/
+func (wrapper *workerWrapper) stop() bool {
+	if atomic.CompareAndSwapUint32(&wrapper.poolOpen, 1, 0) {
+		close(wrapper.closing)
+	}
+
+	// Start is serialised on the worker mutex, so a worker is either started by now or will
+	// see that it has been stopped
+	wrapper.workerMutex.Lock()
+	started := atomic.LoadUint32(&wrapper.started) == 1
+	done := wrapper.done
+	wrapper.workerMutex.Unlock()
+
+	if !started {
+		return false
+	}
+	<-done
+
+	// The worker may have retired on its own, in which case it has been counted already
+	return atomic.CompareAndSwapUint32(&wrapper.started, 1, 0)
+}
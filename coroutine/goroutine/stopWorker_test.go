@@ -0,0 +1,115 @@
+package goroutine
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStopWorker(t *testing.T) {
+	counters := []*countingExtWorker{{}, {}, {}}
+	pool, err := CreateCustomPool([]GoroutineWorker{counters[0], counters[1], counters[2]}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	finished := make(chan error, 1)
+	pool.SendWorkAsync(func() {
+		close(started)
+		<-release
+	}, func(_ interface{}, err error) {
+		finished <- err
+	})
+	<-started
+
+	busy := -1
+	for _, status := range pool.Snapshot() {
+		if status.State == WorkerRunning && status.CurrentJobDuration > 0 {
+			busy = status.Index
+		}
+	}
+	if busy < 0 {
+		t.Errorf("Expected a worker to be running the job, got %+v", pool.Snapshot())
+		return
+	}
+
+	// The busy worker is stopped once its job is done, the others keep serving jobs meanwhile
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- pool.StopWorker(busy)
+	}()
+	for i := 0; i < 10; i++ {
+		if result, err := pool.SendWork(i); err != nil || result != i {
+			t.Errorf("Expected %v, got %v, %v", i, result, err)
+		}
+	}
+	select {
+	case err := <-stopped:
+		t.Errorf("Expected StopWorker to wait for the current job, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Errorf("Failed to stop worker: %v", err)
+	}
+	if err := <-finished; err != nil {
+		t.Errorf("Expected the running job to complete, got %v", err)
+	}
+	if n := atomic.LoadInt32(&counters[busy].terminated); n != 1 {
+		t.Errorf("Expected the stopped worker to be terminated once, got %v", n)
+	}
+	if n := pool.NumWorkers(); n != 2 {
+		t.Errorf("Expected 2 workers, got %v", n)
+	}
+	if state := pool.Snapshot()[busy].State; state != WorkerStopped {
+		t.Errorf("Expected the worker to be stopped, got %v", state)
+	}
+
+	// Jobs are no longer run by the stopped worker
+	before := pool.Snapshot()[busy].TotalJobsCompleted
+	for i := 0; i < 20; i++ {
+		if _, err := pool.SendWork(i); err != nil {
+			t.Errorf("Failed to send work: %v", err)
+		}
+	}
+	if completed := pool.Snapshot()[busy].TotalJobsCompleted; completed != before {
+		t.Errorf("Expected no jobs on the stopped worker, it completed %v", completed-before)
+	}
+
+	if err := pool.StopWorker(busy); err != ErrWorkerStopped {
+		t.Errorf("Expected ErrWorkerStopped, got %v", err)
+	}
+	if err := pool.StopWorker(3); err != ErrWorkerIndex {
+		t.Errorf("Expected ErrWorkerIndex, got %v", err)
+	}
+	if err := pool.StopWorker((busy + 1) % 3); err != nil {
+		t.Errorf("Failed to stop worker: %v", err)
+	}
+	if err := pool.StopWorker((busy + 2) % 3); err != ErrLastWorker {
+		t.Errorf("Expected ErrLastWorker, got %v", err)
+	}
+	if result, err := pool.SendWork("last"); err != nil || result != "last" {
+		t.Errorf("Expected the last worker to serve jobs, got %v, %v", result, err)
+	}
+
+	// Stopped workers stay stopped across a reopen
+	pool.Close()
+	if _, err := pool.Open(); err != nil {
+		t.Errorf("Failed to reopen pool: %v", err)
+		return
+	}
+	if n := pool.NumWorkers(); n != 1 {
+		t.Errorf("Expected 1 worker after reopening, got %v", n)
+	}
+	if result, err := pool.SendWork("reopened"); err != nil || result != "reopened" {
+		t.Errorf("Expected the pool to serve jobs after reopening, got %v, %v", result, err)
+	}
+	if n := atomic.LoadInt32(&counters[busy].initialized); n != 1 {
+		t.Errorf("Expected the stopped worker not to be started again, initialized %v times", n)
+	}
+}
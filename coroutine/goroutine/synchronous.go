@@ -0,0 +1,71 @@
+package goroutine
+
+import (
+	"context"
+	"time"
+)
+
+/*
+CreatePoolSynchronous - Creates a pool without workers that runs every job on the goroutine that
+submits it, for deterministic tests of code built on a pool. SendWork and the other synchronous
+calls run the job inline, SendWorkAsync runs the job inline and calls its callback before
+returning, and the trace function, Stats and metrics are updated as for any other pool. The pool
+has no workers, so NumWorkers reports 0.
+
+A job submitted with a timeout, or with a context that can be cancelled, runs on a goroutine of its
+own so that the timeout is still honoured, the caller gives up on it exactly as it would on a
+worker.
+*/
+func CreatePoolSynchronous(job func(interface{}) interface{}, opts ...Option) *WorkPool {
+	pool := CreatePool(0, job, opts...)
+	pool.synchronous = true
+	pool.inlineJob = job
+	return pool
+}
+
+/*
+runSynchronous - Runs a job of a synchronous pool, which has already passed the pool's limits.
+Without a timeout or cancellation the job runs inline, otherwise the caller waits for it on a
+goroutine until it gives up.
+*/
+func (pool *WorkPool) runSynchronous(ctx context.Context, job jobRequest, trace TraceFunc, enqueued time.Time, timeout <-chan time.Time) (interface{}, error) {
+	dispatched := pool.adaptive.now()
+	release := func() {
+		pool.adaptive.release(dispatched)
+		pool.releaseSemaphore()
+		pool.groups.release("")
+	}
+
+	cancel := ctx.Done()
+	if timeout == nil && cancel == nil {
+		defer release()
+		return pool.runOnCaller(job, trace, enqueued)
+	}
+
+	results := make(chan jobResult, 1)
+	go func() {
+		results <- pool.runInline(job)
+	}()
+
+	select {
+	case result := <-results:
+		release()
+		pool.finishJob(trace, job, -1, enqueued, result, JobOK)
+		if result.panicked {
+			return nil, result.panicError()
+		}
+		return result.data, result.err
+	case <-timeout:
+	case <-cancel:
+	}
+
+	err, outcome := expired(ctx, job, true)
+	pool.inFlight.extend()
+	go func() {
+		result := <-results
+		release()
+		pool.inFlight.leave()
+		pool.finishJob(trace, job, -1, enqueued, result, outcome)
+	}()
+	return nil, err
+}
@@ -0,0 +1,101 @@
+package goroutine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSynchronousPool(t *testing.T) {
+	pool, err := CreatePoolSynchronous(func(in interface{}) interface{} {
+		if d, ok := in.(time.Duration); ok {
+			time.Sleep(d)
+		}
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if pool.NumWorkers() != 0 {
+		t.Errorf("Expected a synchronous pool to report no workers, got %v", pool.NumWorkers())
+	}
+
+	if result, err := pool.SendWork("inline"); err != nil || result != "inline" {
+		t.Errorf("Expected the job to run inline, got %v, %v", result, err)
+	}
+
+	// The callback has been called by the time SendWorkAsync returns
+	var order []string
+	pool.SendWorkAsync("async", func(result interface{}, err error) {
+		order = append(order, result.(string))
+	})
+	order = append(order, "returned")
+	if strings.Join(order, ",") != "async,returned" {
+		t.Errorf("Expected the callback before SendWorkAsync returned, got %v", order)
+	}
+	if pending := pool.NumPendingAsyncJobs(); pending != 0 {
+		t.Errorf("Expected no pending jobs, got %v", pending)
+	}
+
+	// Timed calls still time out
+	if _, err := pool.SendWorkTimed(10*time.Millisecond, 50*time.Millisecond); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut, got %v", err)
+	}
+	if result, err := pool.SendWorkTimed(1000*time.Millisecond, time.Duration(0)); err != nil || result != time.Duration(0) {
+		t.Errorf("Expected the timed job to complete, got %v, %v", result, err)
+	}
+
+	if result, err := pool.Do(context.Background(), func() (interface{}, error) {
+		return "do", nil
+	}); err != nil || result != "do" {
+		t.Errorf("Expected Do to run the closure, got %v, %v", result, err)
+	}
+}
+
+// Example of a table driven test of code that uses a pool, with a synchronous pool every case
+// runs to completion in order
+func TestSynchronousPoolTable(t *testing.T) {
+	upper := func(pool *WorkPool, words []string) []string {
+		var out []string
+		for _, word := range words {
+			pool.SendWorkAsync(word, func(result interface{}, err error) {
+				out = append(out, result.(string))
+			})
+		}
+		return out
+	}
+
+	pool, err := CreatePoolSynchronous(func(in interface{}) interface{} {
+		return strings.ToUpper(in.(string))
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	for _, test := range []struct {
+		words []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"a"}, "A"},
+		{[]string{"a", "b", "c"}, "A,B,C"},
+	} {
+		if got := strings.Join(upper(pool, test.words), ","); got != test.want {
+			t.Errorf("Expected %q for %v, got %q", test.want, test.words, got)
+		}
+	}
+
+	traces := 0
+	pool.SetTraceFunc(func(JobTrace) { traces++ })
+	upper(pool, []string{"x", "y"})
+	if traces != 2 {
+		t.Errorf("Expected every job to be traced, got %v", traces)
+	}
+}
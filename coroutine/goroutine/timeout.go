@@ -0,0 +1,60 @@
+package goroutine
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+/*
+WithDefaultTimeout - Sets a timeout which SendWork applies to every job, as if each call were made
+with SendWorkTimed, so that callers of SendWork are protected from stalled workers. SendWorkTimed
+and the other calls taking a timeout or context still use their own. A timeout of zero, the
+default, leaves SendWork waiting for as long as the job takes. Jobs sent with a timeout are never
+run on the caller by WithCallerRunsPolicy.
+*/
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(pool *WorkPool) {
+		pool.SetTimeout(timeout)
+	}
+}
+
+/*
+Timeout - The timeout SendWork applies to every job, zero if there is none.
+*/
+func (pool *WorkPool) Timeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&pool.defaultTimeout))
+}
+
+/*
+SetTimeout - Changes the timeout SendWork applies to every job, it may be called at any time and
+takes effect for jobs submitted afterwards. Zero or less removes the timeout.
+*/
+func (pool *WorkPool) SetTimeout(timeout time.Duration) {
+	if timeout < 0 {
+		timeout = 0
+	}
+	atomic.StoreInt64(&pool.defaultTimeout, int64(timeout))
+}
+
+/*
+defaultDeadline - The deadline of a job sent with SendWork now, zero if there is no default
+timeout.
+*/
+func (pool *WorkPool) defaultDeadline() time.Time {
+	if timeout := pool.Timeout(); timeout > 0 {
+		return pool.clock.Now().Add(timeout)
+	}
+	return time.Time{}
+}
+
+/*
+sendWorkDefault - Sends a job for SendWork, giving up at the deadline of the default timeout if
+one is set.
+*/
+func (pool *WorkPool) sendWorkDefault(deadline time.Time, jobData interface{}) (interface{}, error) {
+	if !deadline.IsZero() {
+		return pool.sendWorkUntil(context.Background(), deadline, jobData)
+	}
+	return pool.sendWork("", pool.callerRuns, jobData)
+}
@@ -0,0 +1,36 @@
+package goroutine
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDefaultTimeout(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		time.Sleep(in.(time.Duration))
+		return in
+	}, WithDefaultTimeout(10*time.Millisecond)).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if timeout := pool.Timeout(); timeout != 10*time.Millisecond {
+		t.Errorf("Expected a 10ms timeout, got %v", timeout)
+	}
+	if _, err := pool.SendWork(50 * time.Millisecond); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected SendWork to time out, got %v", err)
+	}
+
+	// A per call timeout overrides the default
+	if result, err := pool.SendWorkTimed(1000*time.Millisecond, 20*time.Millisecond); err != nil || result != 20*time.Millisecond {
+		t.Errorf("Expected SendWorkTimed to use its own timeout, got %v, %v", result, err)
+	}
+
+	pool.SetTimeout(0)
+	if result, err := pool.SendWork(20 * time.Millisecond); err != nil || result != 20*time.Millisecond {
+		t.Errorf("Expected SendWork to wait without a timeout, got %v, %v", result, err)
+	}
+}
@@ -0,0 +1,43 @@
+package goroutine
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+timerPool - Timers reused across timed submissions, so that a job which completes quickly hands
+its timer back straight away rather than leaving it to fire and be collected. Only timers of the
+real clock are pooled.
+*/
+var timerPool sync.Pool
+
+/*
+getTimer - Takes a timer of the clock set to fire after d.
+*/
+func getTimer(clock Clock, d time.Duration) Timer {
+	if _, ok := clock.(realClock); !ok {
+		return clock.NewTimer(d)
+	}
+	if timer, ok := timerPool.Get().(*realTimer); ok {
+		timer.Reset(d)
+		return timer
+	}
+	return clock.NewTimer(d)
+}
+
+/*
+putTimer - Stops a timer and returns it to the pool. A tick left unreceived by a timer that has
+already fired is drained, so that the next user of the timer does not time out straight away.
+*/
+func putTimer(timer Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C():
+		default:
+		}
+	}
+	if timer, ok := timer.(*realTimer); ok {
+		timerPool.Put(timer)
+	}
+}
@@ -0,0 +1,58 @@
+package goroutine
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSendWorkTimedReusesTimers(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		time.Sleep(in.(time.Duration))
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	// Jobs finishing just as their timer fires get exactly one outcome, and the timer they
+	// hand back must not carry a stale tick into the next call
+	for i := 0; i < 200; i++ {
+		result, err := pool.SendWorkTimed(time.Millisecond, time.Millisecond)
+		if err == nil && result != time.Millisecond {
+			t.Errorf("Expected the job result, got %v", result)
+		}
+		if err != nil && (!errors.Is(err, ErrJobTimedOut) || result != nil) {
+			t.Errorf("Expected ErrJobTimedOut, got %v, %v", result, err)
+		}
+
+		if result, err := pool.SendWorkTimed(1000*time.Millisecond, time.Duration(0)); err != nil || result != time.Duration(0) {
+			t.Errorf("Expected a fresh timer for the next call, got %v, %v", result, err)
+		}
+	}
+}
+
+/*
+BenchmarkSendWorkTimedMillion - Soak of one million fast timed calls per op, a fast call should
+hand its timer back rather than leave one per call waiting to fire.
+*/
+func BenchmarkSendWorkTimedMillion(b *testing.B) {
+	pool, err := CreatePool(4, func(in interface{}) interface{} {
+		return in
+	}).Open()
+	if err != nil {
+		b.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 1000000; j++ {
+			pool.SendWorkTimed(1000*time.Millisecond, 10)
+		}
+	}
+}
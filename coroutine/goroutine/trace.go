@@ -0,0 +1,99 @@
+package goroutine
+
+import (
+	"time"
+)
+
+/*
+JobOutcome - Describes how a job submitted to the pool ended.
+*/
+type JobOutcome int
+
+const (
+	// JobOK - The job ran to completion and its result was delivered.
+	JobOK JobOutcome = iota
+
+	// JobTimedOut - The job did not complete before its timeout.
+	JobTimedOut
+
+	// JobPanicked - The job panicked while running on a worker.
+	JobPanicked
+
+	// JobCancelled - The job was abandoned by the client.
+	JobCancelled
+)
+
+func (o JobOutcome) String() string {
+	switch o {
+	case JobOK:
+		return "ok"
+	case JobTimedOut:
+		return "timeout"
+	case JobPanicked:
+		return "panic"
+	case JobCancelled:
+		return "cancelled"
+	}
+	return "unknown"
+}
+
+/*
+JobTrace - The timeline of a single job. All timestamps are taken from the pool's clock, which
+by default is time.Now() and so carries monotonic clock readings, Started and Finished are zero if
+the job never reached a worker.
+*/
+type JobTrace struct {
+	Seq      uint64
+	Worker   int
+	Enqueued time.Time
+	Started  time.Time
+	Finished time.Time
+	Outcome  JobOutcome
+
+	// AllocBytes is the approximate heap allocation of the job when AllocSampled is set, see
+	// WithAllocSampling
+	AllocBytes   uint64
+	AllocSampled bool
+}
+
+/*
+TraceFunc - Receives the trace of every job once it has completed.
+*/
+type TraceFunc func(JobTrace)
+
+/*
+SetTraceFunc - Sets the function called with the trace of each job after it completes, or
+removes it if nil. The trace function is never called on a worker goroutine, it runs on the
+goroutine that submitted the job (or the goroutine collecting an abandoned result) so that a
+slow trace function cannot stall dispatch.
+*/
+func (pool *WorkPool) SetTraceFunc(trace func(JobTrace)) {
+	pool.traceFunc.Store(TraceFunc(trace))
+}
+
+func (pool *WorkPool) getTraceFunc() TraceFunc {
+	trace, _ := pool.traceFunc.Load().(TraceFunc)
+	return trace
+}
+
+/*
+traceJob - Reports the trace of a job if tracing is enabled.
+*/
+func traceJob(trace TraceFunc, job jobRequest, worker int, enqueued time.Time, result jobResult, outcome JobOutcome) {
+	if trace == nil {
+		return
+	}
+	if result.panicked && outcome == JobOK {
+		outcome = JobPanicked
+	}
+	trace(JobTrace{
+		Seq:          job.seq,
+		Worker:       worker,
+		Enqueued:     enqueued,
+		Started:      result.started,
+		Finished:     result.finished,
+		Outcome:      outcome,
+		AllocBytes:   result.allocBytes,
+		AllocSampled: result.allocSampled,
+	})
+}
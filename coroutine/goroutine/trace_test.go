@@ -0,0 +1,186 @@
+package goroutine
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTraceFunc(t *testing.T) {
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		switch in {
+		case "panic":
+			panic("job panicked")
+		case "slow":
+			time.Sleep(50 * time.Millisecond)
+		}
+		return in
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	tracesMutex := sync.Mutex{}
+	traces := []JobTrace{}
+	gotTrace := make(chan struct{}, 10)
+	pool.SetTraceFunc(func(trace JobTrace) {
+		tracesMutex.Lock()
+		traces = append(traces, trace)
+		tracesMutex.Unlock()
+		gotTrace <- struct{}{}
+	})
+
+	if _, err := pool.SendWork("ok"); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	if _, err := pool.SendWork("panic"); !errors.Is(err, ErrJobPanicked) {
+		t.Errorf("Expected ErrJobPanicked, got %v", err)
+	}
+	if _, err := pool.SendWorkTimed(10*time.Millisecond, "slow"); !errors.Is(err, ErrJobTimedOut) {
+		t.Errorf("Expected ErrJobTimedOut, got %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-gotTrace:
+		case <-time.After(time.Second):
+			t.Errorf("Timed out waiting for trace %v", i)
+			return
+		}
+	}
+
+	tracesMutex.Lock()
+	defer tracesMutex.Unlock()
+
+	expected := []JobOutcome{JobOK, JobPanicked, JobTimedOut}
+	for i, trace := range traces {
+		if trace.Outcome != expected[i] {
+			t.Errorf("Wrong outcome for job %v: %v != %v", i, expected[i], trace.Outcome)
+		}
+		if trace.Seq != uint64(i+1) {
+			t.Errorf("Wrong sequence for job %v: %v != %v", i, i+1, trace.Seq)
+		}
+		if trace.Worker != 0 {
+			t.Errorf("Wrong worker for job %v: %v", i, trace.Worker)
+		}
+		if trace.Started.Before(trace.Enqueued) || trace.Finished.Before(trace.Started) {
+			t.Errorf("Timestamps out of order for job %v: %+v", i, trace)
+		}
+	}
+	if len(traces) == 3 && traces[2].Finished.Sub(traces[2].Started) < 50*time.Millisecond {
+		t.Errorf("Expected the late result of the timed out job to be traced, got %+v", traces[2])
+	}
+
+	// Removing the trace function stops tracing
+	pool.SetTraceFunc(nil)
+	if _, err := pool.SendWork("ok"); err != nil {
+		t.Errorf("Failed to send work: %v", err)
+	}
+	if len(traces) != 3 {
+		t.Errorf("Expected no trace after removing the trace function, got %v", len(traces))
+	}
+}
+
+func TestTraceFuncUntracedAllocs(t *testing.T) {
+	newPool := func() *WorkPool {
+		pool, err := CreatePool(1, func(in interface{}) interface{} {
+			return in
+		}).Open()
+		if err != nil {
+			t.Fatalf("Failed to create pool: %v", err)
+		}
+		return pool
+	}
+
+	// A pool which never traced is the baseline, one whose trace function was removed is untraced
+	baseline := newPool()
+	defer baseline.Close()
+	untraced := newPool()
+	defer untraced.Close()
+	untraced.SetTraceFunc(func(JobTrace) {})
+	untraced.SetTraceFunc(nil)
+
+	baselineAllocs := testing.AllocsPerRun(1000, func() {
+		baseline.SendWork(10)
+	})
+	untracedAllocs := testing.AllocsPerRun(1000, func() {
+		untraced.SendWork(10)
+	})
+	if untracedAllocs > baselineAllocs {
+		t.Errorf("Expected no additional allocations when untraced, got %v over a baseline of %v", untracedAllocs, baselineAllocs)
+	}
+
+	if allocs := testing.AllocsPerRun(1000, func() {
+		traceJob(nil, jobRequest{}, 0, time.Time{}, jobResult{}, JobOK)
+	}); allocs != 0 {
+		t.Errorf("Expected reporting to a nil trace function not to allocate, got %v", allocs)
+	}
+}
+
+func benchmarkSendWork(b *testing.B, trace func(JobTrace)) {
+	pool, err := CreatePool(4, func(in interface{}) interface{} {
+		return in
+	}).Open()
+	if err != nil {
+		b.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if trace != nil {
+		pool.SetTraceFunc(trace)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pool.SendWork(10)
+	}
+}
+
+func BenchmarkSendWorkUntraced(b *testing.B) {
+	benchmarkSendWork(b, nil)
+}
+
+func BenchmarkSendWorkTraced(b *testing.B) {
+	benchmarkSendWork(b, func(JobTrace) {})
+}
+
+func BenchmarkSendWorkParallel(b *testing.B) {
+	pool, err := CreatePool(4, func(in interface{}) interface{} {
+		return in
+	}).Open()
+	if err != nil {
+		b.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pool.SendWork(10)
+		}
+	})
+}
+
+func BenchmarkSendWorkTimed(b *testing.B) {
+	pool, err := CreatePool(4, func(in interface{}) interface{} {
+		return in
+	}).Open()
+	if err != nil {
+		b.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pool.SendWorkTimed(1000*time.Millisecond, 10)
+	}
+}
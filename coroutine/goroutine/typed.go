@@ -0,0 +1,135 @@
+package goroutine
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrPayloadType = errors.New("the pool was not created for this payload type")
+)
+
+/*
+CreateIntPool - Creates a pool whose job takes and returns an int, for SendWorkInt. Boxing a small
+value into an interface{} allocates, which dominates the cost of submitting very small jobs, and
+SendWorkInt hands the int to the job without ever boxing it. The pool is an ordinary pool
+otherwise, SendWork and the other calls still work with int payloads, and timeouts, limits, stats
+and Close apply to both alike.
+*/
+func CreateIntPool(numWorkers int, job func(int) int, opts ...Option) *WorkPool {
+	var boxed func(interface{}) interface{}
+	if job != nil {
+		boxed = func(in interface{}) interface{} {
+			return job(in.(int))
+		}
+	}
+	pool := CreatePool(numWorkers, boxed, opts...)
+	pool.intJob = job
+	return pool
+}
+
+/*
+CreateStringPool - Creates a pool whose job takes and returns a string, for SendWorkString, see
+CreateIntPool.
+*/
+func CreateStringPool(numWorkers int, job func(string) string, opts ...Option) *WorkPool {
+	var boxed func(interface{}) interface{}
+	if job != nil {
+		boxed = func(in interface{}) interface{} {
+			return job(in.(string))
+		}
+	}
+	pool := CreatePool(numWorkers, boxed, opts...)
+	pool.stringJob = job
+	return pool
+}
+
+/*
+SendWorkInt - Send an int to a worker of a pool created with CreateIntPool and return the result as
+SendWork does, without allocating. Only checks which inspect the payload, such as WithAdmissionFunc
+or WithPayloadLimit, are given it boxed. ErrPayloadType is returned for other pools.
+*/
+func (pool *WorkPool) SendWorkInt(n int) (int, error) {
+	if pool.intJob == nil {
+		return 0, ErrPayloadType
+	}
+	call := typedCalls.Get().(*typedCall)
+	call.intJob, call.num = pool.intJob, n
+
+	if _, err := pool.send(call); err != nil {
+		// A job given up on may still be running, so the call is left to the garbage collector
+		return 0, err
+	}
+	result := call.num
+	call.reset()
+	return result, nil
+}
+
+/*
+SendWorkString - Send a string to a worker of a pool created with CreateStringPool and return the
+result, see SendWorkInt.
+*/
+func (pool *WorkPool) SendWorkString(s string) (string, error) {
+	if pool.stringJob == nil {
+		return "", ErrPayloadType
+	}
+	call := typedCalls.Get().(*typedCall)
+	call.stringJob, call.str = pool.stringJob, s
+
+	if _, err := pool.send(call); err != nil {
+		return "", err
+	}
+	result := call.str
+	call.reset()
+	return result, nil
+}
+
+/*
+typedCall - The payload of SendWorkInt and SendWorkString. It travels through the pool as a pointer,
+which an interface{} holds without allocating, and the worker runs it in place of the pool's job,
+as it does a jobCall, writing the result back into the call.
+*/
+type typedCall struct {
+	intJob    func(int) int
+	stringJob func(string) string
+	num       int
+	str       string
+}
+
+var typedCalls = sync.Pool{
+	New: func() interface{} {
+		return new(typedCall)
+	},
+}
+
+func (call *typedCall) run() {
+	if call.intJob != nil {
+		call.num = call.intJob(call.num)
+		return
+	}
+	call.str = call.stringJob(call.str)
+}
+
+// boxed returns the payload as an interface{}, for the checks which inspect payloads
+func (call *typedCall) boxed() interface{} {
+	if call.intJob != nil {
+		return call.num
+	}
+	return call.str
+}
+
+func (call *typedCall) reset() {
+	*call = typedCall{}
+	typedCalls.Put(call)
+}
+
+/*
+inspected - The payload of a job as it is given to the checks which inspect payloads, typed calls
+are boxed.
+*/
+func inspected(jobData interface{}) interface{} {
+	if call, ok := jobData.(*typedCall); ok {
+		return call.boxed()
+	}
+	return jobData
+}
@@ -0,0 +1,126 @@
+package goroutine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSendWorkTyped(t *testing.T) {
+	intPool, err := CreateIntPool(2, func(n int) int {
+		return n * 2
+	}).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer intPool.Close()
+
+	stringPool, err := CreateStringPool(2, strings.TrimSpace).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer stringPool.Close()
+
+	for i := 0; i < 10; i++ {
+		if result, err := intPool.SendWorkInt(i); err != nil || result != i*2 {
+			t.Errorf("Expected %v, got %v, %v", i*2, result, err)
+		}
+	}
+	if result, err := stringPool.SendWorkString(" abc "); err != nil || result != "abc" {
+		t.Errorf("Expected abc, got %v, %v", result, err)
+	}
+
+	// The generic calls still work on typed pools
+	if result, err := intPool.SendWork(21); err != nil || result != 42 {
+		t.Errorf("Expected 42, got %v, %v", result, err)
+	}
+
+	if _, err := stringPool.SendWorkInt(1); err != ErrPayloadType {
+		t.Errorf("Expected ErrPayloadType, got %v", err)
+	}
+	if _, err := intPool.SendWorkString("a"); err != ErrPayloadType {
+		t.Errorf("Expected ErrPayloadType, got %v", err)
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { intPool.SendWorkInt(10) }); allocs != 0 {
+		t.Errorf("Expected SendWorkInt not to allocate, got %v allocs", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { stringPool.SendWorkString("abc") }); allocs != 0 {
+		t.Errorf("Expected SendWorkString not to allocate, got %v allocs", allocs)
+	}
+}
+
+func TestSendWorkTypedChecks(t *testing.T) {
+	var seen interface{}
+	pool, err := CreateIntPool(1, func(n int) int {
+		return n
+	}, WithPayloadLimit(func(in interface{}) error {
+		seen = in
+		return nil
+	})).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	if _, err := pool.SendWorkInt(5); err != nil {
+		t.Errorf("Failed to send job: %v", err)
+	}
+	if seen != 5 {
+		t.Errorf("Expected the payload check to see 5, got %v", seen)
+	}
+}
+
+func BenchmarkSendWorkInt(b *testing.B) {
+	pool, err := CreateIntPool(4, func(n int) int {
+		return n
+	}).Open()
+	if err != nil {
+		b.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pool.SendWorkInt(i)
+	}
+}
+
+func BenchmarkSendWorkString(b *testing.B) {
+	pool, err := CreateStringPool(4, func(s string) string {
+		return s
+	}).Open()
+	if err != nil {
+		b.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pool.SendWorkString("job")
+	}
+}
+
+// BenchmarkSendWorkIntGeneric - SendWork with int payloads, for comparison with BenchmarkSendWorkInt
+func BenchmarkSendWorkIntGeneric(b *testing.B) {
+	pool, err := CreateIntPool(4, func(n int) int {
+		return n
+	}).Open()
+	if err != nil {
+		b.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pool.SendWork(i)
+	}
+}
@@ -0,0 +1,167 @@
+package goroutine
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+WithStuckWorkerDetection - Watches for jobs which run for longer than threshold, calling onStuck
+with the index of the worker, the sequence number of the job and how long it has been running. The
+callback is called once when a job crosses the threshold and, if repeat is positive, again every
+repeat for as long as the job keeps running. The worker is reported by StuckWorkers() until its
+job completes. The callback runs on the watchdog goroutine and should not block.
+*/
+func WithStuckWorkerDetection(
+	threshold, repeat time.Duration,
+	onStuck func(workerIndex int, jobSeq uint64, runningFor time.Duration),
+) Option {
+	return func(pool *WorkPool) {
+		if threshold <= 0 {
+			return
+		}
+		pool.watchdog = &stuckWatchdog{
+			threshold: threshold,
+			repeat:    repeat,
+			onStuck:   onStuck,
+		}
+	}
+}
+
+/*
+stuckWatchdog - Settings of the stuck worker detection of a pool, the watchdog goroutine runs
+while the pool is open.
+*/
+type stuckWatchdog struct {
+	threshold time.Duration
+	repeat    time.Duration
+	onStuck   func(workerIndex int, jobSeq uint64, runningFor time.Duration)
+	stop      chan struct{}
+	clock     Clock
+}
+
+/*
+runningJob - The job a watched worker is running, guarded by its own mutex since the watchdog
+reads it while the job runs.
+*/
+type runningJob struct {
+	mutex    sync.Mutex
+	seq      uint64
+	started  time.Time
+	stuck    bool
+	reported time.Time
+}
+
+func (running *runningJob) begin(seq uint64, now time.Time) {
+	running.mutex.Lock()
+	running.seq = seq
+	running.started = now
+	running.mutex.Unlock()
+}
+
+func (running *runningJob) end() {
+	running.mutex.Lock()
+	running.started = time.Time{}
+	running.stuck = false
+	running.mutex.Unlock()
+}
+
+/*
+check - Returns the job to report if it has been running for longer than the threshold and has
+not been reported within the repeat interval.
+*/
+func (running *runningJob) check(now time.Time, threshold, repeat time.Duration) (uint64, time.Duration, bool) {
+	running.mutex.Lock()
+	defer running.mutex.Unlock()
+
+	if running.started.IsZero() {
+		return 0, 0, false
+	}
+	runningFor := now.Sub(running.started)
+	if runningFor < threshold {
+		return 0, 0, false
+	}
+	if running.stuck && (repeat <= 0 || now.Sub(running.reported) < repeat) {
+		return 0, 0, false
+	}
+	running.stuck = true
+	running.reported = now
+	return running.seq, runningFor, true
+}
+
+func (running *runningJob) isStuck() bool {
+	running.mutex.Lock()
+	defer running.mutex.Unlock()
+
+	return running.stuck
+}
+
+/*
+start - Launches the watchdog goroutine over the workers of an opening pool.
+*/
+func (watchdog *stuckWatchdog) start(workers []*workerWrapper) {
+	if watchdog == nil {
+		return
+	}
+
+	interval := watchdog.threshold
+	if watchdog.repeat > 0 && watchdog.repeat < interval {
+		interval = watchdog.repeat
+	}
+	interval /= 4
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+
+	watchdog.stop = make(chan struct{})
+	go watchdog.loop(workers, interval, watchdog.stop)
+}
+
+/*
+close - Stops the watchdog goroutine, a callback in progress is allowed to finish on its own.
+*/
+func (watchdog *stuckWatchdog) close() {
+	if watchdog == nil {
+		return
+	}
+	close(watchdog.stop)
+}
+
+func (watchdog *stuckWatchdog) loop(workers []*workerWrapper, interval time.Duration, stop chan struct{}) {
+	// A timer set again after each check rather than a ticker, so that a fake clock drives it
+	timer := watchdog.clock.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-timer.C():
+			timer.Reset(interval)
+			for i, workerWrapper := range workers {
+				seq, runningFor, stuck := workerWrapper.running.check(now, watchdog.threshold, watchdog.repeat)
+				if stuck && watchdog.onStuck != nil {
+					watchdog.onStuck(i, seq, runningFor)
+				}
+			}
+		}
+	}
+}
+
+/*
+StuckWorkers - Returns the indexes of the workers whose current job has been running for longer
+than the threshold given to WithStuckWorkerDetection, a worker is removed once its job completes.
+*/
+func (pool *WorkPool) StuckWorkers() []int {
+	if pool.watchdog == nil {
+		return nil
+	}
+
+	var stuck []int
+	for i, workerWrapper := range pool.lockFreeWorkers() {
+		if workerWrapper.running.isStuck() {
+			stuck = append(stuck, i)
+		}
+	}
+	return stuck
+}
@@ -0,0 +1,97 @@
+package goroutine
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStuckWorkerDetection(t *testing.T) {
+	release := make(chan struct{})
+
+	var mutex sync.Mutex
+	var reports []uint64
+
+	pool, err := CreatePool(2, func(in interface{}) interface{} {
+		if in == "block" {
+			<-release
+		} else {
+			time.Sleep(5 * time.Millisecond)
+		}
+		return in
+	}, WithStuckWorkerDetection(40*time.Millisecond, 0, func(workerIndex int, jobSeq uint64, runningFor time.Duration) {
+		if runningFor < 40*time.Millisecond {
+			t.Errorf("Reported a job running for only %v", runningFor)
+		}
+		mutex.Lock()
+		reports = append(reports, jobSeq)
+		mutex.Unlock()
+	})).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	done := make(chan struct{})
+	go func() {
+		pool.SendWork("block")
+		close(done)
+	}()
+
+	// Short jobs on the other worker must not be reported
+	for i := 0; i < 10; i++ {
+		if _, err := pool.SendWork("short"); err != nil {
+			t.Errorf("Failed to send work: %v", err)
+		}
+	}
+
+	time.Sleep(120 * time.Millisecond)
+
+	if stuck := pool.StuckWorkers(); len(stuck) != 1 {
+		t.Errorf("Expected one stuck worker, got %v", stuck)
+	}
+	mutex.Lock()
+	if len(reports) != 1 {
+		t.Errorf("Expected one report for the blocked job, got %v", reports)
+	}
+	mutex.Unlock()
+
+	close(release)
+	<-done
+
+	if stuck := pool.StuckWorkers(); len(stuck) != 0 {
+		t.Errorf("Expected the stuck flag to clear, got %v", stuck)
+	}
+}
+
+func TestStuckWorkerRepeat(t *testing.T) {
+	release := make(chan struct{})
+	reported := make(chan uint64, 16)
+
+	pool, err := CreatePool(1, func(in interface{}) interface{} {
+		<-release
+		return in
+	}, WithStuckWorkerDetection(10*time.Millisecond, 10*time.Millisecond, func(workerIndex int, jobSeq uint64, runningFor time.Duration) {
+		select {
+		case reported <- jobSeq:
+		default:
+		}
+	})).Open()
+	if err != nil {
+		t.Errorf("Failed to create pool: %v", err)
+		return
+	}
+	defer pool.Close()
+
+	go pool.SendWork(nil)
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-reported:
+		case <-time.After(time.Second):
+			t.Errorf("Expected repeated reports, got %v", i)
+		}
+	}
+	close(release)
+}
@@ -1,87 +1,506 @@
 package goroutine
 
 import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
+/*
+jobRequest - A job as it is handed to a worker, this is passed by value so that dispatching
+allocates nothing beyond the job data itself.
+*/
+type jobRequest struct {
+	data interface{}
+	seq  uint64
+
+	// ctx is passed to workers implementing GoroutineContextWorker, nil for jobs submitted
+	// without a context
+	ctx context.Context
+
+	// returned marks a borrowed worker being handed back, there is no job to run
+	returned bool
+
+	// uncollected marks a job whose result nobody will receive, see SetDiscardedResultHandler
+	uncollected bool
+
+	// sampleAllocs marks a job whose allocations are measured, see WithAllocSampling
+	sampleAllocs bool
+
+	// progress passes on the progress of a job sent with SendWorkAsyncProgress, nil otherwise
+	progress *progressReporter
+
+	// pending tracks a job of SendWorkAsync on a pool with a pending codec, nil otherwise
+	pending *pendingEntry
+
+	// tracked tracks the job for PendingJobs and RunningJobs, nil without WithJobInspection
+	tracked *trackedJob
+
+	// reply receives the result in place of the output channel for buffered workers
+	reply chan jobResult
+}
+
+/*
+jobResult - The outcome of running a job on a worker, err is only set by closures submitted
+with Do.
+*/
+type jobResult struct {
+	data     interface{}
+	err      error
+	panicked bool
+	panic    *jobPanic
+	started  time.Time
+	finished time.Time
+
+	// allocBytes is only measured for jobs sampled by WithAllocSampling
+	allocBytes   uint64
+	allocSampled bool
+
+	// skipped marks a job which was drained or parked rather than run, see Quiesce
+	skipped bool
+}
+
 type workerWrapper struct {
+	// Progress of the worker read by Snapshot, these are first to keep them 64-bit aligned
+	jobStartedAt  int64
+	jobsCompleted int64
+	lastJobAt     int64
+
+	// joinWakeups counts the receives of Join, for tests of shutdown
+	joinWakeups uint64
+
 	readyChan  chan int
-	jobChan    chan interface{}
-	outputChan chan interface{}
+	jobChan    chan jobRequest
+	outputChan chan jobResult
 	poolOpen   uint32
+	started    uint32
+	idle       uint32
+	busy       uint32
+	polling    uint32
+	stopped    uint32
+	retiring   uint32
+	retired    uint32
+	closing    chan struct{}
+	done       chan struct{}
+	flushErr   error
 	worker     GoroutineWorker
+
+	// workerMutex is held whenever the worker is being called, so that it can be swapped
+	// between jobs, swapMutex guards the worker field for Interrupt which runs mid-job.
+	workerMutex sync.Mutex
+	swapMutex   sync.RWMutex
+
+	// running tracks the current job for the stuck worker watchdog, only when watched
+	watched bool
+	running runningJob
+
+	lockOSThread bool
+
+	// bufDepth is the number of jobs that may be queued on a buffered worker, 0 if unbuffered
+	bufDepth int
+
+	// shared marks the slot of an external pool, served by every RunWorker call at once
+	shared bool
+
+	index  int
+	logger *poolLogger
+
+	// readied is closed once the worker first reports ready after starting, until then starting
+	// is set so that the pool can tell a worker coming up from a busy one without locking
+	readied        chan struct{}
+	starting       uint32
+	unhealthyAfter time.Duration
+	unhealthy      uint32
+	clock          Clock
+
+	// scaleDownDelay is how long the worker of a lazy pool may stay idle before it is retired,
+	// onRetire reports whether the pool lets it retire
+	scaleDownDelay time.Duration
+	onRetire       func() bool
+
+	hooks *LifecycleHooks
+
+	// panics is shared with the pool, see PanicHistory
+	panics *panicHistory
+
+	// ticker is shared by the workers of the pool waiting to be ready, see WithReadyPollInterval
+	ticker *readyTicker
+
+	// factory creates the worker replacing one which failed to reinitialize, see WithWorkerFactory
+	factory func() GoroutineWorker
+
+	// methodPanics are the panics recovered from the worker's methods since it started, and
+	// terminatedAt when it last terminated, see CloseReport
+	panicMutex   sync.Mutex
+	methodPanics []*WorkerPanicError
+	terminatedAt time.Time
 }
 
 func (wrapper *workerWrapper) Loop() {
+	if wrapper.lockOSThread {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+	}
 
-	// TODO: Configure?
-	tout := time.Duration(5)
+	// Terminate is owned by the worker goroutine, it runs exactly once after the job channel
+	// has been drained and before Join returns. A retired worker may be started again as soon
+	// as it is marked as not started, so the channel closed is the one of this run.
+	done := wrapper.done
+	defer close(done)
+	defer wrapper.retire()
+	defer wrapper.terminate()
+
+	if wrapper.bufDepth > 0 {
+		wrapper.bufferedLoop()
+		return
+	}
+
+	wrapper.waitReady()
+	wrapper.signalReadied()
+
+	for wrapper.signalReady() {
+		job, open := <-wrapper.jobChan
+		if !open {
+			break
+		}
+		if !job.returned {
+			result := wrapper.run(job)
+			wrapper.outputChan <- result
+			if result.panicked {
+				wrapper.reinitialize()
+			}
+		}
+		wrapper.waitReady()
+	}
+
+	// A retired worker keeps its channels for when it is started again
+	if atomic.LoadUint32(&wrapper.retired) == 1 {
+		return
+	}
+
+	// The pool goes on selecting on the ready channel of a worker stopped with StopWorker, so
+	// it is left open and never signalled again
+	if atomic.LoadUint32(&wrapper.stopped) == 0 {
+		close(wrapper.readyChan)
+	}
+	close(wrapper.outputChan)
+
+}
+
+// waitReady polls the worker until it is ready for the next job or the worker is closed
+func (wrapper *workerWrapper) waitReady() {
+	wrapper.workerMutex.Lock()
+	defer wrapper.workerMutex.Unlock()
+
+	var waiting time.Time
 	for !wrapper.worker.Ready() {
+		atomic.StoreUint32(&wrapper.polling, 1)
 		// It's sad that we can't simply check if jobChan is closed here.
 		if atomic.LoadUint32(&wrapper.poolOpen) == 0 {
 			break
 		}
-		time.Sleep(tout * time.Millisecond)
+		if wrapper.unhealthyAfter > 0 {
+			if waiting.IsZero() {
+				waiting = wrapper.clock.Now()
+			} else if waited := wrapper.clock.Now().Sub(waiting); waited > wrapper.unhealthyAfter {
+				wrapper.markUnhealthy(true, waited)
+			}
+		}
+		tick := wrapper.ticker.wait()
+		select {
+		case <-tick:
+		case <-wrapper.closing:
+		}
+		wrapper.ticker.done()
+		if atomic.LoadUint32(&wrapper.poolOpen) == 0 {
+			break
+		}
 	}
+	atomic.StoreUint32(&wrapper.polling, 0)
 
-	wrapper.readyChan <- 1
+	var waited time.Duration
+	if !waiting.IsZero() {
+		waited = wrapper.clock.Now().Sub(waiting)
+		wrapper.markUnhealthy(false, waited)
+	}
+	wrapper.hooks.ready(wrapper.index, waited)
+}
 
-	for data := range wrapper.jobChan {
-		wrapper.outputChan <- wrapper.worker.Job(data)
-		for !wrapper.worker.Ready() {
-			if atomic.LoadUint32(&wrapper.poolOpen) == 0 {
-				break
+// signalReadied records that the worker has been ready for the first time since it started
+func (wrapper *workerWrapper) signalReadied() {
+	atomic.StoreUint32(&wrapper.starting, 0)
+	select {
+	case <-wrapper.readied:
+	default:
+		close(wrapper.readied)
+	}
+}
+
+// signalReady blocks until the pool takes this worker or the worker is closed, the worker is
+// idle while it waits. Returns false if the worker was closed.
+func (wrapper *workerWrapper) signalReady() bool {
+	select {
+	case <-wrapper.closing:
+		return false
+	default:
+	}
+
+	atomic.StoreUint32(&wrapper.idle, 1)
+	defer atomic.StoreUint32(&wrapper.idle, 0)
+
+	// A nil channel never fires, so the worker only retires WithScaleDownDelay
+	var retire <-chan time.Time
+	if wrapper.scaleDownDelay > 0 {
+		timer := wrapper.clock.NewTimer(wrapper.scaleDownDelay)
+		defer timer.Stop()
+		retire = timer.C()
+		atomic.StoreUint32(&wrapper.retiring, 1)
+		defer atomic.StoreUint32(&wrapper.retiring, 0)
+	}
+
+	for {
+		select {
+		case wrapper.readyChan <- 1:
+			return true
+		case <-wrapper.closing:
+			return false
+		case <-retire:
+			if wrapper.canRetire() {
+				atomic.StoreUint32(&wrapper.retired, 1)
+				return false
 			}
-			time.Sleep(tout * time.Millisecond)
+			retire = nil
+			atomic.StoreUint32(&wrapper.retiring, 0)
 		}
-		wrapper.readyChan <- 1
 	}
+}
 
-	close(wrapper.readyChan)
-	close(wrapper.outputChan)
+// run calls the worker for a single job, recovering the job if it panics
+func (wrapper *workerWrapper) run(job jobRequest) (result jobResult) {
+	defer job.tracked.finish()
+	if err := job.pending.start(); err != nil {
+		return jobResult{err: err, skipped: true}
+	}
+
+	wrapper.workerMutex.Lock()
+	defer wrapper.workerMutex.Unlock()
+
+	atomic.StoreUint32(&wrapper.busy, 1)
+	defer atomic.StoreUint32(&wrapper.busy, 0)
+
+	wrapper.hooks.jobStart(wrapper.index)
+	result.started = wrapper.clock.Now()
+	job.tracked.start(wrapper.index, result.started)
+	atomic.StoreInt64(&wrapper.jobStartedAt, result.started.UnixNano())
+	if wrapper.watched {
+		wrapper.running.begin(job.seq, result.started)
+		defer wrapper.running.end()
+	}
+	if job.sampleAllocs {
+		defer result.measureAllocs(heapAllocBytes())
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			result.data = nil
+			result.panicked = true
+			result.panic = &jobPanic{value: r, stack: debug.Stack()}
+		}
+		result.finished = wrapper.clock.Now()
+		if result.panicked {
+			wrapper.panics.add(result.panic.record(wrapper.index, inspected(job.data), result.finished))
+		}
+		atomic.StoreInt64(&wrapper.jobStartedAt, 0)
+		atomic.StoreInt64(&wrapper.lastJobAt, result.finished.UnixNano())
+		atomic.AddInt64(&wrapper.jobsCompleted, 1)
+		if wrapper.hooks != nil {
+			err := result.err
+			if result.panicked {
+				err = ErrJobPanicked
+			}
+			wrapper.hooks.jobEnd(wrapper.index, result.finished.Sub(result.started), err)
+		}
+	}()
 
+	if call, ok := job.data.(jobCall); ok {
+		result.data, result.err = call()
+		return
+	}
+	if call, ok := job.data.(*typedCall); ok {
+		call.run()
+		return
+	}
+	if progressWorker, ok := wrapper.worker.(GoroutineProgressWorker); ok {
+		result.data = progressWorker.JobProgress(job.data, job.progress.report)
+		return
+	}
+	if ctxWorker, ok := wrapper.worker.(GoroutineContextWorker); ok {
+		ctx := job.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		result.data = ctxWorker.JobCtx(ctx, job.data)
+		return
+	}
+	result.data = wrapper.worker.Job(job.data)
+	return
 }
 
+// Open creates the channels of the worker, follow this with Start() to launch its goroutine
 func (wrapper *workerWrapper) Open() {
-	if extWorker, ok := wrapper.worker.(GoroutineExtendedWorker); ok {
-		extWorker.Initialize()
+	if wrapper.clock == nil {
+		wrapper.clock = realClock{}
 	}
-
-	wrapper.readyChan = make(chan int)
-	wrapper.jobChan = make(chan interface{})
-	wrapper.outputChan = make(chan interface{})
+	if wrapper.ticker == nil {
+		wrapper.ticker = newReadyTicker()
+		wrapper.ticker.clock = wrapper.clock
+	}
+	wrapper.readyChan = make(chan int, wrapper.bufDepth)
+	wrapper.jobChan = make(chan jobRequest, wrapper.bufDepth)
+	wrapper.outputChan = make(chan jobResult)
+	wrapper.closing = make(chan struct{})
 
 	atomic.SwapUint32(&wrapper.poolOpen, uint32(1))
+}
+
+// Start initializes the worker and launches its goroutine, returns false if already started
+func (wrapper *workerWrapper) Start() bool {
+	// Avoid waiting on the worker mutex of a started worker which is busy with a job
+	if atomic.LoadUint32(&wrapper.started) == 1 {
+		return false
+	}
+
+	wrapper.workerMutex.Lock()
+	defer wrapper.workerMutex.Unlock()
+
+	if atomic.LoadUint32(&wrapper.stopped) == 1 || !atomic.CompareAndSwapUint32(&wrapper.started, 0, 1) {
+		return false
+	}
+
+	wrapper.panicMutex.Lock()
+	wrapper.methodPanics = nil
+	wrapper.panicMutex.Unlock()
+
+	if extWorker, ok := wrapper.worker.(GoroutineExtendedWorker); ok {
+		wrapper.guard("Initialize", extWorker.Initialize)
+	}
+	wrapper.hooks.initialize(wrapper.index)
+	wrapper.logger.printf("worker %d initialized", wrapper.index)
 
+	atomic.StoreUint32(&wrapper.retired, 0)
+	atomic.StoreUint32(&wrapper.starting, 1)
+	wrapper.done = make(chan struct{})
+	wrapper.readied = make(chan struct{})
+	wrapper.flushErr = nil
 	go wrapper.Loop()
+	return true
 }
 
-// Follow this with Join(), otherwise terminate isn't called on the worker
-func (wrapper *workerWrapper) Close() {
-	close(wrapper.jobChan)
+// terminate is called by Loop once it exits, flushing the worker before it is terminated
+func (wrapper *workerWrapper) terminate() {
+	wrapper.workerMutex.Lock()
+	defer wrapper.workerMutex.Unlock()
+
+	if flushWorker, ok := wrapper.worker.(GoroutineFlushableWorker); ok {
+		wrapper.guard("Flush", func() {
+			wrapper.flushErr = flushWorker.Flush()
+		})
+	}
+	if extWorker, ok := wrapper.worker.(GoroutineExtendedWorker); ok {
+		wrapper.guard("Terminate", extWorker.Terminate)
+	}
+	wrapper.hooks.terminate(wrapper.index)
+	wrapper.logger.printf("worker %d terminated", wrapper.index)
+	wrapper.terminatedAt = wrapper.clock.Now()
+}
 
+// Close stops the worker from accepting jobs, it is safe to call more than once. The worker
+// terminates on its own goroutine once its current job is done, use Join() to wait for it.
+func (wrapper *workerWrapper) Close() {
 	// Breaks the worker out of a Ready() -> false loop
-	atomic.SwapUint32(&wrapper.poolOpen, uint32(0))
+	if atomic.CompareAndSwapUint32(&wrapper.poolOpen, 1, 0) {
+		close(wrapper.closing)
+		close(wrapper.jobChan)
+	}
 }
 
+// Join waits for a closed worker to terminate, it may be called concurrently
 func (wrapper *workerWrapper) Join() {
-	// Ensure that both the ready and output channels are closed
-	for {
-		_, readyOpen := <-wrapper.readyChan
-		_, outputOpen := <-wrapper.outputChan
-		if !readyOpen && !outputOpen {
-			break
-		}
+	// A worker that was never started has not been initialized and has nothing to wait for
+	if atomic.LoadUint32(&wrapper.started) == 0 {
+		return
 	}
 
-	if extWorker, ok := wrapper.worker.(GoroutineExtendedWorker); ok {
-		extWorker.Terminate()
+	// Ensure that both the ready and output channels are closed, draining them meanwhile. A
+	// channel is dropped from the select once closed, as receiving from it would never block
+	ready, output, done := wrapper.readyChan, wrapper.outputChan, wrapper.done
+	for ready != nil || output != nil {
+		atomic.AddUint64(&wrapper.joinWakeups, 1)
+		select {
+		case <-done:
+			// The worker has exited, a worker which retired leaves its channels open
+			ready, output = nil, nil
+		case _, open := <-ready:
+			if !open {
+				ready = nil
+			}
+		case _, open := <-output:
+			if !open {
+				output = nil
+			}
+		}
 	}
+
+	<-wrapper.done
+	atomic.StoreUint32(&wrapper.started, 0)
 }
 
 func (wrapper *workerWrapper) Interrupt() {
-	if extWorker, ok := wrapper.worker.(GoroutineInterruptable); ok {
-		extWorker.Interrupt()
+	wrapper.hooks.interrupt(wrapper.index)
+	if extWorker, ok := wrapper.currentWorker().(GoroutineInterruptable); ok {
+		wrapper.guard("Interrupt", extWorker.Interrupt)
+	}
+}
+
+// currentWorker returns the worker without waiting for it to finish its job
+func (wrapper *workerWrapper) currentWorker() GoroutineWorker {
+	wrapper.swapMutex.RLock()
+	defer wrapper.swapMutex.RUnlock()
+
+	return wrapper.worker
+}
+
+/*
+SetWorker - Swaps the worker of a running wrapper without stopping it. The call waits for the
+current worker to finish its job and report ready, terminates it if it is extended, initializes
+the new worker and then lets the loop continue with the new worker handling the next job. If the
+wrapper has not been started the worker is simply replaced and is initialized on Start.
+*/
+func (wrapper *workerWrapper) SetWorker(worker GoroutineWorker) error {
+	if worker == nil {
+		return ErrWorkerNil
+	}
+
+	wrapper.workerMutex.Lock()
+	defer wrapper.workerMutex.Unlock()
+
+	started := atomic.LoadUint32(&wrapper.started) == 1
+	if started {
+		if extWorker, ok := wrapper.worker.(GoroutineExtendedWorker); ok {
+			wrapper.guard("Terminate", extWorker.Terminate)
+		}
+	}
+
+	wrapper.swapMutex.Lock()
+	wrapper.worker = worker
+	wrapper.swapMutex.Unlock()
+
+	if started {
+		if extWorker, ok := worker.(GoroutineExtendedWorker); ok {
+			wrapper.guard("Initialize", extWorker.Initialize)
+		}
 	}
+	return nil
 }
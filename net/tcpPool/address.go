@@ -0,0 +1,200 @@
+package tcpPool
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoAddresses 表示没有提供任何地址
+	ErrNoAddresses = errors.New("no addresses given")
+	// ErrNoHealthyAddress 表示所有地址当前都不可达
+	ErrNoHealthyAddress = errors.New("no reachable address")
+)
+
+// addressDialer 在多个地址之间轮询创建连接，并跳过不可达的地址
+type addressDialer struct {
+	mu sync.Mutex
+	//所有的地址
+	addrs []string
+	//下一个尝试的地址下标
+	next int
+	//不可达的地址，值为标记不可达的时间
+	down map[string]time.Time
+
+	dial func(network, address string) (net.Conn, error)
+}
+
+func newAddressDialer(addrs []string) *addressDialer {
+	return &addressDialer{
+		addrs: append([]string(nil), addrs...),
+		down:  make(map[string]time.Time),
+		dial:  net.Dial,
+	}
+}
+
+// Dial 从下一个健康的地址开始轮询，直到成功创建连接或所有健康地址都失败
+func (d *addressDialer) Dial() (net.Conn, error) {
+	var lastErr error
+
+	for i := 0; i < len(d.addrs); i++ {
+		addr, ok := d.nextHealthy()
+		if !ok {
+			break
+		}
+
+		conn, err := d.dial("tcp", addr)
+		if err != nil {
+			d.markDown(addr)
+			lastErr = err
+			continue
+		}
+		return conn, nil
+	}
+
+	if lastErr == nil {
+		lastErr = ErrNoHealthyAddress
+	}
+	return nil, lastErr
+}
+
+func (d *addressDialer) nextHealthy() (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := 0; i < len(d.addrs); i++ {
+		addr := d.addrs[d.next]
+		d.next = (d.next + 1) % len(d.addrs)
+		if _, isDown := d.down[addr]; !isDown {
+			return addr, true
+		}
+	}
+	return "", false
+}
+
+func (d *addressDialer) markDown(addr string) {
+	d.mu.Lock()
+	if _, isDown := d.down[addr]; !isDown {
+		d.down[addr] = time.Now()
+	}
+	d.mu.Unlock()
+}
+
+func (d *addressDialer) markUp(addr string) {
+	d.mu.Lock()
+	delete(d.down, addr)
+	d.mu.Unlock()
+}
+
+// retest 重新尝试连接不可达的地址，连接成功的地址重新加入轮询
+func (d *addressDialer) retest() {
+	d.mu.Lock()
+	downAddrs := make([]string, 0, len(d.down))
+	for addr := range d.down {
+		downAddrs = append(downAddrs, addr)
+	}
+	d.mu.Unlock()
+
+	for _, addr := range downAddrs {
+		conn, err := d.dial("tcp", addr)
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		d.markUp(addr)
+	}
+}
+
+// Endpoints 返回当前健康的地址
+func (d *addressDialer) Endpoints() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	endpoints := make([]string, 0, len(d.addrs))
+	for _, addr := range d.addrs {
+		if _, isDown := d.down[addr]; !isDown {
+			endpoints = append(endpoints, addr)
+		}
+	}
+	return endpoints
+}
+
+// EndpointPool NewChannelPoolFromAddresses返回的连接池实现的接口，通过类型断言获取
+type EndpointPool interface {
+	Pool
+	// Endpoints 返回当前健康的地址
+	Endpoints() []string
+}
+
+// addressPool 在channelPool的基础上，新连接在多个地址之间轮询创建
+type addressPool struct {
+	*channelPool
+
+	dialer *addressDialer
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewChannelPoolFromAddresses 创建一个连接池，新连接在addrs之间轮询创建。
+// 不可达的地址会被跳过，并按照WithRetryInterval设置的间隔重新检测。
+// 返回的连接池实现了EndpointPool，Endpoints返回当前健康的地址。
+func NewChannelPoolFromAddresses(addrs []string, initialCap, maxCap int, opts ...PoolOption) (Pool, error) {
+	p, err := newAddressPool(addrs, net.Dial, initialCap, maxCap, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// newAddressPool 使用dial创建连接的多地址连接池
+func newAddressPool(addrs []string, dial func(network, address string) (net.Conn, error), initialCap, maxCap int, opts ...PoolOption) (*addressPool, error) {
+	if len(addrs) == 0 {
+		return nil, ErrNoAddresses
+	}
+
+	dialer := newAddressDialer(addrs)
+	dialer.dial = dial
+	c, err := newChannelPool(initialCap, maxCap, dialer.Dial, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &addressPool{
+		channelPool: c,
+		dialer:      dialer,
+		stop:        make(chan struct{}),
+	}
+	go p.retestLoop(c.retryInterval)
+
+	return p, nil
+}
+
+// retestLoop 每隔interval重新检测不可达的地址，每次的间隔加上WithJitter设置的随机抖动
+func (p *addressPool) retestLoop(interval time.Duration) {
+	timer := time.NewTimer(p.jittered(interval))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			p.dialer.retest()
+			timer.Reset(p.jittered(interval))
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Endpoints 返回当前健康的地址
+func (p *addressPool) Endpoints() []string {
+	return p.dialer.Endpoints()
+}
+
+func (p *addressPool) Close() {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+	p.channelPool.Close()
+}
@@ -0,0 +1,153 @@
+package tcpPool
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// BorrowAll 取出所有空闲连接用于需要同时操作每个连接的批量操作，例如对每个分片执行FLUSHDB。
+// 在调用release之前Get和GetContext等待，已经取出的连接不受影响。release一次归还所有连接，
+// 调用者不能再单独关闭这些连接，失效的连接通过MarkUnusable标记后由release关闭
+func (c *channelPool) BorrowAll() ([]net.Conn, func(), error) {
+	c.mu.Lock()
+
+	for c.batch != nil {
+		wait := c.batch
+		c.mu.Unlock()
+		<-wait
+		c.mu.Lock()
+	}
+
+	if c.conns == nil {
+		c.mu.Unlock()
+		return nil, nil, ErrClosed
+	}
+
+	batch := make(chan struct{})
+	c.batch = batch
+	atomic.StoreInt32(&c.batching, 1)
+	idle := c.drainIdle()
+
+	c.mu.Unlock()
+
+	conns := make([]net.Conn, len(idle))
+	for i, conn := range idle {
+		conns[i] = conn.checkout()
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+
+			c.mu.Lock()
+			c.batch = nil
+			atomic.StoreInt32(&c.batching, 0)
+			c.mu.Unlock()
+			close(batch)
+		})
+	}
+	return conns, release, nil
+}
+
+// waitBatch 等待BorrowAll取出的连接归还，直到ctx结束
+func (c *channelPool) waitBatch(ctx context.Context) error {
+	for atomic.LoadInt32(&c.batching) == 1 {
+		c.mu.Lock()
+		wait := c.batch
+		c.mu.Unlock()
+
+		if wait == nil {
+			return nil
+		}
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// BorrowAll 取出sync.Pool中所有的空闲连接，在调用release之前Get等待，用法与channelPool相同
+func (s *syncPool) BorrowAll() ([]net.Conn, func(), error) {
+	s.mu.Lock()
+
+	for s.batch != nil {
+		wait := s.batch
+		s.mu.Unlock()
+		<-wait
+		s.mu.Lock()
+	}
+
+	if s.closed {
+		s.mu.Unlock()
+		return nil, nil, ErrClosed
+	}
+
+	batch := make(chan struct{})
+	s.batch = batch
+	atomic.StoreInt32(&s.batching, 1)
+
+	var conns []net.Conn
+	for {
+		conn, ok := s.free.Get().(*PoolConn)
+		if !ok {
+			break
+		}
+		conns = append(conns, conn.checkout())
+	}
+	//取出所有连接之后没有空闲连接，GC回收的连接也不再计入
+	atomic.StoreInt32(&s.idle, 0)
+
+	s.mu.Unlock()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			//空闲连接不占用名额，因此不经过put直接放回
+			for _, conn := range conns {
+				p := conn.(*PoolConn)
+				if s.closed || p.unusable {
+					p.Conn.Close()
+					continue
+				}
+				atomic.AddInt32(&s.idle, 1)
+				s.free.Put(p)
+			}
+
+			s.batch = nil
+			atomic.StoreInt32(&s.batching, 0)
+			close(batch)
+		})
+	}
+	return conns, release, nil
+}
+
+// waitBatch 等待BorrowAll取出的连接归还，连接池关闭时返回ErrClosed
+func (s *syncPool) waitBatch() error {
+	for atomic.LoadInt32(&s.batching) == 1 {
+		s.mu.RLock()
+		wait := s.batch
+		s.mu.RUnlock()
+
+		if wait == nil {
+			return nil
+		}
+
+		select {
+		case <-wait:
+		case <-s.done:
+			return ErrClosed
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package tcpPool
+
+import (
+	"bufio"
+)
+
+// WithBuffering 为每个连接加上大小分别为readSize和writeSize的读写缓冲，Get返回*BufferedConn。
+// 缓冲随连接一起保存在连接池中，连接多次取出之间读缓冲中未读的数据不会丢失。小于等于0的大小使用bufio的默认大小
+func WithBuffering(readSize, writeSize int) PoolOption {
+	return func(c *channelPool) {
+		c.buffering = true
+		c.readBufSize = readSize
+		c.writeBufSize = writeSize
+	}
+}
+
+// BufferedConn 带有读写缓冲的连接，Read和Write经过缓冲，写入的数据在Flush之后才发送。
+// Close在归还连接之前先Flush，失败时连接被标记为不可用并关闭
+type BufferedConn struct {
+	*PoolConn
+	rw *bufio.ReadWriter
+}
+
+// newBufferedConn 为p创建读写缓冲
+func newBufferedConn(p *PoolConn, readSize, writeSize int) *BufferedConn {
+	var r *bufio.Reader
+	if readSize > 0 {
+		r = bufio.NewReaderSize(p, readSize)
+	} else {
+		r = bufio.NewReader(p)
+	}
+
+	var w *bufio.Writer
+	if writeSize > 0 {
+		w = bufio.NewWriterSize(p, writeSize)
+	} else {
+		w = bufio.NewWriter(p)
+	}
+
+	return &BufferedConn{PoolConn: p, rw: bufio.NewReadWriter(r, w)}
+}
+
+func (b *BufferedConn) Read(p []byte) (int, error) {
+	return b.rw.Read(p)
+}
+
+func (b *BufferedConn) Write(p []byte) (int, error) {
+	return b.rw.Write(p)
+}
+
+// Flush 发送写缓冲中的数据
+func (b *BufferedConn) Flush() error {
+	return b.rw.Flush()
+}
+
+func (b *BufferedConn) ReadByte() (byte, error) {
+	return b.rw.ReadByte()
+}
+
+func (b *BufferedConn) UnreadByte() error {
+	return b.rw.UnreadByte()
+}
+
+// ReadString 读取数据直到遇到delim，返回的字符串包含delim
+func (b *BufferedConn) ReadString(delim byte) (string, error) {
+	return b.rw.ReadString(delim)
+}
+
+// Peek 返回接下来的n个字节但不读取它们
+func (b *BufferedConn) Peek(n int) ([]byte, error) {
+	return b.rw.Peek(n)
+}
+
+func (b *BufferedConn) WriteByte(c byte) error {
+	return b.rw.WriteByte(c)
+}
+
+func (b *BufferedConn) WriteString(s string) (int, error) {
+	return b.rw.WriteString(s)
+}
+
+// Buffered 返回读缓冲中可以读取的字节数
+func (b *BufferedConn) Buffered() int {
+	return b.rw.Reader.Buffered()
+}
+
+// ReadWriter 返回连接的读写缓冲，用于需要*bufio.ReadWriter的场景
+func (b *BufferedConn) ReadWriter() *bufio.ReadWriter {
+	return b.rw
+}
+
+// Close 先发送写缓冲中的数据再归还连接，发送失败时连接被标记为不可用并关闭，返回发送的错误
+func (b *BufferedConn) Close() error {
+	if b.unusable {
+		return b.PoolConn.Close()
+	}
+
+	if err := b.rw.Flush(); err != nil {
+		b.MarkUnusable()
+		b.PoolConn.Close()
+		return err
+	}
+	return b.PoolConn.Close()
+}
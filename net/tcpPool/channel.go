@@ -1,27 +1,130 @@
 package tcpPool
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // channelPool 实现Pool接口 并且带有缓冲的连接池.
 type channelPool struct {
 	//mu 为了保证每个连接获取是协成安全的
 	mu sync.Mutex
-	//连接的缓存
-	conns chan net.Conn
-
-	// 创建新连接的工厂方法
-	factory Factory
+	//连接的缓存，同一个PoolConn在多次取出之间复用
+	conns chan *PoolConn
+
+	// 创建新连接的工厂方法，SetFactory会替换
+	factory FactoryContext
+	// 工厂方法的版本，每次SetFactory加一，旧版本创建的连接归还时被关闭
+	factoryGen uint64
+
+	// 不可达地址的重试间隔，仅用于多地址连接池
+	retryInterval time.Duration
+	// 定时任务间隔的随机抖动系数
+	jitter float64
+
+	//最大连接数，GetContext在打开的连接数达到maxCap时等待连接归还，Resize会修改，需要原子操作
+	maxCap int32
+	//当前打开的连接数，包括空闲的和正在使用的连接
+	openConns int32
+	//有连接被关闭时通知等待中的GetContext
+	freed chan struct{}
+	//GetContext等待连接的最长时间，0表示只受context限制
+	maxWaitTime time.Duration
+	//等待连接的统计信息
+	waits waitStats
+	//调用工厂方法的次数和失败的次数，需要原子操作
+	dials      int64
+	dialErrors int64
+
+	//连接池的名称
+	name string
+
+	//创建初始连接时同时调用工厂方法的个数，小于2时依次创建
+	dialConcurrency int
+
+	//BorrowAll取出连接期间不为nil，release时关闭，由mu保护。batching为1表示batch不为nil，用于Get不加锁的检查
+	batch    chan struct{}
+	batching int32
+
+	//新连接进行TLS握手的配置，nil表示不使用TLS
+	tlsConfig     *tls.Config
+	tlsServerName string
+
+	//新连接上发送的PROXY协议头部，nil表示不发送
+	proxyHeader  *proxyHeaderWriter
+	proxyVersion ProxyProtocolVersion
+
+	//Validate检查空闲连接的函数和补足的空闲连接数
+	validator Validator
+	minIdle   int
+	//Ping花费的时间超过这个阈值时记录警告日志，0表示不记录
+	slowPingThreshold time.Duration
+
+	//WithBuffering设置的读写缓冲大小
+	buffering    bool
+	readBufSize  int
+	writeBufSize int
+
+	//创建和关闭连接时调用的函数
+	connectHook func(conn net.Conn, dialDuration time.Duration)
+	closeHook   func(conn net.Conn, reason CloseReason)
+	//WithConnectionLabels设置的标签，创建后不再修改
+	labels map[string]string
+	//回收空闲连接的策略，nil表示不回收
+	evictionPolicy EvictionPolicy
+	//按照关闭原因统计的关闭连接数
+	closed [closeReasons]int64
+	//最近关闭的连接，用于HTTPHandler
+	evictions evictionLog
 }
 
 // Factory 获取创建一个连接
 type Factory func() (net.Conn, error)
 
-func NewChannelPool(initialCap, maxCap int, factory Factory) (Pool, error) {
+// FactoryContext 可以取消的工厂方法，ctx结束时应当放弃正在创建的连接并返回错误
+type FactoryContext func(ctx context.Context) (net.Conn, error)
+
+// withContext 把Factory转换为忽略ctx的FactoryContext
+func (f Factory) withContext() FactoryContext {
+	if f == nil {
+		return nil
+	}
+	return func(context.Context) (net.Conn, error) {
+		return f()
+	}
+}
+
+// NewChannelPool 创建一个带缓冲的连接池，初始创建initialCap个连接，最多缓存maxCap个空闲连接
+func NewChannelPool(initialCap, maxCap int, factory Factory, opts ...PoolOption) (Pool, error) {
+	c, err := newChannelPool(initialCap, maxCap, factory, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewChannelPoolContext 与NewChannelPool相同，但是创建初始连接时可以通过ctx取消：
+// ctx传给factory，并且每创建一个连接之前检查ctx，ctx结束时关闭已经创建的连接并返回ctx的错误。
+// 之后GetContext和WarmUp创建连接时把它们的ctx传给factory，Get使用context.Background()
+func NewChannelPoolContext(ctx context.Context, initialCap, maxCap int, factory FactoryContext, opts ...PoolOption) (Pool, error) {
+	c, err := newChannelPoolContext(ctx, initialCap, maxCap, factory, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func newChannelPool(initialCap, maxCap int, factory Factory, opts ...PoolOption) (*channelPool, error) {
+	return newChannelPoolContext(context.Background(), initialCap, maxCap, factory.withContext(), opts...)
+}
+
+func newChannelPoolContext(ctx context.Context, initialCap, maxCap int, factory FactoryContext, opts ...PoolOption) (*channelPool, error) {
 
 	if initialCap < 0 || maxCap <= 0 || initialCap > maxCap {
 
@@ -30,23 +133,100 @@ func NewChannelPool(initialCap, maxCap int, factory Factory) (Pool, error) {
 	}
 
 	c := &channelPool{
-		conns:   make(chan net.Conn, maxCap),
-		factory: factory,
+		conns:             make(chan *PoolConn, maxCap),
+		factory:           factory,
+		retryInterval:     DefaultRetryInterval,
+		jitter:            DefaultJitter,
+		slowPingThreshold: DefaultSlowPingThreshold,
+		maxCap:            int32(maxCap),
+		freed:             make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.dialConcurrency > 1 && initialCap > 1 {
+		if err := c.fillConcurrently(ctx, initialCap); err != nil {
+			return nil, err
+		}
+		return c, nil
 	}
 
 	for i := 0; i < initialCap; i++ {
-		conn, err := factory()
+		if err := ctx.Err(); err != nil {
+			c.Close()
+			return nil, err
+		}
+		conn, err := c.dial(ctx)
 		if err != nil {
 			c.Close()
-			return nil, fmt.Errorf("factory is not able to fill the pool: %s", err)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, fmt.Errorf("factory is not able to fill the pool: %w", err)
 		}
+		atomic.AddInt32(&c.openConns, 1)
 		c.conns <- conn
 	}
 
 	return c, nil
 }
 
-func (c *channelPool) getConns() chan net.Conn {
+// fillConcurrently 最多同时调用dialConcurrency次工厂方法创建initialCap个初始连接，每个连接创建成功后立即放入连接池。
+// 失败的次数多于成功的次数时关闭连接池，返回最后一个错误；否则连接池以成功创建的连接启动
+func (c *channelPool) fillConcurrently(ctx context.Context, initialCap int) error {
+	sem := make(chan struct{}, c.dialConcurrency)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		successes int
+		failures  int
+		lastErr   error
+	)
+
+dial:
+	for i := 0; i < initialCap; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break dial
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			conn, err := c.dial(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures++
+				lastErr = err
+				return
+			}
+			successes++
+			atomic.AddInt32(&c.openConns, 1)
+			c.conns <- conn
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		c.Close()
+		return err
+	}
+	if failures > successes {
+		c.Close()
+		return fmt.Errorf("factory is not able to fill the pool: %w", lastErr)
+	}
+	return nil
+}
+
+func (c *channelPool) getConns() chan *PoolConn {
 
 	c.mu.Lock()
 
@@ -57,13 +237,64 @@ func (c *channelPool) getConns() chan net.Conn {
 	return conns
 }
 
-func (c *channelPool) wrapConn(conn net.Conn) net.Conn {
-	p := &PoolConn{c: c}
-	p.Conn = conn
-	return p
+func (c *channelPool) Get() (net.Conn, error) {
+	if err := c.waitBatch(context.Background()); err != nil {
+		return nil, err
+	}
+
+	conns := c.getConns()
+
+	for {
+
+		if conns == nil {
+
+			return nil, ErrClosed
+
+		}
+
+		select {
+
+		case conn := <-conns:
+
+			if conn == nil {
+
+				// 缓存被Resize替换或者连接池已关闭
+				conns = c.getConns()
+
+				continue
+
+			}
+
+			if c.evict(conn) {
+				continue
+			}
+
+			return conn.checkout(), nil
+
+		default:
+
+			conn, err := c.dial(context.Background())
+
+			if err != nil {
+
+				return nil, err
+
+			}
+
+			atomic.AddInt32(&c.openConns, 1)
+
+			return conn.checkout(), nil
+		}
+	}
 }
 
-func (c *channelPool) Get() (net.Conn, error) {
+// GetContext 获取一个连接，没有空闲连接时如果打开的连接数未达到maxCap则创建新连接，
+// 否则等待其他连接归还，直到ctx结束或者超过WithMaxWaitTime设置的等待时间。
+func (c *channelPool) GetContext(ctx context.Context) (net.Conn, error) {
+	if err := c.waitBatch(ctx); err != nil {
+		return nil, err
+	}
+
 	conns := c.getConns()
 
 	if conns == nil {
@@ -72,32 +303,230 @@ func (c *channelPool) Get() (net.Conn, error) {
 
 	}
 
-	select {
+	for idle := true; idle; {
 
-	case conn := <-conns:
+		select {
 
-		if conn == nil {
+		case conn := <-conns:
 
-			return nil, ErrClosed
+			if conn == nil {
+
+				// 缓存被Resize替换或者连接池已关闭
+				if conns = c.getConns(); conns == nil {
+
+					return nil, ErrClosed
+
+				}
+
+				continue
+
+			}
 
+			if c.evict(conn) {
+				continue
+			}
+
+			return conn.checkout(), nil
+
+		default:
+
+			idle = false
 		}
+	}
 
-		return c.wrapConn(conn), nil
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	default:
+	var timeout <-chan time.Time
+	if c.maxWaitTime > 0 {
+		timer := time.NewTimer(c.maxWaitTime)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var start time.Time
+
+	for {
+		if c.reserveConn() {
+
+			conn, err := c.dial(ctx)
+
+			if err != nil {
+
+				c.releaseConn()
+
+				return nil, err
+
+			}
+
+			c.recordWait(start)
+
+			return conn.checkout(), nil
+		}
+
+		if start.IsZero() {
+			start = time.Now()
+			atomic.AddInt32(&c.waits.waiting, 1)
+			defer atomic.AddInt32(&c.waits.waiting, -1)
+		}
+
+		select {
+
+		case conn := <-conns:
+
+			if conn == nil {
+
+				// 缓存被Resize替换或者连接池已关闭
+				if conns = c.getConns(); conns == nil {
+
+					c.recordWait(start)
+
+					return nil, ErrClosed
+
+				}
+
+				continue
+
+			}
+
+			if c.evict(conn) {
+				continue
+			}
+
+			c.recordWait(start)
+
+			return conn.checkout(), nil
+
+		case <-c.freed:
+
+		case <-ctx.Done():
+
+			c.recordWait(start)
+
+			return nil, ctx.Err()
+
+		case <-timeout:
+
+			c.recordWait(start)
+			atomic.AddInt64(&c.waits.timeouts, 1)
+
+			return nil, ErrWaitTimeout
+		}
+	}
+}
+
+// WarmUp 并发创建最多n个连接放入连接池，打开的连接数达到maxCap后不再创建
+func (c *channelPool) WarmUp(ctx context.Context, n int) error {
+	return warmUp(ctx, n, func(ctx context.Context) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-		conn, err := c.factory()
+		if !c.reserveConn() {
+			return nil
+		}
 
+		conn, err := c.dial(ctx)
 		if err != nil {
+			c.releaseConn()
+			return err
+		}
+
+		return c.put(conn)
+	})
+}
 
+// dial 使用工厂方法创建一个新连接，并分配连接编号
+func (c *channelPool) dial(ctx context.Context) (*PoolConn, error) {
+	c.mu.Lock()
+	factory := c.factory
+	gen := c.factoryGen
+	c.mu.Unlock()
+
+	if factory == nil {
+		return nil, ErrClosed
+	}
+
+	start := time.Now()
+
+	atomic.AddInt64(&c.dials, 1)
+	conn, err := factory(ctx)
+	if err != nil {
+		atomic.AddInt64(&c.dialErrors, 1)
+		return nil, err
+	}
+	if c.proxyHeader != nil {
+		if err := c.proxyHeader.write(ctx, conn, c.proxyVersion); err != nil {
+			return nil, err
+		}
+	}
+	if c.tlsConfig != nil {
+		if conn, err = c.handshakeTLS(ctx, conn); err != nil {
 			return nil, err
+		}
+	}
+
+	p := newPoolConn(c, conn)
+	p.factoryGen = gen
+	p.labels = c.labels
+	if c.buffering {
+		p.buffered = newBufferedConn(p, c.readBufSize, c.writeBufSize)
+	}
+
+	if c.connectHook != nil {
+		c.connectHook(p, time.Since(start))
+	}
+
+	return p, nil
+}
 
+// reserveConn 在打开的连接数未达到maxCap时占用一个名额
+func (c *channelPool) reserveConn() bool {
+	for {
+		open := atomic.LoadInt32(&c.openConns)
+		if open >= atomic.LoadInt32(&c.maxCap) {
+			return false
 		}
+		if atomic.CompareAndSwapInt32(&c.openConns, open, open+1) {
+			return true
+		}
+	}
+}
+
+// releaseConn 释放一个连接名额，并通知等待中的GetContext
+func (c *channelPool) releaseConn() {
+	atomic.AddInt32(&c.openConns, -1)
 
-		return c.wrapConn(conn), nil
+	select {
+	case c.freed <- struct{}{}:
+	default:
 	}
 }
-func (c *channelPool) put(conn net.Conn) error {
+
+// closeConn 关闭一个由连接池创建的连接，reason为关闭的原因
+func (c *channelPool) closeConn(conn *PoolConn, reason CloseReason) error {
+	if c.closeHook != nil {
+		c.closeHook(conn, reason)
+	}
+	atomic.AddInt64(&c.closed[reason], 1)
+	c.evictions.record(conn.id, reason)
+	c.releaseConn()
+	return conn.Conn.Close()
+}
+
+// recordWait 记录一次等待连接的时间，start为零表示没有等待
+func (c *channelPool) recordWait(start time.Time) {
+	if !start.IsZero() {
+		c.waits.record(time.Since(start))
+	}
+}
+func (c *channelPool) put(conn *PoolConn) error {
+	return c.putConn(conn, true)
+}
+
+// putConn 把连接放回连接池，evict为false时不询问回收策略，用于Ping放回只是检查过的连接
+func (c *channelPool) putConn(conn *PoolConn, evict bool) error {
 
 	if conn == nil {
 
@@ -105,23 +534,44 @@ func (c *channelPool) put(conn net.Conn) error {
 
 	}
 
+	// 释放mu之后再关闭连接，closeHook可能调用Stats等需要mu的方法
+	if reason, rejected := c.putIdle(conn, evict); rejected {
+		return c.closeConn(conn, reason)
+	}
+
+	return nil
+}
+
+// putIdle 持有mu把连接放入空闲连接的缓存，连接不能放回时返回关闭的原因和true，由调用者关闭
+func (c *channelPool) putIdle(conn *PoolConn, evict bool) (CloseReason, bool) {
+
 	c.mu.Lock()
 
 	defer c.mu.Unlock()
 
 	if c.conns == nil {
-		return conn.Close()
+		return ClosePoolClose, true
+	}
+
+	if conn.factoryGen != c.factoryGen {
+		return CloseUnusable, true
+	}
+
+	if evict {
+		if reason, ok := c.shouldEvict(conn); ok {
+			return reason, true
+		}
 	}
 
 	select {
 
 	case c.conns <- conn:
 
-		return nil
+		return 0, false
 
 	default:
 
-		return conn.Close()
+		return CloseOverflow, true
 
 	}
 }
@@ -140,8 +590,148 @@ func (c *channelPool) Close() {
 	close(conns)
 
 	for conn := range conns {
-		conn.Close()
+		c.closeConn(conn, ClosePoolClose)
+	}
+}
+
+// Resize 修改连接池的最大连接数，空闲连接转移到新的缓存中，缩小时超出的空闲连接被关闭。
+// 已经取出的连接不受影响，归还时如果空闲连接已满则被关闭。
+func (c *channelPool) Resize(newMax int) error {
+	if newMax <= 0 {
+		return errors.New("invalid capacity settings")
+	}
+
+	c.mu.Lock()
+
+	old := c.conns
+
+	if old == nil {
+		c.mu.Unlock()
+		return ErrClosed
 	}
+
+	conns := make(chan *PoolConn, newMax)
+	c.conns = conns
+	atomic.StoreInt32(&c.maxCap, int32(newMax))
+
+	// 关闭旧的缓存，等待中的Get会转到新的缓存上
+	close(old)
+
+	var overflow []*PoolConn
+	for conn := range old {
+		select {
+		case conns <- conn:
+		default:
+			overflow = append(overflow, conn)
+		}
+	}
+
+	c.mu.Unlock()
+
+	for _, conn := range overflow {
+		c.closeConn(conn, CloseOverflow)
+	}
+
+	// 扩大时唤醒等待名额的GetContext
+	select {
+	case c.freed <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// SetFactory 替换创建连接的工厂方法，例如认证信息或者地址变化之后。当前的空闲连接被关闭，
+// 之后的Get使用新的工厂方法创建连接；已经取出的连接不受影响，归还时作为不可用的连接关闭
+func (c *channelPool) SetFactory(factory Factory) error {
+	if factory == nil {
+		return errors.New("factory is nil")
+	}
+
+	c.mu.Lock()
+
+	if c.conns == nil {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+
+	c.factory = factory.withContext()
+	c.factoryGen++
+
+	idle := c.drainIdle()
+
+	c.mu.Unlock()
+
+	for _, conn := range idle {
+		c.closeConn(conn, CloseUnusable)
+	}
+
+	return nil
+}
+
+// CloseIdleConnections 关闭所有空闲连接，例如远端服务重启之后。连接池保持打开，之后的Get创建新连接；
+// 已经取出的连接不受影响，归还时照常放回连接池
+func (c *channelPool) CloseIdleConnections() {
+	c.mu.Lock()
+
+	if c.conns == nil {
+		c.mu.Unlock()
+		return
+	}
+
+	idle := c.drainIdle()
+
+	c.mu.Unlock()
+
+	for _, conn := range idle {
+		c.closeConn(conn, CloseIdleFlush)
+	}
+}
+
+// drainIdle 取出所有空闲连接，调用者需要持有mu
+func (c *channelPool) drainIdle() []*PoolConn {
+	var idle []*PoolConn
+	for {
+		select {
+		case conn := <-c.conns:
+			idle = append(idle, conn)
+		default:
+			return idle
+		}
+	}
+}
+
+// PeekIdleConns 返回当前空闲连接的快照。从channel读取会取走连接，因此先取出所有空闲连接再立即放回，
+// 期间持有mu，避免与归还连接、Resize和Close同时进行。并发的Get仍然可能在这期间取走其中的连接
+func (c *channelPool) PeekIdleConns() []net.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conns == nil {
+		return nil
+	}
+
+	var idle []net.Conn
+drain:
+	for {
+		select {
+		case conn := <-c.conns:
+			idle = append(idle, conn)
+		default:
+			break drain
+		}
+	}
+
+	for _, conn := range idle {
+		c.conns <- conn.(*PoolConn)
+	}
+
+	return idle
+}
+
+// Name 返回通过WithPoolName设置的连接池名称
+func (c *channelPool) Name() string {
+	return c.name
 }
 
 func (c *channelPool) Len() int {
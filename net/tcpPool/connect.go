@@ -1,11 +1,66 @@
 package tcpPool
 
-import "net"
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
 
+// connSeq 最后分配的连接编号
+var connSeq uint64
+
+// connPool 是PoolConn归还或者关闭连接时使用的连接池
+type connPool interface {
+	put(conn *PoolConn) error
+	closeConn(conn *PoolConn, reason CloseReason) error
+}
+
+// PoolConn 连接池中的连接，关闭时归还给连接池。同一个PoolConn在多次取出之间复用，编号保持不变
 type PoolConn struct {
 	net.Conn
-	c        *channelPool
+	c        connPool
+	id       uint64
 	unusable bool
+	//创建连接的时间
+	created time.Time
+	//连接被取出的次数，需要原子操作
+	uses int64
+	//连接最后一次归还的时间(UnixNano)，需要原子操作
+	lastUsed int64
+	//连接上最后一次读写的错误，保存connError
+	lastErr atomic.Value
+	//创建连接的工厂方法版本，仅用于channelPool
+	factoryGen uint64
+	//带缓冲的包装，仅用于设置了WithBuffering的channelPool
+	buffered *BufferedConn
+	//连接池的标签，与连接池共用，不能修改
+	labels map[string]string
+}
+
+// newPoolConn 包装工厂方法创建的连接并分配一个新的编号
+func newPoolConn(c connPool, conn net.Conn) *PoolConn {
+	now := time.Now()
+	return &PoolConn{
+		Conn:     conn,
+		c:        c,
+		id:       atomic.AddUint64(&connSeq, 1),
+		created:  now,
+		lastUsed: now.UnixNano(),
+	}
+}
+
+// checkout 记录连接被取出一次，返回交给调用者的连接
+func (p *PoolConn) checkout() net.Conn {
+	atomic.AddInt64(&p.uses, 1)
+	if p.buffered != nil {
+		return p.buffered
+	}
+	return p
+}
+
+// ID 返回连接的编号，进程内每个连接唯一，用于在日志和统计信息中追踪一个连接从创建到关闭的过程
+func (p *PoolConn) ID() uint64 {
+	return p.id
 }
 
 func (p *PoolConn) Close() error {
@@ -14,7 +69,7 @@ func (p *PoolConn) Close() error {
 
 		if p.Conn != nil {
 
-			return p.Conn.Close()
+			return p.c.closeConn(p, CloseUnusable)
 
 		}
 
@@ -22,7 +77,9 @@ func (p *PoolConn) Close() error {
 
 	}
 
-	return p.c.put(p.Conn)
+	atomic.StoreInt64(&p.lastUsed, time.Now().UnixNano())
+
+	return p.c.put(p)
 
 }
 
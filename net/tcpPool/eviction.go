@@ -0,0 +1,165 @@
+package tcpPool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ConnMeta 交给EvictionPolicy判断的连接信息
+type ConnMeta struct {
+	//创建连接的时间
+	CreatedAt time.Time
+	//连接最后一次归还的时间，没有取出过的连接为创建的时间
+	LastUsedAt time.Time
+	//连接被取出的次数
+	UseCount int64
+	//连接上最后一次读写的错误，没有出错时为nil
+	Error error
+}
+
+// EvictionPolicy 决定空闲连接是否应当关闭，连接池在取出空闲连接、归还连接和Validate时询问。
+// ShouldEvict可能被多个goroutine同时调用
+type EvictionPolicy interface {
+	ShouldEvict(conn ConnMeta) bool
+}
+
+// WithEvictionPolicy 设置回收连接的策略，被回收的连接以策略对应的CloseReason关闭，
+// 内置的策略分别为CloseIdleEviction、CloseMaxAge和CloseMaxUses，其他策略为CloseEvictionPolicy
+func WithEvictionPolicy(policy EvictionPolicy) PoolOption {
+	return func(c *channelPool) {
+		c.evictionPolicy = policy
+	}
+}
+
+// reasonedPolicy 内置策略实现的接口，返回是否回收连接和对应的关闭原因
+type reasonedPolicy interface {
+	evict(conn ConnMeta, now time.Time) (CloseReason, bool)
+}
+
+// evictReason 询问policy是否回收连接
+func evictReason(policy EvictionPolicy, conn ConnMeta, now time.Time) (CloseReason, bool) {
+	if reasoned, ok := policy.(reasonedPolicy); ok {
+		return reasoned.evict(conn, now)
+	}
+	return CloseEvictionPolicy, policy.ShouldEvict(conn)
+}
+
+type lruEviction time.Duration
+
+// LRUEviction 回收归还之后空闲超过maxIdle的连接
+func LRUEviction(maxIdle time.Duration) EvictionPolicy {
+	return lruEviction(maxIdle)
+}
+
+func (p lruEviction) ShouldEvict(conn ConnMeta) bool {
+	_, evict := p.evict(conn, time.Now())
+	return evict
+}
+
+func (p lruEviction) evict(conn ConnMeta, now time.Time) (CloseReason, bool) {
+	return CloseIdleEviction, now.Sub(conn.LastUsedAt) > time.Duration(p)
+}
+
+type maxAgeEviction time.Duration
+
+// MaxAgeEviction 回收创建之后存在超过maxAge的连接
+func MaxAgeEviction(maxAge time.Duration) EvictionPolicy {
+	return maxAgeEviction(maxAge)
+}
+
+func (p maxAgeEviction) ShouldEvict(conn ConnMeta) bool {
+	_, evict := p.evict(conn, time.Now())
+	return evict
+}
+
+func (p maxAgeEviction) evict(conn ConnMeta, now time.Time) (CloseReason, bool) {
+	return CloseMaxAge, now.Sub(conn.CreatedAt) > time.Duration(p)
+}
+
+type maxUsesEviction int64
+
+// MaxUsesEviction 回收被取出max次的连接
+func MaxUsesEviction(max int) EvictionPolicy {
+	return maxUsesEviction(max)
+}
+
+func (p maxUsesEviction) ShouldEvict(conn ConnMeta) bool {
+	_, evict := p.evict(conn, time.Now())
+	return evict
+}
+
+func (p maxUsesEviction) evict(conn ConnMeta, now time.Time) (CloseReason, bool) {
+	return CloseMaxUses, conn.UseCount >= int64(p)
+}
+
+type compositeEviction []EvictionPolicy
+
+// CompositeEviction 任意一个策略要求回收时回收连接，关闭原因为第一个要求回收的策略的原因
+func CompositeEviction(policies ...EvictionPolicy) EvictionPolicy {
+	return compositeEviction(append([]EvictionPolicy(nil), policies...))
+}
+
+func (p compositeEviction) ShouldEvict(conn ConnMeta) bool {
+	_, evict := p.evict(conn, time.Now())
+	return evict
+}
+
+func (p compositeEviction) evict(conn ConnMeta, now time.Time) (CloseReason, bool) {
+	for _, policy := range p {
+		if reason, evict := evictReason(policy, conn, now); evict {
+			return reason, true
+		}
+	}
+	return 0, false
+}
+
+// meta 返回连接的信息
+func (p *PoolConn) meta() ConnMeta {
+	meta := ConnMeta{
+		CreatedAt:  p.created,
+		LastUsedAt: time.Unix(0, atomic.LoadInt64(&p.lastUsed)),
+		UseCount:   atomic.LoadInt64(&p.uses),
+	}
+	if err, ok := p.lastErr.Load().(connError); ok {
+		meta.Error = err.err
+	}
+	return meta
+}
+
+// connError 保存在atomic.Value中的错误，atomic.Value要求每次保存相同的类型
+type connError struct {
+	err error
+}
+
+func (p *PoolConn) Read(b []byte) (int, error) {
+	n, err := p.Conn.Read(b)
+	if err != nil {
+		p.lastErr.Store(connError{err})
+	}
+	return n, err
+}
+
+func (p *PoolConn) Write(b []byte) (int, error) {
+	n, err := p.Conn.Write(b)
+	if err != nil {
+		p.lastErr.Store(connError{err})
+	}
+	return n, err
+}
+
+// evict 按照回收策略检查空闲的或者正在归还的连接，需要回收时关闭连接并返回true
+func (c *channelPool) evict(conn *PoolConn) bool {
+	reason, evict := c.shouldEvict(conn)
+	if evict {
+		c.closeConn(conn, reason)
+	}
+	return evict
+}
+
+// shouldEvict 按照回收策略检查连接，返回是否需要回收和关闭的原因，不关闭连接
+func (c *channelPool) shouldEvict(conn *PoolConn) (CloseReason, bool) {
+	if c.evictionPolicy == nil {
+		return 0, false
+	}
+	return evictReason(c.evictionPolicy, conn.meta(), time.Now())
+}
@@ -0,0 +1,73 @@
+package tcpPool
+
+import (
+	"net"
+	"time"
+)
+
+// CloseReason 连接池关闭一个连接的原因
+type CloseReason int
+
+const (
+	// CloseIdleEviction 空闲时间过长被回收
+	CloseIdleEviction CloseReason = iota
+	// CloseMaxAge 连接存在的时间超过了最长寿命
+	CloseMaxAge
+	// CloseValidationFailure 连接没有通过有效性检查
+	CloseValidationFailure
+	// CloseUnusable 使用者通过MarkUnusable标记为不可用
+	CloseUnusable
+	// ClosePoolClose 连接池已经关闭
+	ClosePoolClose
+	// CloseOverflow 归还时空闲连接已满
+	CloseOverflow
+	// CloseIdleFlush 被CloseIdleConnections关闭的空闲连接
+	CloseIdleFlush
+	// CloseMaxUses 连接被取出的次数达到了MaxUsesEviction的上限
+	CloseMaxUses
+	// CloseEvictionPolicy 被WithEvictionPolicy设置的其他策略回收
+	CloseEvictionPolicy
+
+	// closeReasons 关闭原因的个数
+	closeReasons
+)
+
+func (r CloseReason) String() string {
+	switch r {
+	case CloseIdleEviction:
+		return "idle-eviction"
+	case CloseMaxAge:
+		return "max-age"
+	case CloseValidationFailure:
+		return "validation-failure"
+	case CloseUnusable:
+		return "unusable"
+	case ClosePoolClose:
+		return "pool-close"
+	case CloseOverflow:
+		return "overflow"
+	case CloseIdleFlush:
+		return "idle-flush"
+	case CloseMaxUses:
+		return "max-uses"
+	case CloseEvictionPolicy:
+		return "eviction-policy"
+	}
+	return "unknown"
+}
+
+// WithConnectHook 设置每次工厂方法成功创建连接后调用的函数，参数为新连接和创建连接花费的时间。
+// conn是*PoolConn，可以通过ID()取得连接编号，在函数中不能关闭连接
+func WithConnectHook(fn func(conn net.Conn, dialDuration time.Duration)) PoolOption {
+	return func(c *channelPool) {
+		c.connectHook = fn
+	}
+}
+
+// WithCloseHook 设置连接池关闭连接前调用的函数，参数为被关闭的连接和关闭的原因。
+// conn是*PoolConn，编号与WithConnectHook收到的相同
+func WithCloseHook(fn func(conn net.Conn, reason CloseReason)) PoolOption {
+	return func(c *channelPool) {
+		c.closeHook = fn
+	}
+}
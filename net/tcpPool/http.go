@@ -0,0 +1,110 @@
+package tcpPool
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// evictionLogSize 保留的最近关闭连接的个数
+const evictionLogSize = 32
+
+// EvictionEvent 连接池关闭一个连接的记录
+type EvictionEvent struct {
+	ID     uint64
+	Reason CloseReason
+	At     time.Time
+}
+
+// evictionLog 保留最近evictionLogSize个关闭连接的记录，零值可以直接使用
+type evictionLog struct {
+	mu     sync.Mutex
+	events [evictionLogSize]EvictionEvent
+	next   int
+	full   bool
+}
+
+func (l *evictionLog) record(id uint64, reason CloseReason) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events[l.next] = EvictionEvent{ID: id, Reason: reason, At: time.Now()}
+	l.next++
+	if l.next == len(l.events) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+// snapshot 返回记录的副本，从早到晚
+func (l *evictionLog) snapshot() []EvictionEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.full {
+		return append([]EvictionEvent(nil), l.events[:l.next]...)
+	}
+	events := append([]EvictionEvent(nil), l.events[l.next:]...)
+	return append(events, l.events[:l.next]...)
+}
+
+// HTTPHandler 返回展示连接池状态的http.Handler，可以像net/http/pprof一样注册在调试路径下，例如
+// http.Handle("/debug/pool/db", pool.HTTPHandler())。默认输出便于阅读的HTML页面，包括打开的和最大连接数、
+// 空闲连接数、等待连接的个数、工厂方法的失败率和最近关闭的连接，?format=json输出与DumpMetrics相同的JSON
+func (c *channelPool) HTTPHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := c.metricsSnapshot(time.Now())
+
+		if r.FormValue("format") == MetricsJSON {
+			w.Header().Set("Content-Type", "application/json")
+			snapshot.writeJSON(w)
+			return
+		}
+
+		//先写入缓冲，模板出错时还可以返回500
+		var buf bytes.Buffer
+		if err := poolPage.Execute(&buf, snapshot); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+}
+
+var poolPage = template.Must(template.New("pool").Funcs(template.FuncMap{
+	"percent": func(rate float64) float64 { return rate * 100 },
+}).Parse(`<html>
+<head>
+<title>/debug/pool/{{.Stats.Name}}</title>
+</head>
+<body>
+/debug/pool/{{.Stats.Name}}<br>
+<br>
+<table>
+<tr><td align=right>{{.Stats.OpenConns}}<td>open connections
+<tr><td align=right>{{.Stats.MaxCap}}<td>max connections
+<tr><td align=right>{{.Stats.IdleConns}}<td>idle connections
+<tr><td align=right>{{.Stats.Waiting}}<td>waiting for a connection
+<tr><td align=right>{{.Stats.WaitCount}}<td>waits ({{.Stats.WaitTimeouts}} timed out, {{.Stats.WaitDuration}} in total)
+<tr><td align=right>{{printf "%.2f%%" (percent .Stats.DialErrorRate)}}<td>factory error rate ({{.Stats.DialErrors}} of {{.Stats.Dials}} dials)
+</table>
+<br>
+Idle connections:<br>
+<table>
+<tr><th>id<th>age<th>uses
+{{range .Conns}}<tr><td>{{.ID}}<td>{{.Age}}<td>{{.Uses}}
+{{end}}</table>
+<br>
+Recent evictions:<br>
+<table>
+<tr><th>id<th>reason<th>at
+{{range .Evictions}}<tr><td>{{.ID}}<td>{{.Reason}}<td>{{.At.Format "2006-01-02 15:04:05.000"}}
+{{end}}</table>
+<br>
+<a href="?format=json">json</a>
+</body>
+</html>
+`))
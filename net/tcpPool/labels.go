@@ -0,0 +1,57 @@
+package tcpPool
+
+import (
+	"regexp"
+	"sort"
+)
+
+// LabelledConn 带有标签的连接，连接池创建的连接都是*PoolConn，可以通过conn.(*LabelledConn).Labels()取得标签
+type LabelledConn = PoolConn
+
+// WithConnectionLabels 为连接池创建的每个连接加上标签，比如在多租户的系统中标明连接属于哪个租户。
+// WithConnectHook和WithCloseHook收到的conn是*LabelledConn，可以取得标签。
+// Prometheus格式的统计信息把标签作为每个指标的标签输出，名称不合法或者与pool、conn、le重复的标签不输出
+func WithConnectionLabels(labels map[string]string) PoolOption {
+	return func(c *channelPool) {
+		c.labels = make(map[string]string, len(labels))
+		for name, value := range labels {
+			c.labels[name] = value
+		}
+	}
+}
+
+// Labels 返回连接的标签的副本，没有设置WithConnectionLabels时返回nil
+func (p *PoolConn) Labels() map[string]string {
+	if p.labels == nil {
+		return nil
+	}
+	labels := make(map[string]string, len(p.labels))
+	for name, value := range p.labels {
+		labels[name] = value
+	}
+	return labels
+}
+
+// promLabelName Prometheus标签名称的格式
+var promLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// promLabels 返回按名称排序的Prometheus标签，以逗号开头，可以直接接在pool标签之后
+func promLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		switch name {
+		case "pool", "conn", "le":
+			continue
+		}
+		if promLabelName.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var out string
+	for _, name := range names {
+		out += `,` + name + `="` + promEscaper.Replace(labels[name]) + `"`
+	}
+	return out
+}
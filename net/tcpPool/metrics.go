@@ -0,0 +1,217 @@
+package tcpPool
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DumpMetrics 支持的输出格式
+const (
+	// MetricsText 便于阅读的文本
+	MetricsText = "text"
+	// MetricsJSON JSON对象
+	MetricsJSON = "json"
+	// MetricsPrometheus Prometheus文本格式，可以推送到push-gateway，指标名称见writePrometheus
+	MetricsPrometheus = "prometheus"
+)
+
+// metricsSnapshot 三种输出格式共用的连接池状态快照
+type metricsSnapshot struct {
+	Stats PoolStats
+	//空闲连接的信息，正在使用的连接不在其中
+	Conns []connMetrics
+	//最近关闭的连接，从早到晚
+	Evictions []EvictionEvent
+	//WithConnectionLabels设置的标签
+	Labels map[string]string
+}
+
+// connMetrics 单个空闲连接的信息
+type connMetrics struct {
+	ID   uint64
+	Age  time.Duration
+	Uses int64
+}
+
+// DumpMetrics 把连接池的统计信息和每个空闲连接的存在时间、取出次数以format格式写入w，
+// format为MetricsText、MetricsJSON或者MetricsPrometheus
+func (c *channelPool) DumpMetrics(w io.Writer, format string) error {
+	snapshot := c.metricsSnapshot(time.Now())
+
+	switch format {
+	case MetricsText:
+		return snapshot.writeText(w)
+	case MetricsJSON:
+		return snapshot.writeJSON(w)
+	case MetricsPrometheus:
+		return snapshot.writePrometheus(w)
+	}
+	return fmt.Errorf("unknown metrics format %q", format)
+}
+
+// metricsSnapshot 取得now时刻连接池的快照
+func (c *channelPool) metricsSnapshot(now time.Time) metricsSnapshot {
+	snapshot := metricsSnapshot{Stats: c.Stats(), Evictions: c.evictions.snapshot(), Labels: c.labels}
+
+	for _, conn := range c.PeekIdleConns() {
+		p := conn.(*PoolConn)
+		snapshot.Conns = append(snapshot.Conns, connMetrics{
+			ID:   p.id,
+			Age:  now.Sub(p.created),
+			Uses: atomic.LoadInt64(&p.uses),
+		})
+	}
+
+	return snapshot
+}
+
+func (m metricsSnapshot) writeText(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	s := m.Stats
+
+	fmt.Fprintf(out, "pool %q\n", s.Name)
+	fmt.Fprintf(out, "  max conns:     %d\n", s.MaxCap)
+	fmt.Fprintf(out, "  open conns:    %d\n", s.OpenConns)
+	fmt.Fprintf(out, "  idle conns:    %d\n", s.IdleConns)
+	fmt.Fprintf(out, "  waits:         %d (%v, %d timed out)\n", s.WaitCount, s.WaitDuration, s.WaitTimeouts)
+	fmt.Fprintf(out, "  wait p50/p99:  %v / %v\n", s.WaitHistogram.Quantile(0.5), s.WaitHistogram.Quantile(0.99))
+	fmt.Fprintf(out, "  waiting:       %d\n", s.Waiting)
+	fmt.Fprintf(out, "  dials:         %d (%d failed)\n", s.Dials, s.DialErrors)
+	for _, conn := range m.Conns {
+		fmt.Fprintf(out, "  conn %d: age %v, uses %d\n", conn.ID, conn.Age, conn.Uses)
+	}
+
+	return out.Flush()
+}
+
+func (m metricsSnapshot) writeJSON(w io.Writer) error {
+	type jsonConn struct {
+		ID         uint64  `json:"id"`
+		AgeSeconds float64 `json:"age_seconds"`
+		Uses       int64   `json:"uses"`
+	}
+	type jsonBucket struct {
+		LeSeconds float64 `json:"le_seconds,omitempty"`
+		Count     int64   `json:"count"`
+	}
+	type jsonEviction struct {
+		ID     uint64    `json:"id"`
+		Reason string    `json:"reason"`
+		At     time.Time `json:"at"`
+	}
+
+	s := m.Stats
+	out := struct {
+		Name                string         `json:"name"`
+		MaxConns            int            `json:"max_conns"`
+		OpenConns           int            `json:"open_conns"`
+		IdleConns           int            `json:"idle_conns"`
+		WaitCount           int64          `json:"wait_count"`
+		WaitDurationSeconds float64        `json:"wait_duration_seconds"`
+		WaitTimeouts        int64          `json:"wait_timeouts"`
+		WaitHistogram       []jsonBucket   `json:"wait_histogram"`
+		Waiting             int            `json:"waiting"`
+		Dials               int64          `json:"dials"`
+		DialErrors          int64          `json:"dial_errors"`
+		Conns               []jsonConn     `json:"conns"`
+		Evictions           []jsonEviction `json:"evictions"`
+	}{
+		Name:                s.Name,
+		MaxConns:            s.MaxCap,
+		OpenConns:           s.OpenConns,
+		IdleConns:           s.IdleConns,
+		WaitCount:           s.WaitCount,
+		WaitDurationSeconds: s.WaitDuration.Seconds(),
+		WaitTimeouts:        s.WaitTimeouts,
+		Waiting:             s.Waiting,
+		Dials:               s.Dials,
+		DialErrors:          s.DialErrors,
+		Conns:               []jsonConn{},
+		Evictions:           []jsonEviction{},
+	}
+
+	//最后一个桶是溢出桶，没有上限
+	for i, count := range s.WaitHistogram.Counts {
+		bucket := jsonBucket{Count: count}
+		if i < len(s.WaitHistogram.Buckets) {
+			bucket.LeSeconds = s.WaitHistogram.Buckets[i].Seconds()
+		}
+		out.WaitHistogram = append(out.WaitHistogram, bucket)
+	}
+	for _, conn := range m.Conns {
+		out.Conns = append(out.Conns, jsonConn{ID: conn.ID, AgeSeconds: conn.Age.Seconds(), Uses: conn.Uses})
+	}
+	for _, eviction := range m.Evictions {
+		out.Evictions = append(out.Evictions, jsonEviction{ID: eviction.ID, Reason: eviction.Reason.String(), At: eviction.At})
+	}
+
+	return json.NewEncoder(w).Encode(out)
+}
+
+// writePrometheus 输出Prometheus文本格式，所有指标带有pool标签和WithConnectionLabels设置的标签，指标名称保持不变：
+//
+//	tcppool_max_conns                     gauge     最大连接数
+//	tcppool_open_conns                    gauge     打开的连接数
+//	tcppool_idle_conns                    gauge     空闲的连接数
+//	tcppool_wait_seconds                  histogram GetContext等待连接的时间
+//	tcppool_wait_timeouts_total           counter   等待超过MaxWaitTime的次数
+//	tcppool_conn_age_seconds{conn}        gauge     空闲连接存在的时间
+//	tcppool_conn_uses_total{conn}         counter   空闲连接被取出的次数
+func (m metricsSnapshot) writePrometheus(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	s := m.Stats
+	label := `pool="` + promEscaper.Replace(s.Name) + `"` + promLabels(m.Labels)
+
+	promHeader(out, "tcppool_max_conns", "gauge", "Maximum number of connections.")
+	promSample(out, "tcppool_max_conns", label, strconv.Itoa(s.MaxCap))
+	promHeader(out, "tcppool_open_conns", "gauge", "Open connections, idle and in use.")
+	promSample(out, "tcppool_open_conns", label, strconv.Itoa(s.OpenConns))
+	promHeader(out, "tcppool_idle_conns", "gauge", "Idle connections.")
+	promSample(out, "tcppool_idle_conns", label, strconv.Itoa(s.IdleConns))
+
+	promHeader(out, "tcppool_wait_seconds", "histogram", "Time GetContext waited for a connection.")
+	var cumulative int64
+	for i, count := range s.WaitHistogram.Counts {
+		cumulative += count
+		le := "+Inf"
+		if i < len(s.WaitHistogram.Buckets) {
+			le = strconv.FormatFloat(s.WaitHistogram.Buckets[i].Seconds(), 'g', -1, 64)
+		}
+		promSample(out, "tcppool_wait_seconds_bucket", label+`,le="`+le+`"`, strconv.FormatInt(cumulative, 10))
+	}
+	promSample(out, "tcppool_wait_seconds_sum", label, strconv.FormatFloat(s.WaitDuration.Seconds(), 'g', -1, 64))
+	promSample(out, "tcppool_wait_seconds_count", label, strconv.FormatInt(s.WaitCount, 10))
+
+	promHeader(out, "tcppool_wait_timeouts_total", "counter", "Waits longer than the maximum wait time.")
+	promSample(out, "tcppool_wait_timeouts_total", label, strconv.FormatInt(s.WaitTimeouts, 10))
+
+	promHeader(out, "tcppool_conn_age_seconds", "gauge", "Age of each idle connection.")
+	for _, conn := range m.Conns {
+		promSample(out, "tcppool_conn_age_seconds", label+`,conn="`+strconv.FormatUint(conn.ID, 10)+`"`,
+			strconv.FormatFloat(conn.Age.Seconds(), 'g', -1, 64))
+	}
+	promHeader(out, "tcppool_conn_uses_total", "counter", "Times each idle connection was taken from the pool.")
+	for _, conn := range m.Conns {
+		promSample(out, "tcppool_conn_uses_total", label+`,conn="`+strconv.FormatUint(conn.ID, 10)+`"`,
+			strconv.FormatInt(conn.Uses, 10))
+	}
+
+	return out.Flush()
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promHeader(out *bufio.Writer, name, kind, help string) {
+	out.WriteString("# HELP " + name + " " + help + "\n")
+	out.WriteString("# TYPE " + name + " " + kind + "\n")
+}
+
+func promSample(out *bufio.Writer, name, labels, value string) {
+	out.WriteString(name + "{" + labels + "} " + value + "\n")
+}
@@ -0,0 +1,108 @@
+package tcpPool
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// DefaultSlowPingThreshold Ping默认的慢检查阈值
+const DefaultSlowPingThreshold = time.Second
+
+// WithSlowPingThreshold 设置Ping的慢检查阈值，花费的时间超过d时记录一条警告日志，d不大于0时不记录。默认为DefaultSlowPingThreshold
+func WithSlowPingThreshold(d time.Duration) PoolOption {
+	return func(c *channelPool) {
+		c.slowPingThreshold = d
+	}
+}
+
+// Ping 检查连接池是否可用，可以用作存活检查：取出一个空闲连接，没有空闲连接时用工厂方法创建一个新连接，
+// 打开的连接数达到maxCap时等待连接归还，直到ctx结束。连接经过WithValidator设置的函数检查之后放回连接池，
+// 不计入取出次数，也不更新空闲时间，放回时不询问回收策略。没有通过检查的连接被关闭并创建一个新连接补上，
+// 返回检查的错误，因此Ping之后连接池中的连接不会比之前少
+func (c *channelPool) Ping(ctx context.Context) error {
+	start := time.Now()
+	defer c.warnSlowPing(start)
+
+	conn, err := c.pingConn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.validator != nil {
+		if err := c.validator(conn); err != nil {
+			c.closeConn(conn, CloseValidationFailure)
+			c.replaceConn(ctx)
+			return err
+		}
+	}
+
+	return c.putConn(conn, false)
+}
+
+// pingConn 取出一个空闲连接或者创建一个新连接，不经过checkout
+func (c *channelPool) pingConn(ctx context.Context) (*PoolConn, error) {
+	conns := c.getConns()
+
+	for {
+		if conns == nil {
+			return nil, ErrClosed
+		}
+
+		select {
+		case conn := <-conns:
+			if conn == nil {
+				// 缓存被Resize替换或者连接池已关闭
+				conns = c.getConns()
+				continue
+			}
+			return conn, nil
+		default:
+		}
+
+		if c.reserveConn() {
+			conn, err := c.dial(ctx)
+			if err != nil {
+				c.releaseConn()
+				return nil, err
+			}
+			return conn, nil
+		}
+
+		select {
+		case conn := <-conns:
+			if conn == nil {
+				conns = c.getConns()
+				continue
+			}
+			return conn, nil
+		case <-c.freed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// replaceConn 创建一个新连接放入连接池，补上Ping关闭的连接，打开的连接数已经达到maxCap时不创建
+func (c *channelPool) replaceConn(ctx context.Context) {
+	if !c.reserveConn() {
+		return
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		c.releaseConn()
+		return
+	}
+
+	c.putConn(conn, false)
+}
+
+// warnSlowPing Ping花费的时间超过slowPingThreshold时记录警告日志
+func (c *channelPool) warnSlowPing(start time.Time) {
+	elapsed := time.Since(start)
+	if c.slowPingThreshold <= 0 || elapsed <= c.slowPingThreshold {
+		return
+	}
+	log.Printf("tcpPool: slow ping of pool %q took %v, threshold %v", c.name, elapsed, c.slowPingThreshold)
+}
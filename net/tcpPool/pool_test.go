@@ -0,0 +1,1606 @@
+package tcpPool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pipeFactory 创建内存中的连接，另一端被丢弃
+func pipeFactory() (net.Conn, error) {
+	client, server := net.Pipe()
+	server.Close()
+	return client, nil
+}
+
+func TestSyncPool(t *testing.T) {
+	p, err := NewSyncPool(2, pipeFactory)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+
+	first, err := p.Get()
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	if _, err := p.Get(); err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+
+	// maxCap个连接都已取出，Get需要等待归还
+	got := make(chan net.Conn)
+	go func() {
+		conn, _ := p.Get()
+		got <- conn
+	}()
+
+	select {
+	case <-got:
+		t.Fatal("Expected Get to wait while maxCap connections are out")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	first.Close()
+	select {
+	case conn := <-got:
+		if conn == nil {
+			t.Fatal("Expected a connection once one was returned")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Get did not return after a connection was put back")
+	}
+
+	p.Close()
+	if _, err := p.Get(); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	c, err := newChannelPool(0, 1, pipeFactory)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer c.Close()
+
+	// 默认的抖动系数为DefaultJitter
+	interval := time.Second
+	spread := false
+	for i := 0; i < 100; i++ {
+		d := c.jittered(interval)
+		if d < interval || d > interval+time.Duration(float64(interval)*DefaultJitter) {
+			t.Fatalf("Expected %v to be within the default jitter of %v", d, interval)
+		}
+		spread = spread || d != interval
+	}
+	if !spread {
+		t.Errorf("Expected the intervals to be spread")
+	}
+
+	for _, test := range []struct {
+		factor float64
+		max    time.Duration
+	}{
+		{0, interval},
+		{-1, interval},
+		{0.5, interval * 3 / 2},
+		{2, 2 * interval},
+	} {
+		WithJitter(test.factor)(c)
+		for i := 0; i < 100; i++ {
+			if d := c.jittered(interval); d < interval || d > test.max {
+				t.Fatalf("Expected %v with factor %v to be within %v-%v", d, test.factor, interval, test.max)
+			}
+		}
+	}
+}
+
+// fakeAddresses 按地址创建内存中的连接，down中的地址连接失败
+type fakeAddresses struct {
+	mu     sync.Mutex
+	down   map[string]bool
+	dialed []string
+}
+
+func (f *fakeAddresses) dial(network, address string) (net.Conn, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.down[address] {
+		return nil, fmt.Errorf("dial %v: connection refused", address)
+	}
+	f.dialed = append(f.dialed, address)
+	return pipeFactory()
+}
+
+func (f *fakeAddresses) setDown(address string, down bool) {
+	f.mu.Lock()
+	f.down[address] = down
+	f.mu.Unlock()
+}
+
+func TestChannelPoolFromAddresses(t *testing.T) {
+	if _, err := NewChannelPoolFromAddresses(nil, 0, 1); err != ErrNoAddresses {
+		t.Errorf("Expected ErrNoAddresses, got %v", err)
+	}
+
+	fake := &fakeAddresses{down: map[string]bool{"b": true}}
+	p, err := newAddressPool([]string{"a", "b", "c"}, fake.dial, 0, 10, WithRetryInterval(10*time.Millisecond), WithJitter(0))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	var pool Pool = p
+	endpoints, ok := pool.(EndpointPool)
+	if !ok {
+		t.Fatal("Expected the pool to implement EndpointPool")
+	}
+
+	//轮询创建连接，跳过不可达的地址
+	for i := 0; i < 4; i++ {
+		if _, err := p.Get(); err != nil {
+			t.Fatalf("Failed to get a connection: %v", err)
+		}
+	}
+	fake.mu.Lock()
+	dialed := strings.Join(fake.dialed, ",")
+	fake.mu.Unlock()
+	if dialed != "a,c,a,c" {
+		t.Errorf("Expected round robin over the healthy addresses, got %v", dialed)
+	}
+	if got := strings.Join(endpoints.Endpoints(), ","); got != "a,c" {
+		t.Errorf("Expected endpoints a,c, got %v", got)
+	}
+
+	//重新检测之后恢复的地址重新加入轮询
+	fake.setDown("b", false)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(endpoints.Endpoints()) != 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := strings.Join(endpoints.Endpoints(), ","); got != "a,b,c" {
+		t.Fatalf("Expected b to recover, got %v", got)
+	}
+
+	fake.mu.Lock()
+	fake.dialed = nil
+	fake.mu.Unlock()
+	for i := 0; i < 3; i++ {
+		if _, err := p.Get(); err != nil {
+			t.Fatalf("Failed to get a connection: %v", err)
+		}
+	}
+	fake.mu.Lock()
+	dialed = strings.Join(fake.dialed, ",")
+	fake.mu.Unlock()
+	if dialed != "a,b,c" {
+		t.Errorf("Expected every address to be dialed, got %v", dialed)
+	}
+}
+
+func TestChannelPoolFromAddressesAllDown(t *testing.T) {
+	fake := &fakeAddresses{down: map[string]bool{"a": true, "b": true}}
+	p, err := newAddressPool([]string{"a", "b"}, fake.dial, 0, 1, WithRetryInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	if _, err := p.Get(); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Expected the last dial error, got %v", err)
+	}
+	if _, err := p.Get(); err != ErrNoHealthyAddress {
+		t.Errorf("Expected ErrNoHealthyAddress, got %v", err)
+	}
+	if endpoints := p.Endpoints(); len(endpoints) != 0 {
+		t.Errorf("Expected no healthy endpoints, got %v", endpoints)
+	}
+}
+
+func TestBorrowAll(t *testing.T) {
+	c, err := NewChannelPool(3, 4, pipeFactory)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	s, err := NewSyncPool(4, pipeFactory)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	s.WarmUp(context.Background(), 3)
+
+	for name, p := range map[string]Pool{"channel": c, "sync": s} {
+		inUse, _ := p.Get()
+
+		conns, release, err := p.BorrowAll()
+		if err != nil {
+			t.Fatalf("%s: Failed to borrow connections: %v", name, err)
+		}
+		if p.Len() != 0 {
+			t.Errorf("%s: Expected every idle connection to be borrowed, %v idle", name, p.Len())
+		}
+		//sync.Pool可能丢弃空闲连接，只检查channelPool的个数
+		if name == "channel" && len(conns) != 2 {
+			t.Errorf("%s: Expected 2 connections to be borrowed, got %v", name, len(conns))
+		}
+
+		// Get等待批量操作结束
+		got := make(chan net.Conn)
+		go func() {
+			conn, _ := p.Get()
+			got <- conn
+		}()
+		select {
+		case <-got:
+			t.Errorf("%s: Expected Get to wait until the batch is released", name)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		if len(conns) > 0 {
+			conns[0].(*PoolConn).MarkUnusable()
+		}
+		release()
+		release()
+		if conn := <-got; conn == nil {
+			t.Errorf("%s: Expected Get to return a connection after release", name)
+		} else {
+			conn.Close()
+		}
+		inUse.Close()
+		if name == "channel" && p.Len() != 2 {
+			t.Errorf("%s: Expected 2 idle connections after release, got %v", name, p.Len())
+		}
+
+		p.Close()
+		if _, _, err := p.BorrowAll(); err != ErrClosed {
+			t.Errorf("%s: Expected ErrClosed after Close, got %v", name, err)
+		}
+	}
+
+	// GetContext等待批量操作时受ctx限制
+	c, err = NewChannelPool(1, 1, pipeFactory)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer c.Close()
+
+	_, release, _ := c.BorrowAll()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.(*channelPool).GetContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected GetContext to give up waiting for the batch, got %v", err)
+	}
+	release()
+}
+
+func TestWarmUp(t *testing.T) {
+	p, err := NewChannelPool(0, 3, pipeFactory)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	if err := p.WarmUp(context.Background(), 5); err != nil {
+		t.Errorf("Failed to warm up: %v", err)
+	}
+	if p.Len() != 3 {
+		t.Errorf("Expected warm up to stop at maxCap 3, got %v idle connections", p.Len())
+	}
+
+	var dials int32
+	failing, err := NewChannelPool(0, 4, func() (net.Conn, error) {
+		if atomic.AddInt32(&dials, 1) == 1 {
+			return nil, errors.New("refused")
+		}
+		return pipeFactory()
+	})
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer failing.Close()
+
+	if err := failing.WarmUp(context.Background(), 4); err == nil {
+		t.Error("Expected the dial error from warm up")
+	}
+	if failing.Len() != 3 {
+		t.Errorf("Expected the successful connections in the pool, got %v", failing.Len())
+	}
+}
+
+func TestNewChannelPoolContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var dialled []net.Conn
+	factory := func(ctx context.Context) (net.Conn, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		conn, _ := pipeFactory()
+		dialled = append(dialled, conn)
+		// 第二个连接创建之后取消，剩下的连接不再创建
+		if len(dialled) == 2 {
+			cancel()
+		}
+		return conn, nil
+	}
+
+	if _, err := NewChannelPoolContext(ctx, 4, 4, factory); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if len(dialled) != 2 {
+		t.Errorf("Expected dialling to stop once the context was cancelled, got %d dials", len(dialled))
+	}
+	for _, conn := range dialled {
+		if _, err := conn.Write([]byte("x")); err != io.ErrClosedPipe {
+			t.Errorf("Expected the dialled connections to be closed, got %v", err)
+		}
+	}
+
+	type ctxKey struct{}
+	var got interface{}
+	p, err := NewChannelPoolContext(context.Background(), 2, 4, func(ctx context.Context) (net.Conn, error) {
+		got = ctx.Value(ctxKey{})
+		return pipeFactory()
+	})
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+	if p.Len() != 2 {
+		t.Errorf("Expected 2 idle connections, got %v", p.Len())
+	}
+
+	// 没有空闲连接时GetContext把ctx传给factory
+	p.Get()
+	p.Get()
+	if _, err := p.(*channelPool).GetContext(context.WithValue(context.Background(), ctxKey{}, "get")); err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	if got != "get" {
+		t.Errorf("Expected the factory to receive the context of GetContext, got %v", got)
+	}
+}
+
+func TestDialConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	var dialled []net.Conn
+	var running, maxRunning int32
+	// failEvery大于0时每failEvery次创建失败一次
+	slowFactory := func(failEvery int) Factory {
+		var n int32
+		return func() (net.Conn, error) {
+			r := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if r <= m || atomic.CompareAndSwapInt32(&maxRunning, m, r) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+
+			if failEvery > 0 && atomic.AddInt32(&n, 1)%int32(failEvery) != 0 {
+				return nil, errors.New("dial failed")
+			}
+			conn, _ := pipeFactory()
+			mu.Lock()
+			dialled = append(dialled, conn)
+			mu.Unlock()
+			return conn, nil
+		}
+	}
+
+	start := time.Now()
+	p, err := NewChannelPool(10, 10, slowFactory(0), WithDialConcurrency(5))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+		t.Errorf("Expected the initial connections to be dialled concurrently, took %v", elapsed)
+	}
+	if n := atomic.LoadInt32(&maxRunning); n != 5 {
+		t.Errorf("Expected at most 5 concurrent dials, got %v", n)
+	}
+	if p.Len() != 10 {
+		t.Errorf("Expected 10 idle connections, got %v", p.Len())
+	}
+	p.Close()
+
+	// 一半的连接创建失败，连接池以成功的连接启动
+	p, err = NewChannelPool(10, 10, slowFactory(2), WithDialConcurrency(4))
+	if err != nil {
+		t.Fatalf("Expected the pool to start with half of the connections, got %v", err)
+	}
+	if p.Len() != 5 {
+		t.Errorf("Expected 5 idle connections, got %v", p.Len())
+	}
+	p.Close()
+
+	// 失败多于成功时返回错误，已经创建的连接被关闭
+	dialled = nil
+	var closed int32
+	_, err = NewChannelPool(10, 10, slowFactory(3), WithDialConcurrency(4),
+		WithCloseHook(func(conn net.Conn, reason CloseReason) {
+			atomic.AddInt32(&closed, 1)
+		}))
+	if err == nil || !strings.Contains(err.Error(), "dial failed") {
+		t.Fatalf("Expected the last dial error, got %v", err)
+	}
+	if len(dialled) != 3 || atomic.LoadInt32(&closed) != 3 {
+		t.Errorf("Expected the 3 dialled connections to be closed, dialled %v, closed %v", len(dialled), closed)
+	}
+}
+
+func TestConnectAndCloseHooks(t *testing.T) {
+	var mu sync.Mutex
+	var dials int
+	reasons := map[CloseReason]int{}
+
+	p, err := NewChannelPool(1, 1, pipeFactory,
+		WithConnectHook(func(conn net.Conn, dialDuration time.Duration) {
+			mu.Lock()
+			dials++
+			mu.Unlock()
+		}),
+		WithCloseHook(func(conn net.Conn, reason CloseReason) {
+			mu.Lock()
+			reasons[reason]++
+			mu.Unlock()
+		}))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+
+	first, _ := p.Get()
+	second, _ := p.Get()
+	third, _ := p.Get()
+	third.(*PoolConn).MarkUnusable()
+	third.Close()
+	first.Close()
+	second.Close()
+	p.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if dials != 3 {
+		t.Errorf("Expected 3 dials, got %v", dials)
+	}
+	if reasons[CloseUnusable] != 1 || reasons[CloseOverflow] != 1 || reasons[ClosePoolClose] != 1 {
+		t.Errorf("Unexpected close reasons: %v", reasons)
+	}
+}
+
+func TestCloseHookStats(t *testing.T) {
+	var p *channelPool
+	var mu sync.Mutex
+	reasons := map[CloseReason]int{}
+
+	p, err := newChannelPool(1, 1, pipeFactory, WithEvictionPolicy(MaxUsesEviction(3)),
+		WithCloseHook(func(conn net.Conn, reason CloseReason) {
+			//在关闭连接时读取统计信息不能死锁
+			p.Stats()
+			p.DBStats()
+			mu.Lock()
+			reasons[reason]++
+			mu.Unlock()
+		}))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		first, _ := p.Get()
+		second, _ := p.Get()
+		first.Close()
+		second.Close()
+
+		third, _ := p.Get()
+		third.(*PoolConn).MarkUnusable()
+		third.Close()
+
+		for i := 0; i < 3; i++ {
+			conn, _ := p.Get()
+			conn.Close()
+		}
+
+		p.SetFactory(pipeFactory)
+		stale, _ := p.Get()
+		p.SetFactory(pipeFactory)
+		stale.Close()
+
+		last, _ := p.Get()
+		p.Close()
+		last.Close()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close hook calling Stats deadlocked")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, reason := range []CloseReason{CloseOverflow, CloseUnusable, CloseMaxUses, ClosePoolClose} {
+		if reasons[reason] == 0 {
+			t.Errorf("Expected a connection closed for %v, got %v", reason, reasons)
+		}
+	}
+}
+
+func TestConnectionLabels(t *testing.T) {
+	labels := map[string]string{"tenant": `a"b`, "bad-name": "x", "pool": "y"}
+	var mu sync.Mutex
+	var hooked []map[string]string
+
+	p, err := newChannelPool(1, 1, pipeFactory, WithPoolName("tenants"), WithConnectionLabels(labels),
+		WithConnectHook(func(conn net.Conn, dialDuration time.Duration) {
+			mu.Lock()
+			hooked = append(hooked, conn.(*LabelledConn).Labels())
+			mu.Unlock()
+		}),
+		WithCloseHook(func(conn net.Conn, reason CloseReason) {
+			mu.Lock()
+			hooked = append(hooked, conn.(*LabelledConn).Labels())
+			mu.Unlock()
+		}))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+
+	//修改传入的标签不影响连接池
+	labels["tenant"] = "changed"
+
+	conn, _ := p.Get()
+	if got := conn.(*LabelledConn).Labels()["tenant"]; got != `a"b` {
+		t.Errorf("Expected the tenant label, got %q", got)
+	}
+	conn.Close()
+
+	buf := bytes.Buffer{}
+	if err := p.DumpMetrics(&buf, MetricsPrometheus); err != nil {
+		t.Fatalf("Failed to dump metrics: %v", err)
+	}
+	if want := `tcppool_open_conns{pool="tenants",tenant="a\"b"} 1`; !strings.Contains(buf.String(), want+"\n") {
+		t.Errorf("Expected prometheus output to contain %q, got:\n%s", want, buf.String())
+	}
+	if strings.Contains(buf.String(), "bad-name") || strings.Contains(buf.String(), `pool="y"`) {
+		t.Errorf("Expected invalid and reserved labels to be left out, got:\n%s", buf.String())
+	}
+	p.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hooked) != 2 {
+		t.Fatalf("Expected the connect and close hooks to be called, got %v", len(hooked))
+	}
+	for _, labels := range hooked {
+		if labels["tenant"] != `a"b` {
+			t.Errorf("Expected the hooks to see the tenant label, got %v", labels)
+		}
+	}
+}
+
+// errorEviction 回收读写出错的连接
+type errorEviction struct{}
+
+func (errorEviction) ShouldEvict(conn ConnMeta) bool {
+	return conn.Error != nil
+}
+
+func TestEvictionPolicy(t *testing.T) {
+	//被取出两次之后归还时回收
+	p, err := newChannelPool(1, 1, pipeFactory, WithEvictionPolicy(MaxUsesEviction(2)))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		conn, _ := p.Get()
+		conn.Close()
+	}
+	if p.Len() != 0 || p.closedFor(CloseMaxUses) != 1 {
+		t.Errorf("Expected the connection to be evicted after 2 uses, got %v idle", p.Len())
+	}
+	p.Close()
+
+	//空闲时间过长的连接被Validate回收
+	p, err = newChannelPool(1, 1, pipeFactory, WithEvictionPolicy(LRUEviction(10*time.Millisecond)))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if evicted, _ := p.Validate(); evicted != 1 || p.closedFor(CloseIdleEviction) != 1 {
+		t.Errorf("Expected the idle connection to be evicted, got %v", evicted)
+	}
+	p.Close()
+
+	//取出时回收过期的连接并创建新连接
+	p, err = newChannelPool(1, 1, pipeFactory, WithEvictionPolicy(CompositeEviction(
+		MaxUsesEviction(100), MaxAgeEviction(10*time.Millisecond), errorEviction{})))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	old := p.PeekIdleConns()[0].(*PoolConn).ID()
+	time.Sleep(20 * time.Millisecond)
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatalf("Failed to get a connection: %v", err)
+	}
+	if conn.(*PoolConn).ID() == old || p.closedFor(CloseMaxAge) != 1 {
+		t.Errorf("Expected the old connection to be evicted on Get")
+	}
+
+	//其他策略可以根据读写的错误回收连接
+	if _, err := conn.Write([]byte("x")); err == nil {
+		t.Fatal("Expected writing to a closed pipe to fail")
+	}
+	conn.Close()
+	if p.Len() != 0 || p.closedFor(CloseEvictionPolicy) != 1 {
+		t.Errorf("Expected the failed connection to be evicted on return, got %v idle", p.Len())
+	}
+	p.Close()
+
+	//GetContext同样回收过期的连接
+	p, err = newChannelPool(1, 1, pipeFactory, WithEvictionPolicy(MaxAgeEviction(10*time.Millisecond)))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	old = p.PeekIdleConns()[0].(*PoolConn).ID()
+	time.Sleep(20 * time.Millisecond)
+	conn, err = p.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get a connection: %v", err)
+	}
+	if conn.(*PoolConn).ID() == old || p.closedFor(CloseMaxAge) != 1 {
+		t.Errorf("Expected the old connection to be evicted on GetContext")
+	}
+	conn.Close()
+	p.Close()
+}
+
+func TestResize(t *testing.T) {
+	p, err := newChannelPool(4, 4, pipeFactory)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	if err := p.Resize(2); err != nil {
+		t.Fatalf("Failed to resize: %v", err)
+	}
+	if p.Len() != 2 || p.Stats().OpenConns != 2 {
+		t.Errorf("Expected 2 connections after shrinking, got %v idle and %v open", p.Len(), p.Stats().OpenConns)
+	}
+
+	if err := p.Resize(8); err != nil {
+		t.Fatalf("Failed to resize: %v", err)
+	}
+	if p.Len() != 2 || p.Stats().MaxCap != 8 {
+		t.Errorf("Expected the idle connections to move over, got %v idle with max %v", p.Len(), p.Stats().MaxCap)
+	}
+
+	// Get和归还与Resize同时进行
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				conn, err := p.GetContext(context.Background())
+				if err != nil {
+					t.Errorf("Failed to get connection: %v", err)
+					return
+				}
+				conn.Close()
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		p.Resize(1 + i%4)
+	}
+	wg.Wait()
+
+	if open, idle := p.Stats().OpenConns, p.Len(); open != idle {
+		t.Errorf("Expected every open connection to be idle, got %v open and %v idle", open, idle)
+	}
+}
+
+func TestMaxWaitTime(t *testing.T) {
+	p, err := newChannelPool(0, 1, pipeFactory, WithMaxWaitTime(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	held, err := p.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get a connection: %v", err)
+	}
+
+	//打开的连接数达到maxCap，等待超过MaxWaitTime
+	before := time.Now()
+	if _, err := p.GetContext(context.Background()); err != ErrWaitTimeout {
+		t.Fatalf("Expected ErrWaitTimeout, got %v", err)
+	}
+	if elapsed := time.Since(before); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected to wait at least 20ms, waited %v", elapsed)
+	}
+
+	//等待期间归还的连接交给等待者
+	got := make(chan error, 1)
+	go func() {
+		conn, err := p.GetContext(context.Background())
+		if err == nil {
+			conn.Close()
+		}
+		got <- err
+	}()
+	for p.Stats().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	held.Close()
+	if err := <-got; err != nil {
+		t.Fatalf("Expected the returned connection, got %v", err)
+	}
+
+	stats := p.Stats()
+	if stats.WaitCount != 2 || stats.WaitTimeouts != 1 || stats.Waiting != 0 {
+		t.Errorf("Expected 2 waits with 1 timeout, got %+v", stats)
+	}
+	h := stats.WaitHistogram
+	if h.Total() != 2 || len(h.Counts) != len(waitBuckets)+1 {
+		t.Fatalf("Expected 2 samples in the histogram, got %+v", h)
+	}
+	//超时的等待不少于20ms，落在10ms之后的桶中
+	var slow int64
+	for _, count := range h.Counts[3:] {
+		slow += count
+	}
+	if slow < 1 || h.Quantile(1) < 50*time.Millisecond || h.Quantile(0) > h.Quantile(1) {
+		t.Errorf("Expected the timed out wait in a bucket above 10ms, got %+v", h)
+	}
+}
+
+func TestWaitHistogramQuantile(t *testing.T) {
+	h := WaitHistogram{
+		Buckets: []time.Duration{time.Millisecond, 10 * time.Millisecond},
+		Counts:  []int64{1, 1, 0},
+	}
+	if q := h.Quantile(0.5); q != time.Millisecond {
+		t.Errorf("Expected the median in the 1ms bucket, got %v", q)
+	}
+	if q := h.Quantile(1); q != 10*time.Millisecond {
+		t.Errorf("Expected the maximum in the 10ms bucket, got %v", q)
+	}
+
+	h.Counts = []int64{0, 0, 3}
+	if q := h.Quantile(0.5); q != 10*time.Millisecond {
+		t.Errorf("Expected the overflow bucket to report the last bound, got %v", q)
+	}
+
+	if q := (WaitHistogram{}).Quantile(0.5); q != 0 {
+		t.Errorf("Expected 0 for an empty histogram, got %v", q)
+	}
+}
+
+func TestPoolConnID(t *testing.T) {
+	var connected, closed uint64
+	p, err := NewChannelPool(0, 1, pipeFactory,
+		WithConnectHook(func(conn net.Conn, dialDuration time.Duration) {
+			connected = conn.(*PoolConn).ID()
+		}),
+		WithCloseHook(func(conn net.Conn, reason CloseReason) {
+			closed = conn.(*PoolConn).ID()
+		}))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+
+	first, _ := p.Get()
+	id := first.(*PoolConn).ID()
+	if id == 0 || id != connected {
+		t.Errorf("Expected the connect hook to see ID %v, got %v", id, connected)
+	}
+	first.Close()
+
+	again, _ := p.Get()
+	if again.(*PoolConn).ID() != id {
+		t.Errorf("Expected the same connection back with ID %v, got %v", id, again.(*PoolConn).ID())
+	}
+
+	other, _ := p.Get()
+	if other.(*PoolConn).ID() == id {
+		t.Error("Expected a new connection to get a new ID")
+	}
+	other.Close()
+
+	// 空闲连接已满，归还的连接被关闭
+	again.Close()
+	if closed != id {
+		t.Errorf("Expected the close hook to see ID %v, got %v", id, closed)
+	}
+	p.Close()
+}
+
+func TestDBStats(t *testing.T) {
+	p, err := newChannelPool(1, 2, pipeFactory)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	first, _ := p.Get()
+	second, _ := p.Get()
+	third, _ := p.Get()
+
+	stats := p.DBStats()
+	if stats.MaxOpenConnections != 2 || stats.OpenConnections != 3 || stats.InUse != 3 || stats.Idle != 0 {
+		t.Errorf("Unexpected stats with every connection in use: %+v", stats)
+	}
+
+	first.Close()
+	second.Close()
+	third.Close()
+
+	stats = p.DBStats()
+	if stats.OpenConnections != 2 || stats.InUse != 0 || stats.Idle != 2 || stats.MaxIdleClosed != 1 {
+		t.Errorf("Unexpected stats after returning connections: %+v", stats)
+	}
+}
+
+func TestPeekIdleConns(t *testing.T) {
+	p, err := NewChannelPool(3, 3, pipeFactory)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	conn, _ := p.Get()
+
+	idle := p.PeekIdleConns()
+	if len(idle) != 2 || p.Len() != 2 {
+		t.Errorf("Expected 2 idle connections left in the pool, got %v and %v", len(idle), p.Len())
+	}
+	for _, c := range idle {
+		if c.(*PoolConn).ID() == conn.(*PoolConn).ID() {
+			t.Error("Expected the connection in use not to be idle")
+		}
+	}
+	conn.Close()
+}
+
+func TestCloseIdleConnections(t *testing.T) {
+	closed := map[CloseReason]int{}
+	p, err := newChannelPool(3, 3, pipeFactory, WithCloseHook(func(conn net.Conn, reason CloseReason) {
+		closed[reason]++
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	inUse, _ := p.Get()
+
+	p.CloseIdleConnections()
+	if p.Len() != 0 || closed[CloseIdleFlush] != 2 {
+		t.Errorf("Expected the 2 idle connections to be closed, got %v idle and %v closed", p.Len(), closed)
+	}
+
+	// 取出的连接不受影响，归还后放回连接池
+	inUse.Close()
+	if p.Len() != 1 {
+		t.Errorf("Expected the connection in use to be put back, got %v idle", p.Len())
+	}
+	if conn, err := p.Get(); err != nil {
+		t.Errorf("Failed to get connection: %v", err)
+	} else {
+		conn.Close()
+	}
+
+	p.Close()
+	p.CloseIdleConnections()
+}
+
+func TestValidate(t *testing.T) {
+	dead := map[uint64]bool{}
+	p, err := newChannelPool(4, 5, pipeFactory, WithMinIdle(3), WithValidator(func(conn net.Conn) error {
+		if dead[conn.(*PoolConn).ID()] {
+			return errors.New("dead")
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	// 4个空闲连接中2个失效，回收之后补足到3个
+	for _, conn := range p.PeekIdleConns()[:2] {
+		dead[conn.(*PoolConn).ID()] = true
+	}
+	evicted, err := p.Validate()
+	if err != nil || evicted != 2 {
+		t.Errorf("Expected 2 connections to be evicted, got %v, %v", evicted, err)
+	}
+	if p.Len() != 3 || p.closedFor(CloseValidationFailure) != 2 {
+		t.Errorf("Expected 3 idle connections after refilling, got %v", p.Len())
+	}
+	for _, conn := range p.PeekIdleConns() {
+		if dead[conn.(*PoolConn).ID()] {
+			t.Errorf("Expected dead connection %v to be closed", conn.(*PoolConn).ID())
+		}
+	}
+
+	// 补足受maxCap限制
+	inUse := make([]net.Conn, 0, 3)
+	for i := 0; i < 3; i++ {
+		conn, _ := p.Get()
+		inUse = append(inUse, conn)
+	}
+	if evicted, err := p.Validate(); err != nil || evicted != 0 || p.Len() != 2 {
+		t.Errorf("Expected 2 new idle connections within maxCap, got %v idle, %v, %v", p.Len(), evicted, err)
+	}
+	for _, conn := range inUse {
+		conn.Close()
+	}
+
+	p.Close()
+	if _, err := p.Validate(); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	var failing int32
+	var dials int32
+	factory := func() (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		return pipeFactory()
+	}
+	p, err := newChannelPool(0, 2, factory, WithValidator(func(conn net.Conn) error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return errors.New("dead")
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	// 连接池为空时创建一个连接，检查之后留在连接池中
+	if err := p.Ping(context.Background()); err != nil {
+		t.Errorf("Unexpected ping error: %v", err)
+	}
+	if p.Len() != 1 || atomic.LoadInt32(&dials) != 1 {
+		t.Errorf("Expected 1 idle connection after the first ping, got %v idle, %v dials", p.Len(), dials)
+	}
+
+	// 有空闲连接时不创建新连接，也不计入取出次数
+	if err := p.Ping(context.Background()); err != nil {
+		t.Errorf("Unexpected ping error: %v", err)
+	}
+	if p.Len() != 1 || atomic.LoadInt32(&dials) != 1 || atomic.LoadInt64(&p.PeekIdleConns()[0].(*PoolConn).uses) != 0 {
+		t.Errorf("Expected the idle connection to be reused, got %v idle, %v dials", p.Len(), dials)
+	}
+
+	// 没有通过检查的连接被关闭，并创建新连接补上
+	atomic.StoreInt32(&failing, 1)
+	if err := p.Ping(context.Background()); err == nil || err.Error() != "dead" {
+		t.Errorf("Expected the validation error, got %v", err)
+	}
+	if p.Len() != 1 || p.Stats().OpenConns != 1 || p.closedFor(CloseValidationFailure) != 1 {
+		t.Errorf("Expected the failed connection to be replaced, got %v idle, %+v", p.Len(), p.Stats())
+	}
+	atomic.StoreInt32(&failing, 0)
+
+	// 打开的连接数达到maxCap时等待连接归还，直到ctx结束
+	inUse := make([]net.Conn, 0, 2)
+	for i := 0; i < 2; i++ {
+		conn, _ := p.Get()
+		inUse = append(inUse, conn)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	if err := p.Ping(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	cancel()
+	for _, conn := range inUse {
+		conn.Close()
+	}
+
+	p.Close()
+	if err := p.Ping(context.Background()); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestSlowPing(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	slow := func() (net.Conn, error) {
+		time.Sleep(20 * time.Millisecond)
+		return pipeFactory()
+	}
+	p, err := newChannelPool(0, 1, slow, WithPoolName("slow"), WithSlowPingThreshold(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	if err := p.Ping(context.Background()); err != nil {
+		t.Errorf("Unexpected ping error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `slow ping of pool "slow"`) {
+		t.Errorf("Expected a slow ping warning, got %q", buf.String())
+	}
+
+	// 空闲连接的检查很快，不记录
+	buf.Reset()
+	if err := p.Ping(context.Background()); err != nil {
+		t.Errorf("Unexpected ping error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no warning, got %q", buf.String())
+	}
+}
+
+// echoFactory 创建内存中的连接，另一端按行回显收到的数据
+func echoFactory() (net.Conn, error) {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		r := bufio.NewReader(server)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if _, err := server.Write([]byte(line)); err != nil {
+				return
+			}
+		}
+	}()
+	return client, nil
+}
+
+func TestBuffering(t *testing.T) {
+	p, err := NewChannelPool(1, 1, echoFactory, WithBuffering(64, 64))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	bc, ok := conn.(*BufferedConn)
+	if !ok {
+		t.Fatalf("Expected a *BufferedConn, got %T", conn)
+	}
+
+	bc.WriteString("hello\n")
+	if err := bc.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if line, err := bc.ReadString('\n'); err != nil || line != "hello\n" {
+		t.Errorf("Expected the echo of hello, got %q, %v", line, err)
+	}
+
+	// 归还连接时发送写缓冲中的数据，回显在下一次取出时读取
+	bc.WriteString("bye\n")
+	if err := bc.Close(); err != nil {
+		t.Fatalf("Failed to return connection: %v", err)
+	}
+	conn, err = p.Get()
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	if conn != bc {
+		t.Errorf("Expected the buffered connection to be reused")
+	}
+	if b, err := bc.ReadByte(); err != nil || b != 'b' {
+		t.Errorf("Expected the echo of bye, got %q, %v", b, err)
+	}
+	bc.UnreadByte()
+	if peek, err := bc.Peek(4); err != nil || string(peek) != "bye\n" {
+		t.Errorf("Expected the echo of bye, got %q, %v", peek, err)
+	}
+	bc.Close()
+
+	// 归还时发送失败的连接被关闭
+	p2, err := NewChannelPool(1, 1, pipeFactory, WithBuffering(0, 0))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p2.Close()
+
+	conn, err = p2.Get()
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	conn.Write([]byte("lost"))
+	if err := conn.Close(); err == nil {
+		t.Errorf("Expected the flush to fail")
+	}
+	if p2.Len() != 0 {
+		t.Errorf("Expected the connection to be closed, %d idle", p2.Len())
+	}
+	if closed := p2.(*channelPool).closed[CloseUnusable]; closed != 1 {
+		t.Errorf("Expected 1 unusable connection closed, got %d", closed)
+	}
+}
+
+func TestSetFactory(t *testing.T) {
+	var newDials int32
+	newFactory := func() (net.Conn, error) {
+		atomic.AddInt32(&newDials, 1)
+		return pipeFactory()
+	}
+
+	closed := map[CloseReason]int{}
+	p, err := newChannelPool(2, 3, pipeFactory, WithCloseHook(func(conn net.Conn, reason CloseReason) {
+		closed[reason]++
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+
+	inUse, _ := p.Get()
+
+	if err := p.SetFactory(newFactory); err != nil {
+		t.Fatalf("Failed to set factory: %v", err)
+	}
+	if p.Len() != 0 || closed[CloseUnusable] != 1 {
+		t.Errorf("Expected the idle connection to be closed, got %v idle and %v closed", p.Len(), closed)
+	}
+
+	conn, _ := p.Get()
+	if atomic.LoadInt32(&newDials) != 1 {
+		t.Errorf("Expected Get to dial with the new factory, dialled %v times", newDials)
+	}
+	conn.Close()
+
+	// The connection taken before the swap is discarded when it comes back
+	inUse.Close()
+	if p.Len() != 1 || closed[CloseUnusable] != 2 {
+		t.Errorf("Expected the old connection to be closed on return, got %v idle and %v closed", p.Len(), closed)
+	}
+
+	if err := p.SetFactory(nil); err == nil {
+		t.Error("Expected an error for a nil factory")
+	}
+	p.Close()
+	if err := p.SetFactory(newFactory); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func testSnapshot() metricsSnapshot {
+	return metricsSnapshot{
+		Stats: PoolStats{
+			Name:         `db"1`,
+			MaxCap:       4,
+			OpenConns:    3,
+			IdleConns:    1,
+			WaitCount:    2,
+			WaitDuration: 3 * time.Millisecond,
+			WaitHistogram: WaitHistogram{
+				Buckets: []time.Duration{time.Millisecond, 10 * time.Millisecond},
+				Counts:  []int64{1, 1, 0},
+			},
+		},
+		Conns: []connMetrics{{ID: 7, Age: 2 * time.Second, Uses: 5}},
+	}
+}
+
+func TestMetricsSnapshotText(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := testSnapshot().writeText(&buf); err != nil {
+		t.Fatalf("Failed to write text: %v", err)
+	}
+	for _, want := range []string{`pool "db\"1"`, "open conns:    3", "waits:         2 (3ms, 0 timed out)", "conn 7: age 2s, uses 5"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected text to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestMetricsSnapshotJSON(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := testSnapshot().writeJSON(&buf); err != nil {
+		t.Fatalf("Failed to write json: %v", err)
+	}
+
+	var out struct {
+		Name          string `json:"name"`
+		OpenConns     int    `json:"open_conns"`
+		WaitHistogram []struct {
+			LeSeconds float64 `json:"le_seconds"`
+			Count     int64   `json:"count"`
+		} `json:"wait_histogram"`
+		Conns []struct {
+			ID         uint64  `json:"id"`
+			AgeSeconds float64 `json:"age_seconds"`
+			Uses       int64   `json:"uses"`
+		} `json:"conns"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("Failed to parse json %s: %v", buf.String(), err)
+	}
+	if out.Name != `db"1` || out.OpenConns != 3 || len(out.WaitHistogram) != 3 || out.WaitHistogram[1].LeSeconds != 0.01 {
+		t.Errorf("Unexpected json: %s", buf.String())
+	}
+	if len(out.Conns) != 1 || out.Conns[0].ID != 7 || out.Conns[0].AgeSeconds != 2 || out.Conns[0].Uses != 5 {
+		t.Errorf("Unexpected connections in json: %s", buf.String())
+	}
+}
+
+func TestMetricsSnapshotPrometheus(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := testSnapshot().writePrometheus(&buf); err != nil {
+		t.Fatalf("Failed to write prometheus: %v", err)
+	}
+	for _, want := range []string{
+		`tcppool_open_conns{pool="db\"1"} 3`,
+		`tcppool_wait_seconds_bucket{pool="db\"1",le="0.001"} 1`,
+		`tcppool_wait_seconds_bucket{pool="db\"1",le="0.01"} 2`,
+		`tcppool_wait_seconds_bucket{pool="db\"1",le="+Inf"} 2`,
+		`tcppool_wait_seconds_count{pool="db\"1"} 2`,
+		`tcppool_conn_age_seconds{pool="db\"1",conn="7"} 2`,
+		`tcppool_conn_uses_total{pool="db\"1",conn="7"} 5`,
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("Expected prometheus output to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestDumpMetrics(t *testing.T) {
+	p, err := newChannelPool(1, 2, pipeFactory, WithPoolName("dump"))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	for i := 0; i < 3; i++ {
+		conn, _ := p.Get()
+		conn.Close()
+	}
+
+	snapshot := p.metricsSnapshot(time.Now())
+	if len(snapshot.Conns) != 1 || snapshot.Conns[0].Uses != 3 || snapshot.Stats.Name != "dump" {
+		t.Errorf("Expected one idle connection used 3 times, got %+v", snapshot)
+	}
+
+	for _, format := range []string{MetricsText, MetricsJSON, MetricsPrometheus} {
+		buf := bytes.Buffer{}
+		if err := p.DumpMetrics(&buf, format); err != nil || buf.Len() == 0 {
+			t.Errorf("Failed to dump %v metrics: %v", format, err)
+		}
+	}
+	if err := p.DumpMetrics(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func benchmarkPool(b *testing.B, p Pool) {
+	defer p.Close()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			conn, err := p.Get()
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			conn.Close()
+		}
+	})
+}
+
+func BenchmarkChannelPool(b *testing.B) {
+	p, err := NewChannelPool(0, runtime.GOMAXPROCS(0), pipeFactory)
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkPool(b, p)
+}
+
+func BenchmarkSyncPool(b *testing.B) {
+	p, err := NewSyncPool(runtime.GOMAXPROCS(0), pipeFactory)
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkPool(b, p)
+}
+
+// 连接数少于并发数时比较两种实现等待连接归还的开销
+func BenchmarkChannelPoolContended(b *testing.B) {
+	p, err := newChannelPool(0, 2, pipeFactory)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.SetParallelism(4)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			conn, err := p.GetContext(ctx)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			conn.Close()
+		}
+	})
+}
+
+func BenchmarkSyncPoolContended(b *testing.B) {
+	p, err := NewSyncPool(2, pipeFactory)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetParallelism(4)
+	benchmarkPool(b, p)
+}
+
+// startConnectProxy 启动一个HTTP CONNECT代理，status不为200时拒绝所有请求
+func startConnectProxy(t *testing.T, status int) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			client, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				req, err := http.ReadRequest(bufio.NewReader(client))
+				if err != nil || req.Method != http.MethodConnect || status != http.StatusOK {
+					fmt.Fprintf(client, "HTTP/1.1 %d %s\r\n\r\n", status, http.StatusText(status))
+					client.Close()
+					return
+				}
+				target, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					fmt.Fprintf(client, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+					client.Close()
+					return
+				}
+				fmt.Fprintf(client, "HTTP/1.1 200 Connection established\r\n\r\n")
+				go func() {
+					io.Copy(target, client)
+					target.Close()
+				}()
+				io.Copy(client, target)
+				client.Close()
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestProxiedChannelPool(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(conn, conn)
+		}
+	}()
+
+	p, err := NewProxiedChannelPool(1, 2, startConnectProxy(t, http.StatusOK), echo.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Failed to write through the tunnel: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("Expected the echo through the tunnel, got %q, %v", buf, err)
+	}
+	conn.Close()
+	if p.Len() != 1 {
+		t.Errorf("Expected the tunnel to be put back, got %v idle", p.Len())
+	}
+
+	_, err = NewProxiedChannelPool(1, 2, startConnectProxy(t, http.StatusForbidden), echo.Addr().String())
+	if !errors.Is(err, ErrProxyConnect) {
+		t.Errorf("Expected ErrProxyConnect, got %v", err)
+	}
+}
+
+func TestProxiedChannelPoolTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "over tls")
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	target := server.Listener.Addr().String()
+
+	// httptest的证书签发给example.com
+	p, err := NewProxiedChannelPool(0, 1, startConnectProxy(t, http.StatusOK), target,
+		WithTLSConfig(&tls.Config{RootCAs: roots, ServerName: "example.com"}))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	defer conn.Close()
+	if _, ok := conn.(*PoolConn).Conn.(*tls.Conn); !ok {
+		t.Errorf("Expected a TLS connection, got %T", conn.(*PoolConn).Conn)
+	}
+
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %s\r\nConnection: close\r\n\r\n", target)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "over tls" {
+		t.Errorf("Expected the response over TLS, got %q", body)
+	}
+}
+
+func TestProxyProtocol(t *testing.T) {
+	client := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 56324}
+	local := &net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 443}
+	client6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324}
+
+	v2 := func(family byte, addrs ...byte) []byte {
+		header := append([]byte("\r\n\r\n\x00\r\nQUIT\n\x21"), family, 0, byte(len(addrs)))
+		return append(header, addrs...)
+	}
+
+	tests := []struct {
+		version       ProxyProtocolVersion
+		local, remote net.Addr
+		expected      []byte
+	}{
+		{0, local, client, []byte("PROXY TCP4 192.0.2.1 192.0.2.2 56324 443\r\n")},
+		{ProxyProtocolV1, local, client6, []byte("PROXY TCP6 2001:db8::1 ::ffff:192.0.2.2 56324 443\r\n")},
+		{ProxyProtocolV1, nil, nil, []byte("PROXY UNKNOWN\r\n")},
+		{ProxyProtocolV2, local, client, v2(0x11, 192, 0, 2, 1, 192, 0, 2, 2, 0xdc, 0x04, 0x01, 0xbb)},
+		{ProxyProtocolV2, nil, client, v2(0x00)},
+	}
+
+	for _, test := range tests {
+		server, conn := net.Pipe()
+		received := make(chan []byte, 1)
+		go func() {
+			buf := make([]byte, len(test.expected))
+			io.ReadFull(server, buf)
+			received <- buf
+			server.Close()
+		}()
+
+		p, err := NewChannelPool(1, 1, func() (net.Conn, error) { return conn, nil },
+			WithProxyProtocol(test.local, test.remote), WithProxyProtocolVersion(test.version))
+		if err != nil {
+			t.Fatalf("Failed to create pool: %v", err)
+		}
+		if header := <-received; !bytes.Equal(header, test.expected) {
+			t.Errorf("Expected header %q, got %q", test.expected, header)
+		}
+		p.Close()
+	}
+
+	if header, err := (&proxyHeaderWriter{src: client6, dst: client6}).header(ProxyProtocolV2); err != nil || len(header) != 16+36 || header[13] != 0x21 {
+		t.Errorf("Expected a 52 byte IPv6 header, got %q, %v", header, err)
+	}
+	if _, err := (&proxyHeaderWriter{}).header(3); err == nil {
+		t.Errorf("Expected an error for an unsupported version")
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	failing := false
+	p, err := newChannelPool(1, 2, func() (net.Conn, error) {
+		if failing {
+			return nil, errors.New("dial failed")
+		}
+		return pipeFactory()
+	}, WithPoolName("web"))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer p.Close()
+
+	conns := []net.Conn{}
+	for i := 0; i < 4; i++ {
+		conn, _ := p.Get()
+		conns = append(conns, conn)
+	}
+	failing = true
+	if _, err := p.Get(); err == nil {
+		t.Fatal("Expected the dial to fail")
+	}
+	conns[0].(*PoolConn).MarkUnusable()
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	handler := p.HTTPHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/pool/web", nil))
+	page := rec.Body.String()
+	for _, want := range []string{"/debug/pool/web", "20.00%", "1 of 5 dials", "unusable", "overflow"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected the page to contain %q, got:\n%s", want, page)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected an HTML page, got %v", ct)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/pool/web?format=json", nil))
+	var out struct {
+		Name       string `json:"name"`
+		Dials      int64  `json:"dials"`
+		DialErrors int64  `json:"dial_errors"`
+		Evictions  []struct {
+			Reason string `json:"reason"`
+		} `json:"evictions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("Failed to parse json %s: %v", rec.Body.String(), err)
+	}
+	if out.Name != "web" || out.Dials != 5 || out.DialErrors != 1 || len(out.Evictions) != 2 || out.Evictions[0].Reason != "unusable" {
+		t.Errorf("Unexpected json: %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,143 @@
+package tcpPool
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var (
+	// ErrProxyConnect 表示HTTP代理拒绝了CONNECT请求
+	ErrProxyConnect = errors.New("proxy refused the CONNECT request")
+)
+
+// NewProxiedChannelPool 创建一个连接池，每个连接都是通过proxyAddr上的HTTP代理用CONNECT方法建立的到targetAddr的隧道。
+// 隧道建立之后与直接创建的连接没有区别，使用WithTLSConfig可以在隧道上与targetAddr进行TLS握手（HTTPS over proxy），
+// 此时如果没有设置ServerName则使用targetAddr的主机名
+func NewProxiedChannelPool(initialCap, maxCap int, proxyAddr, targetAddr string, opts ...PoolOption) (Pool, error) {
+	host, _, err := net.SplitHostPort(targetAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &proxyDialer{proxyAddr: proxyAddr, targetAddr: targetAddr}
+	opts = append([]PoolOption{withTLSServerName(host)}, opts...)
+
+	c, err := newChannelPoolContext(context.Background(), initialCap, maxCap, dialer.DialContext, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// WithTLSConfig 新连接创建之后使用config进行TLS握手，连接池中保存的是*tls.Conn
+func WithTLSConfig(config *tls.Config) PoolOption {
+	return func(c *channelPool) {
+		c.tlsConfig = config
+	}
+}
+
+// withTLSServerName 设置TLS握手时默认的ServerName，WithTLSConfig中的ServerName优先
+func withTLSServerName(name string) PoolOption {
+	return func(c *channelPool) {
+		c.tlsServerName = name
+	}
+}
+
+// handshakeTLS 在conn上进行TLS握手，失败时关闭conn
+func (c *channelPool) handshakeTLS(ctx context.Context, conn net.Conn) (net.Conn, error) {
+	config := c.tlsConfig
+	if config.ServerName == "" && c.tlsServerName != "" {
+		config = config.Clone()
+		config.ServerName = c.tlsServerName
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// proxyDialer 通过HTTP代理的CONNECT方法建立到目标地址的隧道
+type proxyDialer struct {
+	proxyAddr  string
+	targetAddr string
+}
+
+// DialContext 连接代理并发送CONNECT请求，代理返回200之后连接即为到目标地址的隧道
+func (d *proxyDialer) DialContext(ctx context.Context) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", d.proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	//握手期间ctx结束时通过设置过期的deadline中断读写
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+
+	tunnel, err := d.connect(conn)
+	if !stop() || err != nil {
+		conn.Close()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return tunnel, nil
+}
+
+// connect 在conn上发送CONNECT请求并读取代理的响应
+func (d *proxyDialer) connect(conn net.Conn) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: d.targetAddr},
+		Host:   d.targetAddr,
+		Header: make(http.Header),
+	}
+
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s to %s: %s", ErrProxyConnect, d.proxyAddr, d.targetAddr, resp.Status)
+	}
+
+	//代理可能在响应之后紧接着发送了目标地址的数据，这些数据已经读入br
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 先读取br中已经缓存的数据，再从连接读取
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
@@ -0,0 +1,163 @@
+package tcpPool
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ProxyProtocolVersion PROXY协议头部的格式
+type ProxyProtocolVersion int
+
+const (
+	// ProxyProtocolV1 文本格式的头部，例如"PROXY TCP4 192.0.2.1 192.0.2.2 56324 443\r\n"
+	ProxyProtocolV1 ProxyProtocolVersion = 1
+	// ProxyProtocolV2 二进制格式的头部
+	ProxyProtocolV2 ProxyProtocolVersion = 2
+)
+
+// proxyV2Signature PROXY协议v2头部开头固定的12个字节
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// WithProxyProtocol 新连接建立之后、TLS握手和任何应用数据之前先发送PROXY协议头部，用于连接HAProxy等要求PROXY协议的服务，
+// 默认使用v1格式，可以用WithProxyProtocolVersion选择v2。
+// localAddr和remoteAddr是客户端连接的两端，通常就是接受的客户端连接的LocalAddr()和RemoteAddr()：
+// remoteAddr（客户端的真实地址）作为头部中的源地址，localAddr作为目的地址。
+// 任一地址为nil或者不是IP地址时发送UNKNOWN(v1)或者AF_UNSPEC(v2)头部，服务端会使用连接本身的地址
+func WithProxyProtocol(localAddr, remoteAddr net.Addr) PoolOption {
+	return func(c *channelPool) {
+		c.proxyHeader = &proxyHeaderWriter{src: remoteAddr, dst: localAddr}
+	}
+}
+
+// WithProxyProtocolVersion 选择WithProxyProtocol发送的头部格式，默认为ProxyProtocolV1，单独使用时没有效果
+func WithProxyProtocolVersion(version ProxyProtocolVersion) PoolOption {
+	return func(c *channelPool) {
+		c.proxyVersion = version
+	}
+}
+
+// proxyHeaderWriter 在新连接上写入PROXY协议头部
+type proxyHeaderWriter struct {
+	src, dst net.Addr
+}
+
+// write 在conn上写入version格式的头部，version为0时使用v1，失败时关闭conn。ctx结束时通过设置过期的deadline中断写入
+func (w *proxyHeaderWriter) write(ctx context.Context, conn net.Conn, version ProxyProtocolVersion) error {
+	header, err := w.header(version)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetWriteDeadline(time.Unix(1, 0))
+	})
+
+	_, err = conn.Write(header)
+	if !stop() || err != nil {
+		conn.Close()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+
+	conn.SetWriteDeadline(time.Time{})
+	return nil
+}
+
+// header 按照版本编码头部
+func (w *proxyHeaderWriter) header(version ProxyProtocolVersion) ([]byte, error) {
+	src, srcPort, srcOK := proxyAddr(w.src)
+	dst, dstPort, dstOK := proxyAddr(w.dst)
+	known := srcOK && dstOK
+	if known && (src.To4() == nil) != (dst.To4() == nil) {
+		//两个地址的协议族不同时都使用IPv6格式，IPv4地址映射为::ffff:a.b.c.d
+		src, dst = src.To16(), dst.To16()
+	} else if known && src.To4() != nil {
+		src, dst = src.To4(), dst.To4()
+	}
+
+	switch version {
+	case 0, ProxyProtocolV1:
+		return proxyHeaderV1(known, src, dst, srcPort, dstPort), nil
+	case ProxyProtocolV2:
+		return proxyHeaderV2(known, src, dst, srcPort, dstPort), nil
+	}
+	return nil, fmt.Errorf("unsupported PROXY protocol version %d", version)
+}
+
+// proxyHeaderV1 编码v1文本头部，地址未知时为"PROXY UNKNOWN\r\n"
+func proxyHeaderV1(known bool, src, dst net.IP, srcPort, dstPort int) []byte {
+	if !known {
+		return []byte("PROXY UNKNOWN\r\n")
+	}
+
+	if len(src) == net.IPv4len {
+		return []byte(fmt.Sprintf("PROXY TCP4 %s %s %d %d\r\n", src, dst, srcPort, dstPort))
+	}
+	return []byte(fmt.Sprintf("PROXY TCP6 %s %s %d %d\r\n", proxyIPv6(src), proxyIPv6(dst), srcPort, dstPort))
+}
+
+// proxyIPv6 IPv6格式的地址，net.IP.String()会把映射的IPv4地址写成a.b.c.d
+func proxyIPv6(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return "::ffff:" + v4.String()
+	}
+	return ip.String()
+}
+
+// proxyHeaderV2 编码v2二进制头部：12字节签名、版本和命令、协议族和传输协议、地址部分的长度，然后是地址。
+// 地址未知时使用PROXY命令和AF_UNSPEC，没有地址部分
+func proxyHeaderV2(known bool, src, dst net.IP, srcPort, dstPort int) []byte {
+	header := append([]byte(nil), proxyV2Signature...)
+	//高4位为版本2，低4位为PROXY命令
+	header = append(header, 0x21)
+
+	if !known {
+		return append(header, 0x00, 0x00, 0x00)
+	}
+
+	//高4位为协议族(1 AF_INET，2 AF_INET6)，低4位为传输协议(1 STREAM)
+	family := byte(0x11)
+	if len(src) == net.IPv6len {
+		family = 0x21
+	}
+	header = append(header, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(2*len(src)+4))
+	header = append(header, src...)
+	header = append(header, dst...)
+	header = binary.BigEndian.AppendUint16(header, uint16(srcPort))
+	header = binary.BigEndian.AppendUint16(header, uint16(dstPort))
+	return header
+}
+
+// proxyAddr 取出TCP地址的IP和端口，addr为nil或者不是IP地址时ok为false
+func proxyAddr(addr net.Addr) (ip net.IP, port int, ok bool) {
+	switch a := addr.(type) {
+	case nil:
+		return nil, 0, false
+	case *net.TCPAddr:
+		ip, port = a.IP, a.Port
+	default:
+		host, p, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return nil, 0, false
+		}
+		if port, err = strconv.Atoi(p); err != nil {
+			return nil, 0, false
+		}
+		ip = net.ParseIP(host)
+	}
+	if ip == nil || port < 0 || port > 0xffff {
+		return nil, 0, false
+	}
+	return ip, port, true
+}
@@ -0,0 +1,167 @@
+package tcpPool
+
+import (
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// waitBuckets 等待时间直方图各个桶的上限，超过最后一个上限的样本计入溢出桶
+var waitBuckets = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// PoolStats 连接池的统计信息
+type PoolStats struct {
+	//连接池的名称
+	Name string
+	//最大连接数
+	MaxCap int
+	//当前打开的连接数
+	OpenConns int
+	//当前空闲的连接数
+	IdleConns int
+	//GetContext等待连接的次数
+	WaitCount int64
+	//GetContext等待连接的总时间
+	WaitDuration time.Duration
+	//等待超过MaxWaitTime的次数
+	WaitTimeouts int64
+	//等待时间的直方图
+	WaitHistogram WaitHistogram
+	//当前正在GetContext中等待连接的个数
+	Waiting int
+	//调用工厂方法创建连接的次数和其中失败的次数
+	Dials      int64
+	DialErrors int64
+}
+
+// DialErrorRate 工厂方法失败的比例，没有创建过连接时为0
+func (s PoolStats) DialErrorRate() float64 {
+	if s.Dials == 0 {
+		return 0
+	}
+	return float64(s.DialErrors) / float64(s.Dials)
+}
+
+// WaitHistogram 固定桶的等待时间直方图，Counts[i]为等待时间不超过Buckets[i]的样本数，
+// Counts的最后一项为超过所有桶上限的样本数
+type WaitHistogram struct {
+	Buckets []time.Duration
+	Counts  []int64
+}
+
+// Total 返回样本总数
+func (h WaitHistogram) Total() int64 {
+	var total int64
+	for _, count := range h.Counts {
+		total += count
+	}
+	return total
+}
+
+// Quantile 返回q分位(0-1)的近似等待时间，即包含该分位样本的桶的上限。
+// 落在溢出桶时返回最后一个桶的上限，没有样本时返回0
+func (h WaitHistogram) Quantile(q float64) time.Duration {
+	total := h.Total()
+	if total == 0 || len(h.Buckets) == 0 {
+		return 0
+	}
+
+	rank := int64(q*float64(total) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen int64
+	for i, count := range h.Counts {
+		seen += count
+		if seen >= rank && i < len(h.Buckets) {
+			return h.Buckets[i]
+		}
+	}
+	return h.Buckets[len(h.Buckets)-1]
+}
+
+// waitStats 记录等待连接的统计信息，所有字段通过atomic访问
+type waitStats struct {
+	count    int64
+	duration int64
+	timeouts int64
+	buckets  [len(waitBuckets) + 1]int64
+	waiting  int32
+}
+
+func (w *waitStats) record(d time.Duration) {
+	atomic.AddInt64(&w.count, 1)
+	atomic.AddInt64(&w.duration, int64(d))
+
+	i := 0
+	for i < len(waitBuckets) && d > waitBuckets[i] {
+		i++
+	}
+	atomic.AddInt64(&w.buckets[i], 1)
+}
+
+func (w *waitStats) histogram() WaitHistogram {
+	h := WaitHistogram{
+		Buckets: append([]time.Duration(nil), waitBuckets[:]...),
+		Counts:  make([]int64, len(w.buckets)),
+	}
+	for i := range w.buckets {
+		h.Counts[i] = atomic.LoadInt64(&w.buckets[i])
+	}
+	return h
+}
+
+// Stats 返回连接池当前的统计信息
+func (c *channelPool) Stats() PoolStats {
+	return PoolStats{
+		Name:          c.name,
+		MaxCap:        int(atomic.LoadInt32(&c.maxCap)),
+		OpenConns:     int(atomic.LoadInt32(&c.openConns)),
+		IdleConns:     c.Len(),
+		WaitCount:     atomic.LoadInt64(&c.waits.count),
+		WaitDuration:  time.Duration(atomic.LoadInt64(&c.waits.duration)),
+		WaitTimeouts:  atomic.LoadInt64(&c.waits.timeouts),
+		WaitHistogram: c.waits.histogram(),
+		Waiting:       int(atomic.LoadInt32(&c.waits.waiting)),
+		Dials:         atomic.LoadInt64(&c.dials),
+		DialErrors:    atomic.LoadInt64(&c.dialErrors),
+	}
+}
+
+// DBStats 以database/sql.DBStats的字段返回统计信息，便于沿用为database/sql设计的监控。
+// 归还时空闲连接已满而关闭的连接计入MaxIdleClosed
+func (c *channelPool) DBStats() sql.DBStats {
+	open := int(atomic.LoadInt32(&c.openConns))
+	idle := c.Len()
+	inUse := open - idle
+	if inUse < 0 {
+		inUse = 0
+	}
+
+	return sql.DBStats{
+		MaxOpenConnections: int(atomic.LoadInt32(&c.maxCap)),
+		OpenConnections:    open,
+		InUse:              inUse,
+		Idle:               idle,
+		WaitCount:          atomic.LoadInt64(&c.waits.count),
+		WaitDuration:       time.Duration(atomic.LoadInt64(&c.waits.duration)),
+		MaxIdleClosed:      c.closedFor(CloseOverflow),
+		MaxIdleTimeClosed:  c.closedFor(CloseIdleEviction),
+		MaxLifetimeClosed:  c.closedFor(CloseMaxAge),
+	}
+}
+
+// closedFor 返回因为reason关闭的连接数
+func (c *channelPool) closedFor(reason CloseReason) int64 {
+	return atomic.LoadInt64(&c.closed[reason])
+}
@@ -0,0 +1,202 @@
+package tcpPool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// syncPool 实现Pool接口，空闲连接保存在sync.Pool中，内存紧张时GC可以回收空闲连接，
+// 通过信号量限制同时取出的连接数不超过maxCap
+type syncPool struct {
+	//mu 保证Close和归还连接不会同时进行
+	mu sync.RWMutex
+	//空闲连接
+	free sync.Pool
+	//每个取出的连接占用一个名额
+	sem chan struct{}
+	//连接池关闭时关闭，唤醒等待名额的Get
+	done chan struct{}
+
+	// 创建新连接的工厂方法
+	factory Factory
+
+	closed bool
+	//BorrowAll取出连接期间不为nil，release时关闭，batching为1表示batch不为nil，用于Get不加锁的检查
+	batch    chan struct{}
+	batching int32
+	//放入free的空闲连接数，GC回收的连接不会被扣除，因此只是一个上限
+	idle int32
+}
+
+// NewSyncPool 创建一个基于sync.Pool的连接池，最多同时取出maxCap个连接，取出的连接数达到maxCap时Get等待连接归还
+func NewSyncPool(maxCap int, factory Factory) (Pool, error) {
+
+	if maxCap <= 0 {
+
+		return nil, errors.New("invalid capacity settings")
+
+	}
+
+	if factory == nil {
+
+		return nil, errors.New("factory is nil")
+
+	}
+
+	return &syncPool{
+		sem:     make(chan struct{}, maxCap),
+		done:    make(chan struct{}),
+		factory: factory,
+	}, nil
+}
+
+func (s *syncPool) Get() (net.Conn, error) {
+	if err := s.waitBatch(); err != nil {
+		return nil, err
+	}
+
+	select {
+
+	case <-s.done:
+
+		return nil, ErrClosed
+
+	case s.sem <- struct{}{}:
+	}
+
+	if conn, ok := s.free.Get().(*PoolConn); ok {
+
+		atomic.AddInt32(&s.idle, -1)
+
+		return conn.checkout(), nil
+
+	}
+
+	conn, err := s.factory()
+
+	if err != nil {
+
+		<-s.sem
+
+		return nil, err
+
+	}
+
+	return newPoolConn(s, conn).checkout(), nil
+}
+
+func (s *syncPool) put(conn *PoolConn) error {
+
+	if conn == nil {
+
+		return errors.New("connection is nil. rejecting")
+
+	}
+
+	s.mu.RLock()
+
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		<-s.sem
+		return conn.Conn.Close()
+	}
+
+	atomic.AddInt32(&s.idle, 1)
+	s.free.Put(conn)
+	<-s.sem
+
+	return nil
+}
+
+// WarmUp 并发创建最多n个空闲连接，不超过maxCap个
+func (s *syncPool) WarmUp(ctx context.Context, n int) error {
+	if n > cap(s.sem) {
+		n = cap(s.sem)
+	}
+
+	return warmUp(ctx, n, func(ctx context.Context) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		conn, err := s.factory()
+		if err != nil {
+			return err
+		}
+
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		if s.closed {
+			return conn.Close()
+		}
+
+		atomic.AddInt32(&s.idle, 1)
+		s.free.Put(newPoolConn(s, conn))
+
+		return nil
+	})
+}
+
+// PeekIdleConns 返回当前空闲连接的快照，取出sync.Pool中的所有连接后立即放回，期间持有mu避免与归还连接同时进行
+func (s *syncPool) PeekIdleConns() []net.Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	var idle []net.Conn
+	for {
+		conn, ok := s.free.Get().(*PoolConn)
+		if !ok {
+			break
+		}
+		idle = append(idle, conn)
+	}
+
+	for _, conn := range idle {
+		s.free.Put(conn)
+	}
+
+	return idle
+}
+
+// closeConn 关闭一个取出的连接并释放它的名额
+func (s *syncPool) closeConn(conn *PoolConn, reason CloseReason) error {
+	<-s.sem
+	return conn.Conn.Close()
+}
+
+func (s *syncPool) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.done)
+	s.mu.Unlock()
+
+	for {
+		conn, ok := s.free.Get().(*PoolConn)
+		if !ok {
+			break
+		}
+		atomic.AddInt32(&s.idle, -1)
+		conn.Conn.Close()
+	}
+}
+
+// Len 返回空闲连接数的上限，sync.Pool在GC时可能已经丢弃了部分空闲连接
+func (s *syncPool) Len() int {
+	if idle := atomic.LoadInt32(&s.idle); idle > 0 {
+		return int(idle)
+	}
+	return 0
+}
@@ -1,24 +1,121 @@
 package tcpPool
 
 import (
+	"context"
 	"errors"
+	"math/rand"
 	"net"
+	"sync"
+	"time"
 )
 
-const ()
+const (
+	// DefaultRetryInterval 不可达地址默认的重试间隔
+	DefaultRetryInterval = 5 * time.Second
+	// DefaultJitter 定时任务间隔默认的随机抖动系数
+	DefaultJitter = 0.1
+)
 
 var (
 	// ErrClosed 表示连接池已经关闭错误.
 	ErrClosed = errors.New("pool is closed")
+	// ErrWaitTimeout 表示等待连接超过了WithMaxWaitTime设置的时间.
+	ErrWaitTimeout = errors.New("timed out waiting for a connection")
 )
 
 func init() {
 
 }
 
-//连接池基本功能描述。一个连接池应该有最大，最小容量。设计合理的连接池应该是线程安全并且容易使用。
+// 连接池基本功能描述。一个连接池应该有最大，最小容量。设计合理的连接池应该是线程安全并且容易使用。
 type Pool interface {
 	Get() (net.Conn, error)
 	Close()
 	Len() int
+	// WarmUp 并发创建最多n个连接放入连接池，超出最大容量的部分被丢弃，返回遇到的第一个错误
+	WarmUp(ctx context.Context, n int) error
+	// PeekIdleConns 返回当前空闲连接的快照，连接仍然留在连接池中，调用者不能使用或者关闭这些连接
+	PeekIdleConns() []net.Conn
+	// BorrowAll 取出所有空闲连接，在调用release之前Get等待，release一次归还所有连接
+	BorrowAll() (conns []net.Conn, release func(), err error)
+}
+
+// warmUp 并发执行n次fill，返回第一个错误，其余成功创建的连接仍然由fill放入连接池
+func warmUp(ctx context.Context, n int, fill func(ctx context.Context) error) error {
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fill(ctx); err != nil {
+				once.Do(func() {
+					firstErr = err
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// PoolOption 连接池的可选配置，在创建连接池时传入
+type PoolOption func(*channelPool)
+
+// WithRetryInterval 设置多地址连接池中不可达地址的重试间隔
+func WithRetryInterval(d time.Duration) PoolOption {
+	return func(c *channelPool) {
+		if d > 0 {
+			c.retryInterval = d
+		}
+	}
+}
+
+// WithJitter 设置定时任务间隔的随机抖动系数，取值0.0-1.0，每次等待的间隔乘以1+rand.Float64()*factor，
+// 避免指向同一服务器的多个连接池同时重试。目前用于多地址连接池重新检测不可达地址的间隔，默认为DefaultJitter
+func WithJitter(factor float64) PoolOption {
+	return func(c *channelPool) {
+		if factor < 0 {
+			factor = 0
+		}
+		if factor > 1 {
+			factor = 1
+		}
+		c.jitter = factor
+	}
+}
+
+// jittered 返回加上随机抖动之后的间隔
+func (c *channelPool) jittered(d time.Duration) time.Duration {
+	if c.jitter <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + rand.Float64()*c.jitter))
+}
+
+// WithDialConcurrency 设置创建初始连接时最多同时调用工厂方法的个数，适用于initialCap较大并且每次创建连接较慢的情况(比如TLS握手)。
+// 并发创建时个别连接失败不影响连接池的创建，只有失败的次数多于成功的次数时才返回最后一个错误，已经创建的连接会被关闭
+func WithDialConcurrency(n int) PoolOption {
+	return func(c *channelPool) {
+		c.dialConcurrency = n
+	}
+}
+
+// WithMaxWaitTime 设置GetContext等待连接的最长时间，超时返回ErrWaitTimeout，与context的超时相互独立
+func WithMaxWaitTime(d time.Duration) PoolOption {
+	return func(c *channelPool) {
+		c.maxWaitTime = d
+	}
+}
+
+// WithPoolName 设置连接池的名称，用于在统计信息中区分多个连接池
+func WithPoolName(name string) PoolOption {
+	return func(c *channelPool) {
+		c.name = name
+	}
 }
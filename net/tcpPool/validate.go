@@ -0,0 +1,73 @@
+package tcpPool
+
+import (
+	"context"
+	"net"
+)
+
+// Validator 检查一个空闲连接是否仍然可用，返回非nil的错误表示连接已经失效
+type Validator func(conn net.Conn) error
+
+// WithValidator 设置Validate检查空闲连接时使用的函数，conn是*PoolConn，在函数中不能关闭连接
+func WithValidator(v Validator) PoolOption {
+	return func(c *channelPool) {
+		c.validator = v
+	}
+}
+
+// WithMinIdle 设置Validate回收失效连接之后补足的空闲连接数，受maxCap限制
+func WithMinIdle(n int) PoolOption {
+	return func(c *channelPool) {
+		c.minIdle = n
+	}
+}
+
+// Validate 同步检查所有空闲连接，关闭被WithEvictionPolicy回收的和没有通过WithValidator检查的连接并返回关闭的个数，
+// 然后创建新连接使空闲连接数不少于WithMinIdle设置的数量，可以用作就绪检查或者网络分区恢复之后的检查。
+// 检查期间空闲连接被取出，只在取出和放回时持有mu，这期间的Get会创建新连接。返回的错误是补足空闲连接时创建连接失败的错误
+func (c *channelPool) Validate() (int, error) {
+	c.mu.Lock()
+
+	if c.conns == nil {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
+
+	idle := c.drainIdle()
+
+	c.mu.Unlock()
+
+	evicted := 0
+	for _, conn := range idle {
+		if c.evict(conn) {
+			evicted++
+			continue
+		}
+		if c.validator != nil {
+			if err := c.validator(conn); err != nil {
+				c.closeConn(conn, CloseValidationFailure)
+				evicted++
+				continue
+			}
+		}
+		c.put(conn)
+	}
+
+	for c.Len() < c.minIdle {
+		if !c.reserveConn() {
+			break
+		}
+
+		conn, err := c.dial(context.Background())
+		if err != nil {
+			c.releaseConn()
+			return evicted, err
+		}
+
+		if err := c.put(conn); err != nil {
+			return evicted, err
+		}
+	}
+
+	return evicted, nil
+}
@@ -0,0 +1,168 @@
+package udpPool
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// packetPool 实现PacketPool接口 并且带有缓冲的连接池.
+type packetPool struct {
+	//mu 为了保证每个连接获取是协成安全的
+	mu    sync.Mutex
+	conns chan net.PacketConn
+
+	// 创建新连接的工厂方法
+	factory PacketFactory
+}
+
+// NewPacketPool 创建一个带缓冲的数据报连接池，初始创建initialCap个连接，最多缓存maxCap个空闲连接
+func NewPacketPool(initialCap, maxCap int, factory PacketFactory) (PacketPool, error) {
+
+	if initialCap < 0 || maxCap <= 0 || initialCap > maxCap {
+
+		return nil, errors.New("invalid capacity settings")
+
+	}
+
+	if factory == nil {
+
+		return nil, errors.New("factory is nil")
+
+	}
+
+	c := &packetPool{
+		conns:   make(chan net.PacketConn, maxCap),
+		factory: factory,
+	}
+
+	for i := 0; i < initialCap; i++ {
+		conn, err := factory()
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("factory is not able to fill the pool: %s", err)
+		}
+		c.conns <- conn
+	}
+
+	return c, nil
+}
+
+func (c *packetPool) getConnsAndFactory() (chan net.PacketConn, PacketFactory) {
+
+	c.mu.Lock()
+
+	conns := c.conns
+
+	factory := c.factory
+
+	c.mu.Unlock()
+
+	return conns, factory
+}
+
+// Get 取出一个空闲连接，没有空闲连接时使用工厂方法创建，取出的连接关闭时归还给连接池
+func (c *packetPool) Get() (net.PacketConn, error) {
+	conns, factory := c.getConnsAndFactory()
+
+	if conns == nil {
+
+		return nil, ErrClosed
+
+	}
+
+	select {
+
+	case conn := <-conns:
+
+		if conn == nil {
+
+			return nil, ErrClosed
+
+		}
+
+		return c.wrapConn(conn), nil
+
+	default:
+
+		conn, err := factory()
+
+		if err != nil {
+
+			return nil, err
+
+		}
+
+		return c.wrapConn(conn), nil
+	}
+}
+
+// Put 归还Get取出的连接，与关闭连接的效果相同
+func (c *packetPool) Put(conn net.PacketConn) error {
+	p, ok := conn.(*PoolPacketConn)
+	if !ok || p.c != c {
+		return errForeignConn
+	}
+	return c.put(p)
+}
+
+func (c *packetPool) put(p *PoolPacketConn) error {
+
+	if p == nil || p.PacketConn == nil {
+
+		return errors.New("connection is nil. rejecting")
+
+	}
+
+	if p.unusable {
+
+		return p.PacketConn.Close()
+
+	}
+
+	c.mu.Lock()
+
+	defer c.mu.Unlock()
+
+	if c.conns == nil {
+
+		return p.PacketConn.Close()
+
+	}
+
+	select {
+
+	case c.conns <- p.PacketConn:
+
+		return nil
+
+	default:
+
+		return p.PacketConn.Close()
+
+	}
+}
+
+func (c *packetPool) Close() {
+	c.mu.Lock()
+	conns := c.conns
+	c.conns = nil
+	c.factory = nil
+	c.mu.Unlock()
+
+	if conns == nil {
+		return
+	}
+
+	close(conns)
+
+	for conn := range conns {
+		conn.Close()
+	}
+}
+
+func (c *packetPool) Len() int {
+	conns, _ := c.getConnsAndFactory()
+	return len(conns)
+}
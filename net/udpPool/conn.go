@@ -0,0 +1,27 @@
+package udpPool
+
+import (
+	"net"
+)
+
+// PoolPacketConn 连接池中的数据报连接，关闭时归还给连接池
+type PoolPacketConn struct {
+	net.PacketConn
+	c        *packetPool
+	unusable bool
+}
+
+// Close 把连接归还给连接池，被MarkUnusable标记的连接直接关闭
+func (p *PoolPacketConn) Close() error {
+	return p.c.put(p)
+}
+
+// MarkUnusable 标记连接不可用，Close或者Put时关闭连接而不是归还
+func (p *PoolPacketConn) MarkUnusable() {
+	p.unusable = true
+}
+
+// wrapConn 包装工厂方法创建的连接
+func (c *packetPool) wrapConn(conn net.PacketConn) net.PacketConn {
+	return &PoolPacketConn{PacketConn: conn, c: c}
+}
@@ -0,0 +1,70 @@
+package udpPool
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+)
+
+// loopbackFactory 创建绑定在本地回环地址上的UDP连接，并统计创建的连接数
+func loopbackFactory(dials *int32) PacketFactory {
+	return func() (net.PacketConn, error) {
+		atomic.AddInt32(dials, 1)
+		return net.ListenPacket("udp", "127.0.0.1:0")
+	}
+}
+
+func TestPacketPool(t *testing.T) {
+	var dials int32
+	p, err := NewPacketPool(1, 2, loopbackFactory(&dials))
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+
+	first, err := p.Get()
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	second, _ := p.Get()
+	third, _ := p.Get()
+	if dials != 3 || p.Len() != 0 {
+		t.Errorf("Expected 3 connections dialled and none idle, got %v and %v", dials, p.Len())
+	}
+
+	// 数据报连接可以正常收发
+	if _, err := first.WriteTo([]byte("ping"), second.LocalAddr()); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	buf := make([]byte, 16)
+	if n, _, err := second.ReadFrom(buf); err != nil || string(buf[:n]) != "ping" {
+		t.Errorf("Expected ping, got %q, %v", buf[:n], err)
+	}
+
+	if err := p.Put(first); err != nil {
+		t.Errorf("Failed to put connection: %v", err)
+	}
+	second.Close()
+	third.(*PoolPacketConn).MarkUnusable()
+	third.Close()
+	if p.Len() != 2 {
+		t.Errorf("Expected 2 idle connections, got %v", p.Len())
+	}
+
+	if _, err := third.WriteTo([]byte("ping"), first.LocalAddr()); err == nil {
+		t.Error("Expected the unusable connection to be closed")
+	}
+
+	conn, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	if err := p.Put(conn); err == nil {
+		t.Error("Expected an error putting a connection from elsewhere")
+	}
+	conn.Close()
+
+	p.Close()
+	if _, err := p.Get(); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	if p.Len() != 0 {
+		t.Errorf("Expected no idle connections after closing, got %v", p.Len())
+	}
+}
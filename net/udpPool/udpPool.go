@@ -0,0 +1,26 @@
+package udpPool
+
+import (
+	"errors"
+	"net"
+)
+
+var (
+	// ErrClosed 表示连接池已经关闭错误.
+	ErrClosed = errors.New("pool is closed")
+
+	// errForeignConn 归还的连接不是这个连接池创建的
+	errForeignConn = errors.New("connection does not belong to this pool. rejecting")
+)
+
+// PacketFactory 创建一个面向数据报的连接
+type PacketFactory func() (net.PacketConn, error)
+
+// PacketPool 面向数据报(UDP)的连接池，与tcpPool.Pool相同，只是连接的类型为net.PacketConn
+type PacketPool interface {
+	Get() (net.PacketConn, error)
+	// Put 归还Get取出的连接，连接池已满或者已经关闭时关闭连接
+	Put(conn net.PacketConn) error
+	Close()
+	Len() int
+}